@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"path"
+	"strconv"
 
 	"github.com/terramate-io/terramate"
 	"github.com/terramate-io/terramate/cloud/stack"
@@ -33,6 +34,11 @@ const (
 	DeploymentsPath = "/v1/deployments"
 	// StacksPath is the stacks endpoint base path.
 	StacksPath = "/v1/stacks"
+	// DriftsPath is the drifts endpoint base path.
+	DriftsPath = "/v1/drifts"
+	// OIDCTokenPath is the endpoint path exchanging OIDC tokens for Terramate
+	// Cloud tokens.
+	OIDCTokenPath = "/v1/auth/oidc/token"
 )
 
 // ErrUnexpectedStatus indicates the server responded with an unexpected status code.
@@ -75,6 +81,13 @@ func (c *Client) MemberOrganizations(ctx context.Context) (orgs MemberOrganizati
 	return Get[MemberOrganizations](ctx, c, MembershipsPath)
 }
 
+// ExchangeOIDCToken exchanges the token of the client credential, an OIDC
+// token (JWT) issued by an identity provider trusted by Terramate Cloud, for a
+// Terramate Cloud token.
+func (c *Client) ExchangeOIDCToken(ctx context.Context) (OIDCTokenResponse, error) {
+	return Request[OIDCTokenResponse](ctx, c, "POST", OIDCTokenPath, nil)
+}
+
 // Stacks returns all stacks for the given organization.
 func (c *Client) Stacks(ctx context.Context, orgUUID string, status stack.FilterStatus) (StacksResponse, error) {
 	path := path.Join(StacksPath, orgUUID)
@@ -112,6 +125,21 @@ func (c *Client) UpdateDeploymentStacks(ctx context.Context, orgUUID string, dep
 	return err
 }
 
+// CreateStackDrift reports the drift detection result of a stack.
+func (c *Client) CreateStackDrift(ctx context.Context, orgUUID string, payload DriftStackPayloadRequest) error {
+	err := payload.Validate()
+	if err != nil {
+		return errors.E(err, "failed to prepare the request")
+	}
+	_, err = Post[EmptyResponse](ctx, c, payload, DriftsPath, orgUUID)
+	return err
+}
+
+// StackDrifts returns the drifts of the given stack.
+func (c *Client) StackDrifts(ctx context.Context, orgUUID string, stackID int) (Drifts, error) {
+	return Get[Drifts](ctx, c, StacksPath, orgUUID, strconv.Itoa(stackID), "drifts")
+}
+
 // Get requests the endpoint components list making a GET request and decode the response into the
 // entity T if validates successfully.
 func Get[T Resource](ctx context.Context, client *Client, endpoint ...string) (entity T, err error) {
@@ -241,6 +241,81 @@ func TestCloudMemberOrganizations(t *testing.T) {
 	}
 }
 
+func TestCloudExchangeOIDCToken(t *testing.T) {
+	type want struct {
+		res cloud.OIDCTokenResponse
+		err error
+	}
+	type testcase struct {
+		name       string
+		statusCode int
+		body       string
+		want       want
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:       "untrusted token",
+			statusCode: http.StatusUnauthorized,
+			want: want{
+				err: errors.E(cloud.ErrUnexpectedStatus),
+			},
+		},
+		{
+			name:       "missing token field",
+			statusCode: http.StatusOK,
+			body:       `{"expires_in": 3600}`,
+			want: want{
+				err: errors.E(cloud.ErrUnexpectedResponseBody),
+			},
+		},
+		{
+			name:       "invalid expires_in field",
+			statusCode: http.StatusOK,
+			body:       `{"token": "abc", "expires_in": -1}`,
+			want: want{
+				err: errors.E(cloud.ErrUnexpectedResponseBody),
+			},
+		},
+		{
+			name:       "valid exchange",
+			statusCode: http.StatusOK,
+			body:       `{"token": "abc", "expires_in": 3600}`,
+			want: want{
+				res: cloud.OIDCTokenResponse{
+					Token:     "abc",
+					ExpiresIn: 3600,
+				},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(tc.statusCode, tc.body, nil)
+			defer s.Close()
+
+			sdk := cloud.Client{
+				BaseURL:    s.URL,
+				HTTPClient: s.Client(),
+				Credential: credential(),
+			}
+
+			const timeout = 3 * time.Second
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			res, err := sdk.ExchangeOIDCToken(ctx)
+			errtest.Assert(t, err, tc.want.err)
+			if err != nil {
+				return
+			}
+
+			assert.EqualStrings(t, tc.want.res.Token, res.Token)
+			assert.EqualInts(t, tc.want.res.ExpiresIn, res.ExpiresIn)
+		})
+	}
+}
+
 func TestCloudStacks(t *testing.T) {
 	type want struct {
 		stacks cloud.StacksResponse
@@ -506,6 +581,207 @@ func TestCloudStacks(t *testing.T) {
 	}
 }
 
+func TestCloudCreateStackDrift(t *testing.T) {
+	validPayload := cloud.DriftStackPayloadRequest{
+		Repository: "github.com/terramate-io/terramate",
+		Path:       "/stack",
+		MetaID:     "stack-id",
+		Status:     stack.Drifted,
+		Details: &cloud.DriftDetails{
+			Provisioner:    "terraform",
+			ChangesetASCII: "1 to add, 0 to change, 0 to destroy.",
+		},
+		Command: "terraform plan -detailed-exitcode",
+	}
+
+	type testcase struct {
+		name       string
+		payload    func() cloud.DriftStackPayloadRequest
+		statusCode int
+		want       error
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "missing meta_id",
+			payload: func() cloud.DriftStackPayloadRequest {
+				p := validPayload
+				p.MetaID = ""
+				return p
+			},
+			statusCode: http.StatusNoContent,
+			want:       errors.E("missing \"meta_id\" field"),
+		},
+		{
+			name: "invalid status",
+			payload: func() cloud.DriftStackPayloadRequest {
+				p := validPayload
+				p.Status = 0
+				return p
+			},
+			statusCode: http.StatusNoContent,
+			want:       errors.E(stack.ErrInvalidStatus),
+		},
+		{
+			name: "missing provisioner",
+			payload: func() cloud.DriftStackPayloadRequest {
+				p := validPayload
+				p.Details = &cloud.DriftDetails{}
+				return p
+			},
+			statusCode: http.StatusNoContent,
+			want:       errors.E("missing \"provisioner\" field"),
+		},
+		{
+			name: "missing cmd",
+			payload: func() cloud.DriftStackPayloadRequest {
+				p := validPayload
+				p.Command = ""
+				return p
+			},
+			statusCode: http.StatusNoContent,
+			want:       errors.E("missing \"cmd\" field"),
+		},
+		{
+			name: "server error",
+			payload: func() cloud.DriftStackPayloadRequest {
+				return validPayload
+			},
+			statusCode: http.StatusInternalServerError,
+			want:       errors.E(cloud.ErrUnexpectedStatus),
+		},
+		{
+			name: "drift created",
+			payload: func() cloud.DriftStackPayloadRequest {
+				return validPayload
+			},
+			statusCode: http.StatusNoContent,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(tc.statusCode, "", nil)
+			defer s.Close()
+
+			sdk := cloud.Client{
+				BaseURL:    s.URL,
+				HTTPClient: s.Client(),
+				Credential: credential(),
+			}
+
+			const timeout = 3 * time.Second
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			err := sdk.CreateStackDrift(ctx, "org-uuid", tc.payload())
+			errtest.Assert(t, err, tc.want)
+		})
+	}
+}
+
+func TestCloudStackDrifts(t *testing.T) {
+	type want struct {
+		drifts cloud.Drifts
+		err    error
+	}
+	type testcase struct {
+		name       string
+		statusCode int
+		body       string
+		want       want
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			want: want{
+				err: errors.E(cloud.ErrNotFound),
+			},
+		},
+		{
+			name:       "no drifts",
+			statusCode: http.StatusOK,
+			body:       `[]`,
+			want: want{
+				drifts: cloud.Drifts{},
+			},
+		},
+		{
+			name:       "missing status",
+			statusCode: http.StatusOK,
+			body:       `[{"id": 1, "cmd": "terraform plan"}]`,
+			want: want{
+				err: errors.E(cloud.ErrUnexpectedResponseBody),
+			},
+		},
+		{
+			name:       "drifts",
+			statusCode: http.StatusOK,
+			body: `[
+				{
+					"id": 1,
+					"status": "ok",
+					"cmd": "terraform plan -detailed-exitcode"
+				},
+				{
+					"id": 2,
+					"status": "drifted",
+					"drift_details": {
+						"provisioner": "terraform",
+						"changeset_ascii": "1 to add"
+					},
+					"cmd": "terraform plan -detailed-exitcode"
+				}
+			]`,
+			want: want{
+				drifts: cloud.Drifts{
+					{
+						ID:      1,
+						Status:  stack.OK,
+						Command: "terraform plan -detailed-exitcode",
+					},
+					{
+						ID:     2,
+						Status: stack.Drifted,
+						Details: &cloud.DriftDetails{
+							Provisioner:    "terraform",
+							ChangesetASCII: "1 to add",
+						},
+						Command: "terraform plan -detailed-exitcode",
+					},
+				},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(tc.statusCode, tc.body, nil)
+			defer s.Close()
+
+			sdk := cloud.Client{
+				BaseURL:    s.URL,
+				HTTPClient: s.Client(),
+				Credential: credential(),
+			}
+
+			const timeout = 3 * time.Second
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			drifts, err := sdk.StackDrifts(ctx, "org-uuid", 1)
+			errtest.Assert(t, err, tc.want.err)
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(drifts, tc.want.drifts); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
 func newTestServer(statusCode int, body string, headers http.Header) *httptest.Server {
 	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if len(headers) > 0 {
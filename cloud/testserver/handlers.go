@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/julienschmidt/httprouter"
@@ -48,6 +49,21 @@ func (userHandler *userHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request
 	))
 }
 
+// OIDCExchangedToken is the token returned by the OIDC token exchange for any
+// OIDC token.
+const OIDCExchangedToken = "terramate-cloud-token"
+
+func (oidcHandler *oidcTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write([]byte(
+		fmt.Sprintf(`{"token": "%s", "expires_in": 3600}`, OIDCExchangedToken),
+	))
+}
+
 func (dhandler *deploymentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	params := httprouter.ParamsFromContext(r.Context())
 	orguuid := params.ByName("orguuid")
@@ -193,6 +209,9 @@ func (handler *stackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Add("Content-Type", "application/json")
 
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
 	if r.Method == "GET" {
 		var resp cloud.StacksResponse
 		var stacks []cloud.Stack
@@ -271,6 +290,131 @@ func (handler *stackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusMethodNotAllowed)
 }
 
+func (dhandler *driftHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	orguuid := params.ByName("orguuid")
+
+	stacks := dhandler.stacks
+	stacks.mu.Lock()
+	defer stacks.mu.Unlock()
+
+	w.Header().Add("Content-Type", "application/json")
+
+	if r.Method == "GET" {
+		stackid, err := strconv.Atoi(params.ByName("stackid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		if _, ok := stacks.stacks[orguuid][stackid]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		drifts := dhandler.drifts[orguuid][stackid]
+		if drifts == nil {
+			drifts = cloud.Drifts{}
+		}
+		data, err := json.Marshal(drifts)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		_, _ = w.Write(data)
+		return
+	}
+
+	if r.Method == "POST" {
+		defer func() { _ = r.Body.Close() }()
+		data, _ := io.ReadAll(r.Body)
+		var p cloud.DriftStackPayloadRequest
+		err := json.Unmarshal(data, &p)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+
+		err = p.Validate()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+
+		// drift commit_sha is not required but must be present in all test cases.
+		if p.CommitSHA == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`commit_sha is missing`))
+			return
+		}
+
+		if _, ok := stacks.stacks[orguuid]; !ok {
+			stacks.stacks[orguuid] = make(map[int]cloud.Stack)
+		}
+		if _, ok := stacks.statuses[orguuid]; !ok {
+			stacks.statuses[orguuid] = make(map[int]stack.Status)
+		}
+		if _, ok := dhandler.drifts[orguuid]; !ok {
+			dhandler.drifts[orguuid] = make(map[int]cloud.Drifts)
+		}
+
+		st, found := findStack(stacks.stacks[orguuid], p.Repository, p.MetaID)
+		if !found {
+			st = cloud.Stack{
+				ID:         nextStackID(stacks.stacks[orguuid]),
+				Repository: p.Repository,
+				MetaID:     p.MetaID,
+				Status:     stack.Unknown,
+			}
+		}
+		st.Path = p.Path
+		st.MetaName = p.MetaName
+		st.MetaDescription = p.MetaDescription
+		st.MetaTags = p.MetaTags
+
+		// a failed drift detection tells nothing about the stack status.
+		if p.Status == stack.OK || p.Status == stack.Drifted {
+			st.Status = p.Status
+		}
+
+		stacks.stacks[orguuid][st.ID] = st
+		stacks.statuses[orguuid][st.ID] = st.Status
+
+		dhandler.nextDriftID++
+		dhandler.drifts[orguuid][st.ID] = append(dhandler.drifts[orguuid][st.ID], cloud.Drift{
+			ID:      dhandler.nextDriftID,
+			Status:  p.Status,
+			Details: p.Details,
+			Command: p.Command,
+		})
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+func findStack(stacks map[int]cloud.Stack, repository, metaID string) (cloud.Stack, bool) {
+	for _, st := range stacks {
+		if st.Repository == repository && st.MetaID == metaID {
+			return st, true
+		}
+	}
+	return cloud.Stack{}, false
+}
+
+func nextStackID(stacks map[int]cloud.Stack) int {
+	next := 1
+	for id := range stacks {
+		if id >= next {
+			next = id + 1
+		}
+	}
+	return next
+}
+
 func newStackEndpoint() *stackHandler {
 	return &stackHandler{
 		stacks:   make(map[string]map[int]cloud.Stack),
@@ -278,6 +422,13 @@ func newStackEndpoint() *stackHandler {
 	}
 }
 
+func newDriftEndpoint(stacks *stackHandler) *driftHandler {
+	return &driftHandler{
+		stacks: stacks,
+		drifts: make(map[string]map[int]cloud.Drifts),
+	}
+}
+
 func newDeploymentEndpoint() *deploymentHandler {
 	return &deploymentHandler{
 		deployments: make(map[string]map[string]map[int64]cloud.DeploymentStackRequest),
@@ -299,18 +450,28 @@ func RouterWith(enabled map[string]bool) *httprouter.Router {
 		router.Handler("GET", cloud.UsersPath, &userHandler{})
 	}
 
+	stackHandler := newStackEndpoint()
 	if enabled[cloud.StacksPath] {
-		stackHandler := newStackEndpoint()
 		router.Handler("GET", cloud.StacksPath+"/:orguuid", stackHandler)
 
 		// not a real TMC handler, only used by tests to populate the stacks state.
 		router.Handler("PUT", cloud.StacksPath+"/:orguuid/:stackid", stackHandler)
 	}
 
+	if enabled[cloud.DriftsPath] {
+		driftHandler := newDriftEndpoint(stackHandler)
+		router.Handler("POST", cloud.DriftsPath+"/:orguuid", driftHandler)
+		router.Handler("GET", cloud.StacksPath+"/:orguuid/:stackid/drifts", driftHandler)
+	}
+
 	if enabled[cloud.MembershipsPath] {
 		router.Handler("GET", cloud.MembershipsPath, &membershipHandler{})
 	}
 
+	if enabled[cloud.OIDCTokenPath] {
+		router.Handler("POST", cloud.OIDCTokenPath, &oidcTokenHandler{})
+	}
+
 	deploymentEndpoint := newDeploymentEndpoint()
 	if enabled[cloud.DeploymentsPath] {
 		router.Handler("GET", fmt.Sprintf("%s/:orguuid/:deployuuid/stacks", cloud.DeploymentsPath), deploymentEndpoint)
@@ -326,10 +487,20 @@ func RouterWith(enabled map[string]bool) *httprouter.Router {
 type (
 	userHandler       struct{}
 	membershipHandler struct{}
+	oidcTokenHandler  struct{}
 	stackHandler      struct {
+		mu       sync.Mutex
 		stacks   map[string]map[int]cloud.Stack
 		statuses map[string]map[int]stack.Status
 	}
+	driftHandler struct {
+		// stacks are shared with the stacks endpoint, so the drifts
+		// update the stack statuses.
+		stacks      *stackHandler
+		nextDriftID int
+		// map of organization -> (map of stack_id -> drifts)
+		drifts map[string]map[int]cloud.Drifts
+	}
 	deploymentHandler struct {
 		nextStackID int64
 		// as hacky as it can get:
@@ -347,5 +518,7 @@ func EnableAllConfig() map[string]bool {
 		cloud.MembershipsPath: true,
 		cloud.DeploymentsPath: true,
 		cloud.StacksPath:      true,
+		cloud.DriftsPath:      true,
+		cloud.OIDCTokenPath:   true,
 	}
 }
@@ -101,14 +101,26 @@ type (
 	}
 
 	// DeploymentMetadata stores the metadata available in the target platform.
-	// For now, we only support GitHub Metadata.
+	// The common fields are set for every platform and the platform specific
+	// fields are only set for the detected platform.
 	// It's marshaled as a flat hashmap of values.
 	// Note: no sensitive information must be stored here because it could be logged.
-	DeploymentMetadata GitHubMetadata
+	DeploymentMetadata struct {
+		Platform string `json:"platform"`
+
+		DeploymentBranch            string `json:"deployment_branch,omitempty"`
+		DeploymentCommitSHA         string `json:"deployment_commit_sha,omitempty"`
+		DeploymentCommitTitle       string `json:"deployment_commit_title,omitempty"`
+		DeploymentCommitDescription string `json:"deployment_commit_description,omitempty"`
+		DeploymentTriggeredBy       string `json:"deployment_triggered_by,omitempty"`
+
+		*GitHubMetadata
+		*GitLabMetadata
+		*BitbucketMetadata
+	}
 
 	// GitHubMetadata stores the GitHub related metadata.
 	GitHubMetadata struct {
-		Platform                    string `json:"platform"`
 		PullRequestAuthorLogin      string `json:"pull_request_author_login,omitempty"`
 		PullRequestAuthorAvatarURL  string `json:"pull_request_author_avatar_url,omitempty"`
 		PullRequestAuthorGravatarID string `json:"pull_request_author_gravatar_id,omitempty"`
@@ -132,15 +144,9 @@ type (
 		PullRequestClosedAt  time.Time `json:"pull_request_closed_at,omitempty"`
 		PullRequestMergedAt  time.Time `json:"pull_request_merged_at,omitempty"`
 
-		DeploymentBranch string `json:"deployment_branch,omitempty"`
-
 		DeploymentCommitVerified       *bool  `json:"deployment_commit_verified,omitempty"`
 		DeploymentCommitVerifiedReason string `json:"deployment_commit_verified_reason,omitempty"`
 
-		DeploymentCommitSHA         string `json:"deployment_commit_sha,omitempty"`
-		DeploymentCommitTitle       string `json:"deployment_commit_title,omitempty"`
-		DeploymentCommitDescription string `json:"deployment_commit_description,omitempty"`
-
 		DeploymentCommitAuthorLogin      string    `json:"deployment_commit_author_login,omitempty"`
 		DeploymentCommitAuthorAvatarURL  string    `json:"deployment_commit_author_avatar_url,omitempty"`
 		DeploymentCommitAuthorGravatarID string    `json:"deployment_commit_author_gravatar_id,omitempty"`
@@ -154,8 +160,36 @@ type (
 		DeploymentCommitCommitterGitName    string    `json:"deployment_commit_committer_git_name,omitempty"`
 		DeploymentCommitCommitterGitEmail   string    `json:"deployment_commit_committer_git_email,omitempty"`
 		DeploymentCommitCommitterGitDate    time.Time `json:"deployment_commit_committer_git_date,omitempty"`
+	}
+
+	// GitLabMetadata stores the GitLab CI related metadata.
+	GitLabMetadata struct {
+		GitlabProjectID    string `json:"gitlab_project_id,omitempty"`
+		GitlabPipelineID   string `json:"gitlab_pipeline_id,omitempty"`
+		GitlabJobID        string `json:"gitlab_job_id,omitempty"`
+		GitlabCommitAuthor string `json:"gitlab_commit_author,omitempty"`
 
-		DeploymentTriggeredBy string `json:"deployment_triggered_by,omitempty"`
+		GitlabMergeRequestAuthorUsername  string     `json:"gitlab_merge_request_author_username,omitempty"`
+		GitlabMergeRequestAuthorName      string     `json:"gitlab_merge_request_author_name,omitempty"`
+		GitlabMergeRequestAuthorAvatarURL string     `json:"gitlab_merge_request_author_avatar_url,omitempty"`
+		GitlabMergeRequestState           string     `json:"gitlab_merge_request_state,omitempty"`
+		GitlabMergeRequestSourceBranch    string     `json:"gitlab_merge_request_source_branch,omitempty"`
+		GitlabMergeRequestTargetBranch    string     `json:"gitlab_merge_request_target_branch,omitempty"`
+		GitlabMergeRequestCreatedAt       *time.Time `json:"gitlab_merge_request_created_at,omitempty"`
+		GitlabMergeRequestUpdatedAt       *time.Time `json:"gitlab_merge_request_updated_at,omitempty"`
+		GitlabMergeRequestMergedAt        *time.Time `json:"gitlab_merge_request_merged_at,omitempty"`
+	}
+
+	// BitbucketMetadata stores the Bitbucket Pipelines related metadata.
+	BitbucketMetadata struct {
+		BitbucketWorkspace         string `json:"bitbucket_workspace,omitempty"`
+		BitbucketRepoSlug          string `json:"bitbucket_repo_slug,omitempty"`
+		BitbucketPipelineUUID      string `json:"bitbucket_pipeline_uuid,omitempty"`
+		BitbucketBuildNumber       string `json:"bitbucket_build_number,omitempty"`
+		BitbucketStepTriggererUUID string `json:"bitbucket_step_triggerer_uuid,omitempty"`
+
+		BitbucketPullRequestID                string `json:"bitbucket_pull_request_id,omitempty"`
+		BitbucketPullRequestDestinationBranch string `json:"bitbucket_pull_request_destination_branch,omitempty"`
 	}
 
 	// DeploymentReviewRequest is the review_request object.
@@ -169,6 +203,47 @@ type (
 		URL         string `json:"url"`
 	}
 
+	// OIDCTokenResponse is the response of the OIDC token exchange.
+	OIDCTokenResponse struct {
+		Token string `json:"token"`
+		// ExpiresIn is the number of seconds the token is valid for.
+		ExpiresIn int `json:"expires_in"`
+	}
+
+	// DriftStackPayloadRequest is the request payload for the creation of
+	// stack drifts.
+	DriftStackPayloadRequest struct {
+		Repository      string              `json:"repository"`
+		Path            string              `json:"path"`
+		MetaID          string              `json:"meta_id"`
+		MetaName        string              `json:"meta_name,omitempty"`
+		MetaDescription string              `json:"meta_description,omitempty"`
+		MetaTags        []string            `json:"meta_tags,omitempty"`
+		CommitSHA       string              `json:"commit_sha,omitempty"`
+		Status          stack.Status        `json:"drift_status"`
+		Details         *DriftDetails       `json:"drift_details,omitempty"`
+		Metadata        *DeploymentMetadata `json:"metadata,omitempty"`
+		Command         string              `json:"cmd"`
+	}
+
+	// DriftDetails represents the details of a drift, like the changes
+	// planned by the provisioner.
+	DriftDetails struct {
+		Provisioner    string `json:"provisioner"`
+		ChangesetASCII string `json:"changeset_ascii,omitempty"`
+	}
+
+	// Drift represents a drift detection run of a stack.
+	Drift struct {
+		ID      int           `json:"id"`
+		Status  stack.Status  `json:"status"`
+		Details *DriftDetails `json:"drift_details,omitempty"`
+		Command string        `json:"cmd"`
+	}
+
+	// Drifts is a list of drifts.
+	Drifts []Drift
+
 	// UpdateDeploymentStack is the request payload item for updating the deployment status.
 	UpdateDeploymentStack struct {
 		StackID int               `json:"stack_id"`
@@ -196,6 +271,11 @@ var (
 	_ = Resource(UpdateDeploymentStack{})
 	_ = Resource(UpdateDeploymentStacks{})
 	_ = Resource(DeploymentReviewRequest{})
+	_ = Resource(OIDCTokenResponse{})
+	_ = Resource(DriftStackPayloadRequest{})
+	_ = Resource(DriftDetails{})
+	_ = Resource(Drift{})
+	_ = Resource(Drifts{})
 	_ = Resource(EmptyResponse(""))
 )
 
@@ -331,6 +411,71 @@ func (rr DeploymentReviewRequest) Validate() error {
 	return nil
 }
 
+// Validate the OIDC token exchange response.
+func (r OIDCTokenResponse) Validate() error {
+	if r.Token == "" {
+		return errors.E(`missing "token" field`)
+	}
+	if r.ExpiresIn < 0 {
+		return errors.E(`invalid "expires_in" of value %d`, r.ExpiresIn)
+	}
+	return nil
+}
+
+// Validate the drift stack payload.
+func (d DriftStackPayloadRequest) Validate() error {
+	if d.Repository == "" {
+		return errors.E(`missing "repository" field`)
+	}
+	if d.Path == "" {
+		return errors.E(`missing "path" field`)
+	}
+	if d.MetaID == "" {
+		return errors.E(`missing "meta_id" field`)
+	}
+	if strings.ToLower(d.MetaID) != d.MetaID {
+		return errors.E(`"meta_id" requires a lowercase string but %s provided`, d.MetaID)
+	}
+	if err := d.Status.Validate(); err != nil {
+		return err
+	}
+	if d.Details != nil {
+		if err := d.Details.Validate(); err != nil {
+			return err
+		}
+	}
+	if d.Metadata != nil {
+		if err := d.Metadata.Validate(); err != nil {
+			return err
+		}
+	}
+	if d.Command == "" {
+		return errors.E(`missing "cmd" field`)
+	}
+	return nil
+}
+
+// Validate the drift details.
+func (d DriftDetails) Validate() error {
+	if d.Provisioner == "" {
+		return errors.E(`missing "provisioner" field`)
+	}
+	return nil
+}
+
+// Validate the drift object.
+func (d Drift) Validate() error {
+	if d.Details != nil {
+		if err := d.Details.Validate(); err != nil {
+			return err
+		}
+	}
+	return d.Status.Validate()
+}
+
+// Validate the list of drifts.
+func (ds Drifts) Validate() error { return validateResourceList(ds...) }
+
 // Validate the UpdateDeploymentStack object.
 func (d UpdateDeploymentStack) Validate() error {
 	if d.StackID == 0 {
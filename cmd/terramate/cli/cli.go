@@ -128,21 +128,36 @@ type cliSpec struct {
 	List struct {
 		Why                bool   `help:"Shows the reason why the stack has changed"`
 		ExperimentalStatus string `help:"Filter by status"`
+		Format             string `default:"text" enum:"text,json" help:"Output format: 'text' or 'json'"`
 	} `cmd:"" help:"List stacks"`
 
 	Run struct {
-		CloudSyncDeployment   bool     `default:"false" help:"Enable synchronization of stack execution with the Terramate Cloud"`
-		DisableCheckGenCode   bool     `default:"false" help:"Disable outdated generated code check"`
-		DisableCheckGitRemote bool     `default:"false" help:"Disable checking if local default branch is updated with remote"`
-		ContinueOnError       bool     `default:"false" help:"Continue executing in other stacks in case of error"`
-		NoRecursive           bool     `default:"false" help:"Do not recurse into child stacks"`
-		DryRun                bool     `default:"false" help:"Plan the execution but do not execute it"`
-		Reverse               bool     `default:"false" help:"Reverse the order of execution"`
-		Eval                  bool     `default:"false" help:"Evaluate command line arguments as HCL strings"`
-		Command               []string `arg:"" name:"cmd" predictor:"file" passthrough:"" help:"Command to execute"`
+		CloudSyncDeployment   bool          `default:"false" help:"Enable synchronization of stack execution with the Terramate Cloud"`
+		DisableCheckGenCode   bool          `default:"false" help:"Disable outdated generated code check"`
+		DisableCheckGitRemote bool          `default:"false" help:"Disable checking if local default branch is updated with remote"`
+		ContinueOnError       bool          `default:"false" help:"Continue executing in other stacks in case of error"`
+		Parallel              int           `default:"1" help:"Maximum number of stacks executed at the same time, respecting the execution order"`
+		Timeout               time.Duration `help:"Maximum duration of the whole run (e.g. 30m). The commands still running are killed and the remaining stacks are not started"`
+		OutputMode            string        `default:"stream" enum:"stream,prefix,group" help:"How the output of the stacks is written: 'stream', 'prefix' (each line prefixed by the stack path) or 'group' (output of each stack written when it finishes)"`
+		OutputDir             string        `predictor:"file" help:"Also write the stdout and stderr of each stack to files inside the given directory"`
+		Summary               bool          `default:"false" help:"Print a summary table of the executed stacks when the run finishes"`
+		ReportFile            string        `predictor:"file" help:"Write a report of the executed stacks to the given file"`
+		ReportFormat          string        `default:"auto" enum:"auto,json,junit" help:"Format of --report-file: 'json', 'junit' or 'auto' (JUnit XML if the file has the .xml extension, JSON otherwise)"`
+		NoRecursive           bool          `default:"false" help:"Do not recurse into child stacks"`
+		DryRun                bool          `default:"false" help:"Plan the execution but do not execute it"`
+		Format                string        `default:"text" enum:"text,json" help:"Output format of --dry-run: 'text' or 'json'"`
+		Reverse               bool          `default:"false" help:"Reverse the order of execution"`
+		Resume                bool          `default:"false" help:"Skip the stacks where the same command already succeeded on the last run for the current commit"`
+		Eval                  bool          `default:"false" help:"Evaluate command line arguments as HCL strings"`
+		Command               []string      `arg:"" name:"cmd" predictor:"file" passthrough:"" help:"Command to execute"`
 	} `cmd:"" help:"Run command in the stacks"`
 
-	Generate struct{} `cmd:"" help:"Generate terraform code for stacks"`
+	Generate struct {
+		Check              bool   `help:"Show the changes to the generated code as unified diffs without applying them, exit with 0 if the code is up to date, 1 otherwise"`
+		DryRun             bool   `help:"Same as --check"`
+		Format             string `default:"text" enum:"text,json" help:"Output format: 'text' or 'json'"`
+		IncludeRootContext bool   `help:"Also generate the generate_file blocks with context=root when only part of the stacks are selected"`
+	} `cmd:"" help:"Generate terraform code for stacks"`
 
 	InstallCompletions kongplete.InstallCompletions `cmd:"" help:"Install shell completions"`
 
@@ -163,7 +178,10 @@ type cliSpec struct {
 		Globals struct{} `cmd:"" help:"List globals for all stacks"`
 
 		Generate struct {
-			Debug struct{} `cmd:"" help:"Shows generate debug information"`
+			Debug  struct{} `cmd:"" help:"Shows generate debug information"`
+			Origin struct {
+				File string `arg:"" name:"file" predictor:"file" help:"Path of the generated file"`
+			} `cmd:"" help:"Shows the generate block that generated a file and if it was edited manually"`
 		} `cmd:"" help:"Experimental generate commands"`
 
 		RunGraph struct {
@@ -173,6 +191,7 @@ type cliSpec struct {
 
 		RunOrder struct {
 			Basedir string `arg:"" optional:"true" help:"Base directory to search stacks"`
+			Format  string `default:"text" enum:"text,json" help:"Output format: 'text' or 'json'"`
 		} `cmd:"" help:"Show the topological ordering of the stacks"`
 
 		RunEnv struct{} `cmd:"" help:"List run environment variables for all stacks"`
@@ -180,8 +199,8 @@ type cliSpec struct {
 		Vendor struct {
 			Download struct {
 				Dir       string `short:"d" predictor:"file" default:"" help:"dir to vendor downloaded project"`
-				Source    string `arg:"" name:"source" help:"Terraform module source URL, must be Git/Github and should not contain a reference"`
-				Reference string `arg:"" name:"ref" help:"Reference of the Terraform module to vendor"`
+				Source    string `arg:"" name:"source" help:"Terraform module source URL, must be Git/Github or a Terraform Registry address and should not contain a reference"`
+				Reference string `arg:"" name:"ref" help:"Reference of the Terraform module to vendor or the version constraint of Terraform Registry modules"`
 			} `cmd:"" help:"Downloads a Terraform module and stores it on the project vendor dir"`
 		} `cmd:"" help:"Manages vendored Terraform modules"`
 
@@ -202,6 +221,14 @@ type cliSpec struct {
 			Vars   []string          `arg:"" help:"variable to be retrieved" name:"var" passthrough:""`
 		} `cmd:"" help:"Get configuration value"`
 
+		Drift struct {
+			Run struct {
+				Parallel   int      `default:"1" help:"Maximum number of stacks executed at the same time, respecting the execution order"`
+				OutputMode string   `default:"stream" enum:"stream,prefix,group" help:"How the output of the stacks is written: 'stream', 'prefix' (each line prefixed by the stack path) or 'group' (output of each stack written when it finishes)"`
+				Command    []string `arg:"" name:"cmd" predictor:"file" passthrough:"" help:"Drift detection command, which must exit with 2 when the stack has drifted (eg.: terraform plan -detailed-exitcode)"`
+			} `cmd:"" help:"Run a drift detection command in the stacks and report the results to Terramate Cloud"`
+		} `cmd:"" help:"Drift detection commands"`
+
 		Cloud struct {
 			Login struct{} `cmd:"" help:"login for cloud.terramate.io"`
 			Info  struct{} `cmd:"" help:"cloud information status"`
@@ -513,7 +540,8 @@ func (c *cli) run() {
 		c.setupGit()
 		c.runOnStacks()
 	case "generate":
-		c.generate()
+		c.setupGit()
+		c.generate(c.generateFilter())
 	case "experimental clone <srcdir> <destdir>":
 		c.cloneStack()
 	case "experimental trigger":
@@ -528,6 +556,8 @@ func (c *cli) run() {
 	case "experimental generate debug":
 		c.setupGit()
 		c.generateDebug()
+	case "experimental generate origin <file>":
+		c.generateOrigin()
 	case "experimental metadata":
 		c.setupGit()
 		c.printMetadata()
@@ -552,6 +582,11 @@ func (c *cli) run() {
 		log.Fatal().Msg("no variable specified")
 	case "experimental get-config-value <var>":
 		c.getConfigValue()
+	case "experimental drift run":
+		log.Fatal().Msg("no command specified")
+	case "experimental drift run <cmd>":
+		c.setupGit()
+		c.driftRun()
 	case "experimental cloud info":
 		c.cloudInfo()
 	default:
@@ -606,7 +641,7 @@ func (c *cli) vendorDownload() {
 
 	logger.Debug().Msg("vendoring")
 
-	report := download.Vendor(c.rootdir(), c.vendorDir(), parsedSource, eventsStream)
+	report := download.Vendor(c.rootdir(), c.vendorDir(), parsedSource, nil, eventsStream)
 
 	logger.Debug().Msg("finished vendoring, waiting for all vendor events to be handled")
 
@@ -704,6 +739,14 @@ func (c *cli) vendorDir() prj.Path {
 	return prj.NewPath(defaultVendorDir)
 }
 
+// stackManager creates the stack manager of the project, comparing changes
+// against the configured git base ref.
+func (c *cli) stackManager() *stack.Manager {
+	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr.SetVendorDir(c.vendorDir())
+	return mgr
+}
+
 func hasVendorDirConfig(cfg hcl.Config) bool {
 	return cfg.Vendor != nil && cfg.Vendor.Dir != ""
 }
@@ -713,7 +756,7 @@ func (c *cli) triggerStackByFilter() {
 		fatal(errors.E("trigger command expects either a stack path or the --experimental-status flag"))
 	}
 
-	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr := c.stackManager()
 	status := parseStatusFilter(c.parsedArgs.Experimental.Trigger.ExperimentalStatus)
 	stacksReport, err := c.listStacks(mgr, false, status)
 	if err != nil {
@@ -782,18 +825,31 @@ func (c *cli) cloneStack() {
 	c.output.MsgStdOut("Cloned stack %s to %s with success", srcstack, deststack)
 	c.output.MsgStdOut("Generating code on the new cloned stack")
 
-	c.generate()
+	c.generate(nil)
 }
 
-func (c *cli) generate() {
-	report, vendorReport := c.gencodeWithVendor()
+// generate generates the code selected by the filter, or of the whole project
+// if the filter is nil.
+func (c *cli) generate(filter *generate.Filter) {
+	if c.parsedArgs.Generate.Check || c.parsedArgs.Generate.DryRun {
+		c.checkGenerate(filter)
+		return
+	}
 
-	c.output.MsgStdOut(report.Full())
+	report, vendorReport := c.gencodeWithVendor(filter)
 
 	vendorReport.RemoveIgnoredByKind(download.ErrAlreadyVendored)
 
-	if !vendorReport.IsEmpty() {
-		c.output.MsgStdOut(vendorReport.String())
+	if c.parsedArgs.Generate.Format == formatJSON {
+		c.printGenerateReportJSON(report)
+		if !vendorReport.IsEmpty() {
+			c.output.MsgStdErr(vendorReport.String())
+		}
+	} else {
+		c.output.MsgStdOut(report.Full())
+		if !vendorReport.IsEmpty() {
+			c.output.MsgStdOut(vendorReport.String())
+		}
 	}
 
 	if report.HasFailures() || vendorReport.HasFailures() {
@@ -801,9 +857,57 @@ func (c *cli) generate() {
 	}
 }
 
-// gencodeWithVendor will generate code for the whole project providing automatic
-// vendoring of all tm_vendor calls.
-func (c *cli) gencodeWithVendor() (generate.Report, download.Report) {
+// checkGenerate shows the changes that the code generation would make to the
+// project, without changing any file. It exits with 1 if the generated code
+// is outdated or the generation fails.
+func (c *cli) checkGenerate(filter *generate.Filter) {
+	report := generate.DryRun(c.cfg(), c.vendorDir(), filter)
+
+	if c.parsedArgs.Generate.Format == formatJSON {
+		c.printGenerateReportJSON(report)
+	} else {
+		for _, change := range report.Changes {
+			stdfmt.Fprint(c.stdout, change.Diff())
+		}
+		if report.HasFailures() || report.CleanupErr != nil {
+			c.output.MsgStdErr(report.Full())
+		} else if len(report.Changes) == 0 {
+			c.output.MsgStdOut("Nothing to do, generated code is up to date")
+		}
+	}
+
+	if len(report.Changes) > 0 || report.HasFailures() || report.CleanupErr != nil {
+		os.Exit(1)
+	}
+}
+
+// generateFilter returns the filter of the stacks selected by the working
+// directory, --changed and --tags, or nil if the whole project is selected.
+// The generate_file blocks with context=root are only generated for a partial
+// selection if --include-root-context is set.
+func (c *cli) generateFilter() *generate.Filter {
+	if c.wd() == c.rootdir() && !c.parsedArgs.Changed && c.tags.IsEmpty() {
+		return nil
+	}
+
+	stacks, err := c.selectStacks()
+	if err != nil {
+		fatal(err, "computing selected stacks")
+	}
+
+	filter := &generate.Filter{
+		RootContext: c.parsedArgs.Generate.IncludeRootContext,
+	}
+	for _, st := range stacks {
+		filter.Stacks = append(filter.Stacks, st.Dir())
+	}
+	return filter
+}
+
+// gencodeWithVendor will generate code for the stacks selected by the filter,
+// or the whole project if it is nil, providing automatic vendoring of all
+// tm_vendor calls.
+func (c *cli) gencodeWithVendor(filter *generate.Filter) (generate.Report, download.Report) {
 	vendorProgressEvents := download.NewEventStream()
 	progressHandlerDone := c.handleVendorProgressEvents(vendorProgressEvents)
 
@@ -811,6 +915,7 @@ func (c *cli) gencodeWithVendor() (generate.Report, download.Report) {
 	vendorReports := download.HandleVendorRequests(
 		c.prj.rootdir,
 		vendorRequestEvents,
+		nil,
 		vendorProgressEvents,
 	)
 
@@ -818,7 +923,7 @@ func (c *cli) gencodeWithVendor() (generate.Report, download.Report) {
 
 	log.Debug().Msg("generating code")
 
-	report := generate.Do(c.cfg(), c.vendorDir(), vendorRequestEvents)
+	report := generate.DoFiltered(c.cfg(), c.vendorDir(), vendorRequestEvents, filter)
 
 	log.Debug().Msg("code generation finished, waiting for vendor requests to be handled")
 
@@ -1064,7 +1169,7 @@ func (c *cli) initTerraform() {
 
 	c.prj.root = *root
 
-	report, vendorReport := c.gencodeWithVendor()
+	report, vendorReport := c.gencodeWithVendor(nil)
 	if report.HasFailures() {
 		c.output.MsgStdOut("Code generation failed")
 		c.output.MsgStdOut(report.Minimal())
@@ -1119,7 +1224,7 @@ func (c *cli) initDir(baseDir string) error {
 			continue
 		}
 
-		if filepath.Ext(f.Name()) != ".tf" {
+		if !tf.IsTerraformFile(f.Name()) {
 			logger.Trace().Msgf("ignoring file %s", path)
 			continue
 		}
@@ -1250,7 +1355,7 @@ func (c *cli) createStack() {
 		fatal(err, "loading newly created stack")
 	}
 
-	report, vendorReport := c.gencodeWithVendor()
+	report, vendorReport := c.gencodeWithVendor(nil)
 	if report.HasFailures() {
 		c.output.MsgStdOut("Code generation failed")
 		c.output.MsgStdOut(report.Minimal())
@@ -1319,7 +1424,7 @@ func (c *cli) printStacks() {
 		log.Fatal().Msg("the --why flag must be used together with --changed")
 	}
 
-	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr := c.stackManager()
 
 	status := parseStatusFilter(c.parsedArgs.List.ExperimentalStatus)
 	report, err := c.listStacks(mgr, c.parsedArgs.Changed, status)
@@ -1329,6 +1434,22 @@ func (c *cli) printStacks() {
 
 	c.gitFileSafeguards(false)
 
+	if c.parsedArgs.List.Format == formatJSON {
+		var stacks []stackJSON
+		for _, entry := range c.filterStacks(report.Stacks) {
+			if _, ok := c.friendlyFmtDir(entry.Stack.Dir.String()); !ok {
+				continue
+			}
+			st := newStackJSON(entry.Stack)
+			if c.parsedArgs.List.Why {
+				st.Reason = entry.Reason
+			}
+			stacks = append(stacks, st)
+		}
+		c.printStacksJSON(stacks)
+		return
+	}
+
 	for _, entry := range c.filterStacks(report.Stacks) {
 		stack := entry.Stack
 
@@ -1359,14 +1480,14 @@ func parseStatusFilter(strStatus string) cloudstack.FilterStatus {
 }
 
 func (c *cli) printRunEnv() {
-	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr := c.stackManager()
 	report, err := c.listStacks(mgr, c.parsedArgs.Changed, cloudstack.NoFilter)
 	if err != nil {
 		fatal(err, "listing stacks")
 	}
 
 	for _, stackEntry := range c.filterStacks(report.Stacks) {
-		envVars, err := run.LoadEnv(c.cfg(), stackEntry.Stack)
+		envVars, err := run.LoadEnvInfo(c.cfg(), stackEntry.Stack)
 		if err != nil {
 			fatal(err, "loading stack run environment")
 		}
@@ -1374,7 +1495,7 @@ func (c *cli) printRunEnv() {
 		c.output.MsgStdOut("\nstack %q:", stackEntry.Stack.Dir)
 
 		for _, envVar := range envVars {
-			c.output.MsgStdOut("\t%s", envVar)
+			c.output.MsgStdOut("\t%s\t# %s", envVar.Redacted(), envVar.Origin)
 		}
 	}
 }
@@ -1538,6 +1659,11 @@ func (c *cli) printRunOrder() {
 		}
 	}
 
+	if c.parsedArgs.Experimental.RunOrder.Format == formatJSON {
+		c.printStacksJSON(sortableToStackJSON(orderedStacks))
+		return
+	}
+
 	for _, s := range orderedStacks {
 		c.output.MsgStdOut(s.Dir().String())
 	}
@@ -1590,6 +1716,50 @@ func (c *cli) generateDebug() {
 	}
 }
 
+func (c *cli) generateOrigin() {
+	file := c.parsedArgs.Experimental.Generate.Origin.File
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(c.wd(), file)
+	}
+	file = filepath.Clean(file)
+	if file != c.rootdir() && !strings.HasPrefix(file, c.rootdir()+string(filepath.Separator)) {
+		fatal(errors.E("file %s is outside the project", file))
+	}
+
+	manifest, err := generate.LoadManifest(c.cfg())
+	if err != nil {
+		fatal(err, "generate origin: loading manifest")
+	}
+
+	prjpath := prj.PrjAbsPath(c.rootdir(), file)
+	entry, ok := manifest.Lookup(prjpath)
+	if !ok {
+		fatal(errors.E("file %s is not recorded as generated in %s", prjpath, generate.ManifestFile))
+	}
+
+	status := "unmodified"
+	data, err := os.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		status = "missing"
+	case err != nil:
+		fatal(err, "generate origin: reading file")
+	case entry.Modified(string(data)):
+		status = "modified"
+	}
+
+	owner := "root context"
+	if entry.Stack != "" {
+		owner = entry.Stack
+	}
+
+	c.output.MsgStdOut("file:   %s", prjpath)
+	c.output.MsgStdOut("block:  %s %q", entry.Block, entry.Label)
+	c.output.MsgStdOut("origin: %s", entry.Origin)
+	c.output.MsgStdOut("stack:  %s", owner)
+	c.output.MsgStdOut("status: %s", status)
+}
+
 func (c *cli) printStacksGlobals() {
 	logger := log.With().
 		Str("action", "printStacksGlobals()").
@@ -1598,7 +1768,7 @@ func (c *cli) printStacksGlobals() {
 	logger.Trace().
 		Msg("Create new terramate manager.")
 
-	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr := c.stackManager()
 	report, err := c.listStacks(mgr, c.parsedArgs.Changed, cloudstack.NoFilter)
 	if err != nil {
 		fatal(err, "listing stacks globals: listing stacks")
@@ -1635,7 +1805,7 @@ func (c *cli) printMetadata() {
 	logger.Trace().
 		Msg("Create new terramate manager.")
 
-	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr := c.stackManager()
 	report, err := c.listStacks(mgr, c.parsedArgs.Changed, cloudstack.NoFilter)
 	if err != nil {
 		fatal(err, "loading metadata: listing stacks")
@@ -1699,7 +1869,7 @@ func (c *cli) checkGenCode() bool {
 }
 
 func (c *cli) ensureStackID() {
-	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr := c.stackManager()
 	report, err := c.listStacks(mgr, false, cloudstack.NoFilter)
 	if err != nil {
 		fatal(err, "listing stacks")
@@ -1974,7 +2144,7 @@ func (c *cli) runOnStacks() {
 
 	logger.Trace().Msg("Get order of stacks to run command on.")
 
-	orderedStacks, reason, err := run.Sort(c.cfg(), stacks)
+	orderedStacks, deps, reason, err := run.SortWithDeps(c.cfg(), stacks)
 	if err != nil {
 		if errors.IsKind(err, dag.ErrCycleDetected) {
 			fatal(err, "cycle detected: %s", reason)
@@ -1986,17 +2156,65 @@ func (c *cli) runOnStacks() {
 	if c.parsedArgs.Run.Reverse {
 		logger.Trace().Msg("Reversing stacks order.")
 		config.ReverseStacks(orderedStacks)
+		deps = deps.Reverse()
 	}
 
+	journal, err := run.NewJournal(
+		c.rootdir(),
+		c.parsedArgs.Run.Command,
+		c.runJournalCommit(),
+		c.parsedArgs.Run.Resume,
+	)
+	if err != nil {
+		fatal(err, "loading run journal")
+	}
+
+	if c.parsedArgs.Run.Resume {
+		var remaining config.List[*config.SortableStack]
+		for _, st := range orderedStacks {
+			if journal.IsCompleted(st.Dir()) {
+				logger.Info().
+					Stringer("stack", st.Dir()).
+					Msg("skipping stack completed on the previous run")
+				continue
+			}
+			remaining = append(remaining, st)
+		}
+		orderedStacks = remaining
+	}
+
+	if c.parsedArgs.Run.Format == formatJSON && !c.parsedArgs.Run.DryRun {
+		logger.Fatal().Msg("--format=json can only be used together with --dry-run")
+	}
+
+	if c.parsedArgs.Run.Parallel < 1 {
+		logger.Fatal().Msg("--parallel must be greater than zero")
+	}
+
+	stackEnvs := c.loadRunEnvs(orderedStacks)
+
 	if c.parsedArgs.Run.DryRun {
 		logger.Trace().
 			Msg("Do a dry run - get order without actually running command.")
+		if c.parsedArgs.Run.Format == formatJSON {
+			stacks := sortableToStackJSON(orderedStacks)
+			for i, st := range orderedStacks {
+				for _, env := range stackEnvs[st.Dir()] {
+					stacks[i].Env = append(stacks[i].Env, env.Redacted())
+				}
+			}
+			c.printStacksJSON(stacks)
+			return
+		}
 		if len(orderedStacks) > 0 {
 			c.output.MsgStdOut("The stacks will be executed using order below:")
 
 			for i, s := range orderedStacks {
 				stackdir, _ := c.friendlyFmtDir(s.Dir().String())
 				c.output.MsgStdOut("\t%d. %s (%s)", i, s.Name, stackdir)
+				for _, env := range stackEnvs[s.Dir()] {
+					c.output.MsgStdOut("\t\t%s", env.Redacted())
+				}
 			}
 		} else {
 			c.output.MsgStdOut("No stacks will be executed.")
@@ -2005,11 +2223,19 @@ func (c *cli) runOnStacks() {
 		return
 	}
 
+	timeout, retry := c.runTimeoutAndRetry()
+	deadline := c.runDeadline()
+
 	var runStacks []run.ExecContext
 	for _, st := range orderedStacks {
 		run := run.ExecContext{
-			Stack: st.Stack,
-			Cmd:   c.parsedArgs.Run.Command,
+			Stack:     st.Stack,
+			Cmd:       c.parsedArgs.Run.Command,
+			Env:       stackEnvs[st.Dir()],
+			DependsOn: deps[st.Dir()],
+			Timeout:   timeout,
+			Deadline:  deadline,
+			Retry:     retry,
 		}
 		if c.parsedArgs.Run.Eval {
 			run.Cmd = c.evalRunArgs(run.Stack, run.Cmd)
@@ -2019,7 +2245,14 @@ func (c *cli) runOnStacks() {
 
 	c.createCloudDeployment(runStacks)
 
+	if err := journal.Save(); err != nil {
+		fatal(err, "saving run journal")
+	}
+
+	report := run.NewReport()
+
 	beforeHook := func(s *config.Stack, cmd string) {
+		report.Before(s, cmd)
 		if !c.cloudEnabled() || !c.parsedArgs.Run.CloudSyncDeployment {
 			return
 		}
@@ -2027,6 +2260,14 @@ func (c *cli) runOnStacks() {
 	}
 
 	afterHook := func(s *config.Stack, err error) {
+		report.After(s, err)
+		if err == nil {
+			if err := journal.Complete(s.Dir); err != nil {
+				logger.Warn().Err(err).
+					Stringer("stack", s.Dir).
+					Msg("failed to record completed stack in the run journal")
+			}
+		}
 		if !c.cloudEnabled() || !c.parsedArgs.Run.CloudSyncDeployment {
 			return
 		}
@@ -2036,7 +2277,7 @@ func (c *cli) runOnStacks() {
 			status = deployment.OK
 		case errors.IsKind(err, run.ErrCanceled):
 			status = deployment.Canceled
-		case errors.IsKind(err, run.ErrFailed):
+		case errors.IsKind(err, run.ErrFailed), errors.IsKind(err, run.ErrTimeout):
 			status = deployment.Failed
 		default:
 			panic(errors.E(errors.ErrInternal, "unexpected run status"))
@@ -2045,22 +2286,144 @@ func (c *cli) runOnStacks() {
 		c.syncCloudDeployment(s, status)
 	}
 
+	outputDir := c.parsedArgs.Run.OutputDir
+	if outputDir != "" && !filepath.IsAbs(outputDir) {
+		outputDir = filepath.Join(c.wd(), outputDir)
+	}
+
+	output, err := run.NewOutput(
+		c.stdout,
+		c.stderr,
+		run.OutputMode(c.parsedArgs.Run.OutputMode),
+		outputDir,
+	)
+	if err != nil {
+		fatal(err, "setting up the stacks output")
+	}
+
 	err = run.ExecAll(
 		c.cfg(),
 		runStacks,
 		c.stdin,
-		c.stdout,
-		c.stderr,
+		output,
 		c.parsedArgs.Run.ContinueOnError,
+		c.parsedArgs.Run.Parallel,
 		beforeHook,
 		afterHook,
 	)
 
+	c.writeRunReport(report)
+
 	if err != nil {
 		fatal(err, "one or more commands failed")
 	}
 }
 
+// loadRunEnvs loads the run environment of the stacks.
+func (c *cli) loadRunEnvs(stacks config.List[*config.SortableStack]) map[prj.Path][]run.EnvVar {
+	envs := map[prj.Path][]run.EnvVar{}
+	for _, st := range stacks {
+		env, err := run.LoadEnvInfo(c.cfg(), st.Stack)
+		if err != nil {
+			fatal(err, "loading stack run environment")
+		}
+		envs[st.Dir()] = env
+	}
+	return envs
+}
+
+// runJournalCommit returns the commit identifying the run journal, which is
+// empty if the project is not a git repository.
+func (c *cli) runJournalCommit() string {
+	if !c.prj.isRepo {
+		return ""
+	}
+	return c.prj.headCommit()
+}
+
+// writeRunReport prints the summary table and writes the report file, if
+// requested by the user.
+func (c *cli) writeRunReport(report *run.Report) {
+	if c.parsedArgs.Run.Summary {
+		var summary strings.Builder
+		if err := report.WriteTable(&summary); err != nil {
+			fatal(err, "writing run summary")
+		}
+		c.output.MsgStdErr("\n%s", strings.TrimSuffix(summary.String(), "\n"))
+	}
+
+	reportFile := c.parsedArgs.Run.ReportFile
+	if reportFile == "" {
+		return
+	}
+	if !filepath.IsAbs(reportFile) {
+		reportFile = filepath.Join(c.wd(), reportFile)
+	}
+
+	format := c.parsedArgs.Run.ReportFormat
+	if format == "auto" {
+		format = formatJSON
+		if strings.EqualFold(filepath.Ext(reportFile), ".xml") {
+			format = "junit"
+		}
+	}
+
+	f, err := os.Create(reportFile)
+	if err != nil {
+		fatal(err, "creating report file")
+	}
+
+	if format == "junit" {
+		err = report.WriteJUnit(f)
+	} else {
+		err = report.WriteJSON(f)
+	}
+
+	errs := errors.L(err, f.Close())
+	if err := errs.AsError(); err != nil {
+		fatal(err, "writing report file %s", reportFile)
+	}
+
+	log.Debug().
+		Str("file", reportFile).
+		Str("format", format).
+		Msg("run report written")
+}
+
+// runTimeoutAndRetry returns the timeout and retry policy of the commands
+// executed on each stack, from the terramate.config.run configuration.
+func (c *cli) runTimeoutAndRetry() (time.Duration, run.RetryPolicy) {
+	var (
+		timeout time.Duration
+		retry   run.RetryPolicy
+	)
+
+	cfg := c.rootNode()
+	if cfg.Terramate != nil &&
+		cfg.Terramate.Config != nil &&
+		cfg.Terramate.Config.Run != nil {
+		runCfg := cfg.Terramate.Config.Run
+		timeout = runCfg.Timeout
+		if runCfg.Retry != nil {
+			retry = run.RetryPolicy{
+				Attempts:  runCfg.Retry.Attempts,
+				ExitCodes: runCfg.Retry.ExitCodes,
+				Backoff:   runCfg.Retry.Backoff,
+			}
+		}
+	}
+	return timeout, retry
+}
+
+// runDeadline returns the deadline of the whole run, from the --timeout flag.
+// The zero time means no deadline.
+func (c *cli) runDeadline() time.Time {
+	if c.parsedArgs.Run.Timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.parsedArgs.Run.Timeout)
+}
+
 func (c *cli) wd() string           { return c.prj.wd }
 func (c *cli) rootdir() string      { return c.prj.rootdir }
 func (c *cli) cfg() *config.Root    { return &c.prj.root }
@@ -2072,14 +2435,26 @@ func (c *cli) friendlyFmtDir(dir string) (string, bool) {
 }
 
 func (c *cli) computeSelectedStacks(ensureCleanRepo bool) (config.List[*config.SortableStack], error) {
+	stacks, err := c.selectStacks()
+	if err != nil {
+		return nil, err
+	}
+	c.gitFileSafeguards(ensureCleanRepo)
+	return stacks, nil
+}
+
+// selectStacks returns the stacks selected by the working directory, the
+// --changed and the tags filters, and the stacks they want, without checking
+// the repository state.
+func (c *cli) selectStacks() (config.List[*config.SortableStack], error) {
 	logger := log.With().
-		Str("action", "computeSelectedStacks()").
+		Str("action", "selectStacks()").
 		Str("workingDir", c.wd()).
 		Logger()
 
 	logger.Trace().Msg("Create new terramate manager.")
 
-	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr := c.stackManager()
 
 	logger.Trace().Msg("Get list of stacks.")
 
@@ -2088,8 +2463,6 @@ func (c *cli) computeSelectedStacks(ensureCleanRepo bool) (config.List[*config.S
 		return nil, err
 	}
 
-	c.gitFileSafeguards(ensureCleanRepo)
-
 	logger.Trace().Msg("Filter stacks by working directory.")
 
 	entries := c.filterStacks(report.Stacks)
@@ -6,18 +6,14 @@ package cli
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"os"
 	"strings"
 	"time"
 
-	"github.com/cli/go-gh/v2/pkg/auth"
-	"github.com/cli/go-gh/v2/pkg/repository"
 	"github.com/golang-jwt/jwt"
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/cloud"
 	"github.com/terramate-io/terramate/cloud/deployment"
-	"github.com/terramate-io/terramate/cmd/terramate/cli/github"
 	"github.com/terramate-io/terramate/cmd/terramate/cli/out"
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
@@ -32,6 +28,7 @@ const (
 	defaultCloudTimeout  = 60 * time.Second
 	defaultGoogleTimeout = defaultCloudTimeout
 	defaultGithubTimeout = defaultCloudTimeout
+	defaultGitlabTimeout = defaultCloudTimeout
 )
 
 // DisablingCloudMessage is the message displayed in the warning when disabling
@@ -72,6 +69,8 @@ type keyValue struct {
 
 func (c *cli) credentialPrecedence(output out.O) []credential {
 	return []credential{
+		newAPIKeyCredential(output),
+		newGenericOIDC(output, c.cloud.client.BaseURL, c.cloud.client.HTTPClient),
 		newGithubOIDC(output),
 		newGoogleCredential(output, c.cloud.client.IDPKey, c.clicfg),
 	}
@@ -201,11 +200,8 @@ func (c *cli) createCloudDeployment(runStacks []run.ExecContext) {
 	var (
 		err                 error
 		deploymentCommitSHA string
-		deploymentURL       string
-		reviewRequest       *cloud.DeploymentReviewRequest
-		metadata            *cloud.DeploymentMetadata
+		ci                  ciMetadata
 		normalizedRepo      string
-		ghRepo              string
 	)
 
 	if c.prj.isRepo {
@@ -213,7 +209,10 @@ func (c *cli) createCloudDeployment(runStacks []run.ExecContext) {
 		if err == nil {
 			normalizedRepo = cloud.NormalizeGitURI(repoURL)
 			if normalizedRepo != "local" {
-				reviewRequest, metadata, ghRepo = c.tryGithubMetadata(normalizedRepo)
+				ci = c.detectCIMetadata(ciRepository{
+					normalized: normalizedRepo,
+					headCommit: c.prj.headCommit(),
+				})
 			} else {
 				logger.Debug().Msg("skipping review_request for local repository")
 			}
@@ -224,23 +223,14 @@ func (c *cli) createCloudDeployment(runStacks []run.ExecContext) {
 		deploymentCommitSHA = c.prj.headCommit()
 	}
 
-	ghRunID := os.Getenv("GITHUB_RUN_ID")
-	ghAttempt := os.Getenv("GITHUB_RUN_ATTEMPT")
-	if ghRunID != "" && ghAttempt != "" && ghRepo != "" {
-		deploymentURL = fmt.Sprintf(
-			"https://github.com/%s/actions/runs/%s/attempts/%s",
-			ghRepo,
-			ghRunID,
-			ghAttempt,
-		)
-
+	if ci.deploymentURL != "" {
 		logger.Debug().
-			Str("deployment_url", deploymentURL).
+			Str("deployment_url", ci.deploymentURL).
 			Msg("detected deployment url")
 	}
 
-	if metadata != nil {
-		data, err := json.Marshal(metadata)
+	if ci.metadata != nil {
+		data, err := json.Marshal(ci.metadata)
 		if err == nil {
 			logger.Debug().RawJSON("provider_metadata", data).Msg("detected provider metadata")
 		} else {
@@ -251,26 +241,26 @@ func (c *cli) createCloudDeployment(runStacks []run.ExecContext) {
 	}
 
 	payload := cloud.DeploymentStacksPayloadRequest{
-		ReviewRequest: reviewRequest,
+		ReviewRequest: ci.reviewRequest,
 		Workdir:       prj.PrjAbsPath(c.rootdir(), c.wd()),
-		Metadata:      metadata,
+		Metadata:      ci.metadata,
 	}
 
-	for _, run := range runStacks {
-		tags := run.Stack.Tags
+	for _, runStack := range runStacks {
+		tags := runStack.Stack.Tags
 		if tags == nil {
 			tags = []string{}
 		}
 		payload.Stacks = append(payload.Stacks, cloud.DeploymentStackRequest{
-			MetaID:            strings.ToLower(run.Stack.ID),
-			MetaName:          run.Stack.Name,
-			MetaDescription:   run.Stack.Description,
+			MetaID:            strings.ToLower(runStack.Stack.ID),
+			MetaName:          runStack.Stack.Name,
+			MetaDescription:   runStack.Stack.Description,
 			MetaTags:          tags,
 			Repository:        normalizedRepo,
-			Path:              run.Stack.Dir.String(),
+			Path:              runStack.Stack.Dir.String(),
 			CommitSHA:         deploymentCommitSHA,
-			DeploymentCommand: strings.Join(run.Cmd, " "),
-			DeploymentURL:     deploymentURL,
+			DeploymentCommand: run.Redact(runStack.Env, strings.Join(runStack.Cmd, " ")),
+			DeploymentURL:     ci.deploymentURL,
 		})
 	}
 	res, err := c.cloud.client.CreateDeploymentStacks(ctx, c.cloud.run.orgUUID, c.cloud.run.runUUID, payload)
@@ -341,167 +331,9 @@ func (c *cli) cloudInfo() {
 	}
 	c.cred().Info()
 	// verbose info
-	c.cloud.output.MsgStdOutV("next token refresh in: %s", time.Until(c.cred().ExpireAt()))
-}
-
-func (c *cli) tryGithubMetadata(normalizedRepo string) (*cloud.DeploymentReviewRequest, *cloud.DeploymentMetadata, string) {
-	logger := log.With().
-		Str("normalized_repository", normalizedRepo).
-		Str("head_commit", c.prj.headCommit()).
-		Logger()
-
-	r, err := repository.Parse(normalizedRepo)
-	if err != nil {
-		logger.Debug().
-			Msg("repository cannot be normalized: skipping pull request retrievals for commit")
-
-		return nil, nil, ""
-	}
-
-	if r.Host != github.Domain {
-		return nil, nil, ""
-	}
-
-	ghRepo := r.Owner + "/" + r.Name
-
-	logger = logger.With().
-		Str("github_repository", ghRepo).
-		Logger()
-
-	ghToken, tokenSource := auth.TokenForHost(r.Host)
-
-	if ghToken != "" {
-		logger.Debug().Msgf("GitHub token obtained from %s", tokenSource)
-	}
-
-	ghClient := github.Client{
-		BaseURL:    os.Getenv("GITHUB_API_URL"),
-		HTTPClient: &c.httpClient,
-		Token:      ghToken,
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), defaultGithubTimeout)
-	defer cancel()
-
-	headCommit := c.prj.headCommit()
-	pulls, err := ghClient.PullsForCommit(ctx, ghRepo, headCommit)
-	if err != nil {
-		if errors.IsKind(err, github.ErrNotFound) {
-			if ghToken == "" {
-				logger.Warn().Msg("The GITHUB_TOKEN environment variable needs to be exported for private repositories.")
-			} else {
-				logger.Warn().Msg("The provided GitHub token does not have permission to read this repository or it does not exists.")
-			}
-			return nil, nil, ghRepo
-		}
-
-		if errors.IsKind(err, github.ErrUnprocessableEntity) {
-			logger.Warn().
-				Msg("The HEAD commit cannot be found in the remote. Did you forget to push?")
-
-			return nil, nil, ghRepo
-		}
-
-		logger.Warn().
-			Err(err).
-			Msg("failed to retrieve pull requests associated with HEAD")
-	}
-
-	for _, pull := range pulls {
-		logger.Debug().
-			Str("pull_request_url", pull.HTMLURL).
-			Msg("found pull request")
-	}
-
-	metadata := &cloud.DeploymentMetadata{
-		Platform:              "github",
-		DeploymentTriggeredBy: os.Getenv("GITHUB_ACTOR"),
-		DeploymentBranch:      os.Getenv("GITHUB_REF_NAME"),
-		DeploymentCommitSHA:   headCommit,
-	}
-
-	ctx, cancel = context.WithTimeout(context.Background(), defaultGithubTimeout)
-	defer cancel()
-
-	commit, err := ghClient.Commit(ctx, ghRepo, headCommit)
-	if err != nil {
-		logger.Warn().
-			Err(err).
-			Msg("failed to retrieve commit information from GitHub API")
-	} else {
-		isVerified := commit.Verification.Verified
-		metadata.DeploymentCommitVerified = &isVerified
-		metadata.DeploymentCommitVerifiedReason = commit.Verification.Reason
-
-		message := commit.Commit.Message
-		messageParts := strings.Split(message, "\n")
-		metadata.DeploymentCommitTitle = messageParts[0]
-		if len(messageParts) > 1 {
-			metadata.DeploymentCommitDescription = strings.Join(messageParts[1:], "\n")
-		}
-
-		metadata.DeploymentCommitAuthorLogin = commit.Author.Login
-		metadata.DeploymentCommitAuthorAvatarURL = commit.Author.AvatarURL
-		metadata.DeploymentCommitAuthorGravatarID = commit.Author.GravatarID
-
-		metadata.DeploymentCommitAuthorGitName = commit.Commit.Author.Name
-		metadata.DeploymentCommitAuthorGitEmail = commit.Commit.Author.Email
-		metadata.DeploymentCommitAuthorGitDate = commit.Commit.Author.Date
-
-		metadata.DeploymentCommitCommitterLogin = commit.Committer.Login
-		metadata.DeploymentCommitCommitterAvatarURL = commit.Committer.AvatarURL
-		metadata.DeploymentCommitCommitterGravatarID = commit.Committer.GravatarID
-
-		metadata.DeploymentCommitCommitterGitName = commit.Commit.Committer.Name
-		metadata.DeploymentCommitCommitterGitEmail = commit.Commit.Committer.Email
-		metadata.DeploymentCommitCommitterGitDate = commit.Commit.Committer.Date
-	}
-
-	if len(pulls) == 0 {
-		logger.Warn().
-			Msg("no pull request associated with HEAD commit")
-
-		return nil, metadata, ghRepo
+	if expireAt := c.cred().ExpireAt(); !expireAt.IsZero() {
+		c.cloud.output.MsgStdOutV("next token refresh in: %s", time.Until(expireAt))
 	}
-
-	pull := pulls[0]
-
-	logger.Debug().
-		Str("pull_request_url", pull.HTMLURL).
-		Msg("using pull request url")
-
-	reviewRequest := &cloud.DeploymentReviewRequest{
-		Platform:    "github",
-		Repository:  normalizedRepo,
-		URL:         pull.HTMLURL,
-		Number:      pull.Number,
-		Title:       pull.Title,
-		Description: pull.Body,
-		CommitSHA:   pull.Head.SHA,
-	}
-
-	metadata.PullRequestAuthorLogin = pull.User.Login
-	metadata.PullRequestAuthorAvatarURL = pull.User.AvatarURL
-	metadata.PullRequestAuthorGravatarID = pull.User.GravatarID
-	metadata.PullRequestHeadLabel = pull.Head.Label
-	metadata.PullRequestHeadRef = pull.Head.Ref
-	metadata.PullRequestHeadSHA = pull.Head.SHA
-	metadata.PullRequestHeadAuthorLogin = pull.Head.User.Login
-	metadata.PullRequestHeadAuthorAvatarURL = pull.Head.User.AvatarURL
-	metadata.PullRequestHeadAuthorGravatarID = pull.Head.User.GravatarID
-
-	metadata.PullRequestBaseLabel = pull.Base.Label
-	metadata.PullRequestBaseRef = pull.Base.Ref
-	metadata.PullRequestBaseSHA = pull.Base.SHA
-	metadata.PullRequestBaseAuthorLogin = pull.Base.User.Login
-	metadata.PullRequestBaseAuthorAvatarURL = pull.Base.User.AvatarURL
-	metadata.PullRequestBaseAuthorGravatarID = pull.Base.User.GravatarID
-
-	metadata.PullRequestCreatedAt = pull.CreatedAt
-	metadata.PullRequestUpdatedAt = pull.UpdatedAt
-	metadata.PullRequestClosedAt = pull.ClosedAt
-	metadata.PullRequestMergedAt = pull.MergedAt
-	return reviewRequest, metadata, ghToken
 }
 
 func (c *cli) loadCredential() (credential, error) {
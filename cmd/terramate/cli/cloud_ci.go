@@ -0,0 +1,58 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/cloud"
+)
+
+// ciProvider is a CI/CD platform (or code hosting service) which provides
+// metadata about the deployment, like the review request being deployed.
+type ciProvider interface {
+	Name() string
+	// Detect tells if the provider can retrieve the deployment metadata.
+	Detect(repo ciRepository) bool
+	// Metadata retrieves the deployment metadata. Failures are logged and
+	// the metadata available is returned.
+	Metadata(repo ciRepository) ciMetadata
+}
+
+// ciRepository is the repository being deployed.
+type ciRepository struct {
+	// normalized is the normalized remote URL, eg.: github.com/org/repo.
+	normalized string
+	headCommit string
+}
+
+// ciMetadata is the deployment metadata retrieved from a ciProvider.
+type ciMetadata struct {
+	reviewRequest *cloud.DeploymentReviewRequest
+	metadata      *cloud.DeploymentMetadata
+	deploymentURL string
+}
+
+// ciProviders returns the CI/CD providers in order of precedence. The
+// providers detected from the environment of the CI/CD pipeline come first,
+// so the repository host is only used as fallback.
+func (c *cli) ciProviders() []ciProvider {
+	return []ciProvider{
+		newGitlabCI(&c.httpClient),
+		newBitbucketCI(),
+		newGithubCI(&c.httpClient),
+	}
+}
+
+func (c *cli) detectCIMetadata(repo ciRepository) ciMetadata {
+	for _, provider := range c.ciProviders() {
+		if provider.Detect(repo) {
+			log.Debug().
+				Str("provider", provider.Name()).
+				Msg("detected CI/CD provider")
+
+			return provider.Metadata(repo)
+		}
+	}
+	return ciMetadata{}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/cloud"
+)
+
+const bitbucketCIProviderName = "Bitbucket Pipelines"
+
+// bitbucketURL is the Bitbucket Cloud web URL.
+const bitbucketURL = "https://bitbucket.org"
+
+// bitbucketCI retrieves the metadata from the Bitbucket Pipelines variables.
+// No API calls are made, so only the pull request ID and URL are known.
+type bitbucketCI struct{}
+
+func newBitbucketCI() *bitbucketCI {
+	return &bitbucketCI{}
+}
+
+func (b *bitbucketCI) Name() string {
+	return bitbucketCIProviderName
+}
+
+// Detect tells if running in a Bitbucket Pipelines build.
+func (b *bitbucketCI) Detect(_ ciRepository) bool {
+	return os.Getenv("BITBUCKET_BUILD_NUMBER") != ""
+}
+
+func (b *bitbucketCI) Metadata(repo ciRepository) ciMetadata {
+	workspace := os.Getenv("BITBUCKET_WORKSPACE")
+	slug := os.Getenv("BITBUCKET_REPO_SLUG")
+	fullName := os.Getenv("BITBUCKET_REPO_FULL_NAME")
+	if fullName == "" && workspace != "" && slug != "" {
+		fullName = workspace + "/" + slug
+	}
+	buildNumber := os.Getenv("BITBUCKET_BUILD_NUMBER")
+	prID := os.Getenv("BITBUCKET_PR_ID")
+
+	logger := log.With().
+		Str("normalized_repository", repo.normalized).
+		Str("head_commit", repo.headCommit).
+		Str("bitbucket_repository", fullName).
+		Logger()
+
+	metadata := &cloud.DeploymentMetadata{
+		Platform:              "bitbucket",
+		DeploymentTriggeredBy: os.Getenv("BITBUCKET_STEP_TRIGGERER_UUID"),
+		DeploymentBranch:      os.Getenv("BITBUCKET_BRANCH"),
+		DeploymentCommitSHA:   repo.headCommit,
+		BitbucketMetadata: &cloud.BitbucketMetadata{
+			BitbucketWorkspace:                    workspace,
+			BitbucketRepoSlug:                     slug,
+			BitbucketPipelineUUID:                 os.Getenv("BITBUCKET_PIPELINE_UUID"),
+			BitbucketBuildNumber:                  buildNumber,
+			BitbucketStepTriggererUUID:            os.Getenv("BITBUCKET_STEP_TRIGGERER_UUID"),
+			BitbucketPullRequestID:                prID,
+			BitbucketPullRequestDestinationBranch: os.Getenv("BITBUCKET_PR_DESTINATION_BRANCH"),
+		},
+	}
+
+	res := ciMetadata{
+		metadata: metadata,
+	}
+
+	if fullName == "" {
+		logger.Warn().Msg("BITBUCKET_REPO_FULL_NAME is not set: skipping deployment and pull request urls")
+		return res
+	}
+
+	res.deploymentURL = fmt.Sprintf("%s/%s/pipelines/results/%s", bitbucketURL, fullName, buildNumber)
+
+	if prID == "" {
+		logger.Warn().
+			Msg("no pull request associated with the pipeline")
+
+		return res
+	}
+
+	number, err := strconv.Atoi(prID)
+	if err != nil {
+		logger.Warn().
+			Str("BITBUCKET_PR_ID", prID).
+			Msg("invalid pull request ID")
+
+		return res
+	}
+
+	res.reviewRequest = &cloud.DeploymentReviewRequest{
+		Platform:   "bitbucket",
+		Repository: repo.normalized,
+		URL:        fmt.Sprintf("%s/%s/pull-requests/%d", bitbucketURL, fullName, number),
+		Number:     number,
+		CommitSHA:  repo.headCommit,
+	}
+
+	logger.Debug().
+		Str("pull_request_url", res.reviewRequest.URL).
+		Msg("using pull request url")
+
+	return res
+}
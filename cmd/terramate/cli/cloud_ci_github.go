@@ -0,0 +1,219 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/cli/go-gh/v2/pkg/repository"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/cloud"
+	"github.com/terramate-io/terramate/cmd/terramate/cli/github"
+	"github.com/terramate-io/terramate/errors"
+)
+
+const githubCIProviderName = "GitHub"
+
+type githubCI struct {
+	httpClient *http.Client
+}
+
+func newGithubCI(httpClient *http.Client) *githubCI {
+	return &githubCI{
+		httpClient: httpClient,
+	}
+}
+
+func (g *githubCI) Name() string {
+	return githubCIProviderName
+}
+
+// Detect tells if the repository is hosted on GitHub. The GitHub API is also
+// used outside of GitHub Actions, if the repository is hosted there.
+func (g *githubCI) Detect(repo ciRepository) bool {
+	r, err := repository.Parse(repo.normalized)
+	return err == nil && r.Host == github.Domain
+}
+
+func (g *githubCI) Metadata(repo ciRepository) ciMetadata {
+	logger := log.With().
+		Str("normalized_repository", repo.normalized).
+		Str("head_commit", repo.headCommit).
+		Logger()
+
+	r, err := repository.Parse(repo.normalized)
+	if err != nil {
+		logger.Debug().
+			Msg("repository cannot be normalized: skipping pull request retrievals for commit")
+
+		return ciMetadata{}
+	}
+
+	ghRepo := r.Owner + "/" + r.Name
+	res := ciMetadata{
+		deploymentURL: githubDeploymentURL(ghRepo),
+	}
+
+	logger = logger.With().
+		Str("github_repository", ghRepo).
+		Logger()
+
+	ghToken, tokenSource := auth.TokenForHost(r.Host)
+
+	if ghToken != "" {
+		logger.Debug().Msgf("GitHub token obtained from %s", tokenSource)
+	}
+
+	ghClient := github.Client{
+		BaseURL:    os.Getenv("GITHUB_API_URL"),
+		HTTPClient: g.httpClient,
+		Token:      ghToken,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultGithubTimeout)
+	defer cancel()
+
+	headCommit := repo.headCommit
+	pulls, err := ghClient.PullsForCommit(ctx, ghRepo, headCommit)
+	if err != nil {
+		if errors.IsKind(err, github.ErrNotFound) {
+			if ghToken == "" {
+				logger.Warn().Msg("The GITHUB_TOKEN environment variable needs to be exported for private repositories.")
+			} else {
+				logger.Warn().Msg("The provided GitHub token does not have permission to read this repository or it does not exists.")
+			}
+			return res
+		}
+
+		if errors.IsKind(err, github.ErrUnprocessableEntity) {
+			logger.Warn().
+				Msg("The HEAD commit cannot be found in the remote. Did you forget to push?")
+
+			return res
+		}
+
+		logger.Warn().
+			Err(err).
+			Msg("failed to retrieve pull requests associated with HEAD")
+	}
+
+	for _, pull := range pulls {
+		logger.Debug().
+			Str("pull_request_url", pull.HTMLURL).
+			Msg("found pull request")
+	}
+
+	metadata := &cloud.DeploymentMetadata{
+		Platform:              "github",
+		DeploymentTriggeredBy: os.Getenv("GITHUB_ACTOR"),
+		DeploymentBranch:      os.Getenv("GITHUB_REF_NAME"),
+		DeploymentCommitSHA:   headCommit,
+		GitHubMetadata:        &cloud.GitHubMetadata{},
+	}
+	res.metadata = metadata
+
+	ctx, cancel = context.WithTimeout(context.Background(), defaultGithubTimeout)
+	defer cancel()
+
+	commit, err := ghClient.Commit(ctx, ghRepo, headCommit)
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Msg("failed to retrieve commit information from GitHub API")
+	} else {
+		isVerified := commit.Verification.Verified
+		metadata.DeploymentCommitVerified = &isVerified
+		metadata.DeploymentCommitVerifiedReason = commit.Verification.Reason
+
+		message := commit.Commit.Message
+		messageParts := strings.Split(message, "\n")
+		metadata.DeploymentCommitTitle = messageParts[0]
+		if len(messageParts) > 1 {
+			metadata.DeploymentCommitDescription = strings.Join(messageParts[1:], "\n")
+		}
+
+		metadata.DeploymentCommitAuthorLogin = commit.Author.Login
+		metadata.DeploymentCommitAuthorAvatarURL = commit.Author.AvatarURL
+		metadata.DeploymentCommitAuthorGravatarID = commit.Author.GravatarID
+
+		metadata.DeploymentCommitAuthorGitName = commit.Commit.Author.Name
+		metadata.DeploymentCommitAuthorGitEmail = commit.Commit.Author.Email
+		metadata.DeploymentCommitAuthorGitDate = commit.Commit.Author.Date
+
+		metadata.DeploymentCommitCommitterLogin = commit.Committer.Login
+		metadata.DeploymentCommitCommitterAvatarURL = commit.Committer.AvatarURL
+		metadata.DeploymentCommitCommitterGravatarID = commit.Committer.GravatarID
+
+		metadata.DeploymentCommitCommitterGitName = commit.Commit.Committer.Name
+		metadata.DeploymentCommitCommitterGitEmail = commit.Commit.Committer.Email
+		metadata.DeploymentCommitCommitterGitDate = commit.Commit.Committer.Date
+	}
+
+	if len(pulls) == 0 {
+		logger.Warn().
+			Msg("no pull request associated with HEAD commit")
+
+		return res
+	}
+
+	pull := pulls[0]
+
+	logger.Debug().
+		Str("pull_request_url", pull.HTMLURL).
+		Msg("using pull request url")
+
+	res.reviewRequest = &cloud.DeploymentReviewRequest{
+		Platform:    "github",
+		Repository:  repo.normalized,
+		URL:         pull.HTMLURL,
+		Number:      pull.Number,
+		Title:       pull.Title,
+		Description: pull.Body,
+		CommitSHA:   pull.Head.SHA,
+	}
+
+	metadata.PullRequestAuthorLogin = pull.User.Login
+	metadata.PullRequestAuthorAvatarURL = pull.User.AvatarURL
+	metadata.PullRequestAuthorGravatarID = pull.User.GravatarID
+	metadata.PullRequestHeadLabel = pull.Head.Label
+	metadata.PullRequestHeadRef = pull.Head.Ref
+	metadata.PullRequestHeadSHA = pull.Head.SHA
+	metadata.PullRequestHeadAuthorLogin = pull.Head.User.Login
+	metadata.PullRequestHeadAuthorAvatarURL = pull.Head.User.AvatarURL
+	metadata.PullRequestHeadAuthorGravatarID = pull.Head.User.GravatarID
+
+	metadata.PullRequestBaseLabel = pull.Base.Label
+	metadata.PullRequestBaseRef = pull.Base.Ref
+	metadata.PullRequestBaseSHA = pull.Base.SHA
+	metadata.PullRequestBaseAuthorLogin = pull.Base.User.Login
+	metadata.PullRequestBaseAuthorAvatarURL = pull.Base.User.AvatarURL
+	metadata.PullRequestBaseAuthorGravatarID = pull.Base.User.GravatarID
+
+	metadata.PullRequestCreatedAt = pull.CreatedAt
+	metadata.PullRequestUpdatedAt = pull.UpdatedAt
+	metadata.PullRequestClosedAt = pull.ClosedAt
+	metadata.PullRequestMergedAt = pull.MergedAt
+	return res
+}
+
+// githubDeploymentURL returns the URL of the GitHub Actions workflow run
+// attempt, if running in GitHub Actions.
+func githubDeploymentURL(ghRepo string) string {
+	ghRunID := os.Getenv("GITHUB_RUN_ID")
+	ghAttempt := os.Getenv("GITHUB_RUN_ATTEMPT")
+	if ghRunID == "" || ghAttempt == "" {
+		return ""
+	}
+	return fmt.Sprintf(
+		"https://github.com/%s/actions/runs/%s/attempts/%s",
+		ghRepo,
+		ghRunID,
+		ghAttempt,
+	)
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/cloud"
+	"github.com/terramate-io/terramate/cmd/terramate/cli/gitlab"
+	"github.com/terramate-io/terramate/errors"
+)
+
+const gitlabCIProviderName = "GitLab CI"
+
+type gitlabCI struct {
+	httpClient *http.Client
+}
+
+func newGitlabCI(httpClient *http.Client) *gitlabCI {
+	return &gitlabCI{
+		httpClient: httpClient,
+	}
+}
+
+func (g *gitlabCI) Name() string {
+	return gitlabCIProviderName
+}
+
+// Detect tells if running in a GitLab CI pipeline.
+func (g *gitlabCI) Detect(_ ciRepository) bool {
+	return os.Getenv("GITLAB_CI") != ""
+}
+
+func (g *gitlabCI) Metadata(repo ciRepository) ciMetadata {
+	projectID := os.Getenv("CI_PROJECT_ID")
+
+	logger := log.With().
+		Str("normalized_repository", repo.normalized).
+		Str("head_commit", repo.headCommit).
+		Str("gitlab_project_id", projectID).
+		Logger()
+
+	metadata := &cloud.DeploymentMetadata{
+		Platform:                    "gitlab",
+		DeploymentTriggeredBy:       os.Getenv("GITLAB_USER_LOGIN"),
+		DeploymentBranch:            os.Getenv("CI_COMMIT_REF_NAME"),
+		DeploymentCommitSHA:         repo.headCommit,
+		DeploymentCommitTitle:       os.Getenv("CI_COMMIT_TITLE"),
+		DeploymentCommitDescription: os.Getenv("CI_COMMIT_DESCRIPTION"),
+		GitLabMetadata: &cloud.GitLabMetadata{
+			GitlabProjectID:    projectID,
+			GitlabPipelineID:   os.Getenv("CI_PIPELINE_ID"),
+			GitlabJobID:        os.Getenv("CI_JOB_ID"),
+			GitlabCommitAuthor: os.Getenv("CI_COMMIT_AUTHOR"),
+		},
+	}
+
+	res := ciMetadata{
+		metadata:      metadata,
+		deploymentURL: os.Getenv("CI_PIPELINE_URL"),
+	}
+
+	mr := g.mergeRequest(logger, projectID, repo.headCommit)
+	if mr == nil {
+		logger.Warn().
+			Msg("no merge request associated with HEAD commit")
+
+		return res
+	}
+
+	logger.Debug().
+		Str("merge_request_url", mr.WebURL).
+		Msg("using merge request url")
+
+	res.reviewRequest = &cloud.DeploymentReviewRequest{
+		Platform:    "gitlab",
+		Repository:  repo.normalized,
+		URL:         mr.WebURL,
+		Number:      mr.IID,
+		Title:       mr.Title,
+		Description: mr.Description,
+		CommitSHA:   mr.SHA,
+	}
+
+	metadata.GitlabMergeRequestAuthorUsername = mr.Author.Username
+	metadata.GitlabMergeRequestAuthorName = mr.Author.Name
+	metadata.GitlabMergeRequestAuthorAvatarURL = mr.Author.AvatarURL
+	metadata.GitlabMergeRequestState = mr.State
+	metadata.GitlabMergeRequestSourceBranch = mr.SourceBranch
+	metadata.GitlabMergeRequestTargetBranch = mr.TargetBranch
+	metadata.GitlabMergeRequestCreatedAt = mr.CreatedAt
+	metadata.GitlabMergeRequestUpdatedAt = mr.UpdatedAt
+	metadata.GitlabMergeRequestMergedAt = mr.MergedAt
+	return res
+}
+
+// mergeRequest retrieves the merge request being deployed. In merge request
+// pipelines, the merge request is known from the environment, otherwise the
+// first merge request associated with the HEAD commit is used.
+func (g *gitlabCI) mergeRequest(logger zerolog.Logger, projectID, headCommit string) *gitlab.MergeRequest {
+	token := os.Getenv("GITLAB_TOKEN")
+	if token != "" {
+		logger.Debug().Msg("GitLab token obtained from GITLAB_TOKEN")
+	}
+
+	client := gitlab.Client{
+		BaseURL:    os.Getenv("CI_API_V4_URL"),
+		HTTPClient: g.httpClient,
+		Token:      token,
+		JobToken:   os.Getenv("CI_JOB_TOKEN"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultGitlabTimeout)
+	defer cancel()
+
+	if iidStr := os.Getenv("CI_MERGE_REQUEST_IID"); iidStr != "" {
+		iid, err := strconv.Atoi(iidStr)
+		if err != nil {
+			logger.Warn().
+				Str("CI_MERGE_REQUEST_IID", iidStr).
+				Msg("invalid merge request IID")
+
+			return nil
+		}
+
+		mr, err := client.MergeRequest(ctx, projectID, iid)
+		if err == nil {
+			return mr
+		}
+
+		logger.Debug().
+			Err(err).
+			Msg("failed to retrieve merge request from GitLab API: using the merge request pipeline variables")
+
+		return gitlabMergeRequestFromEnv(iid, headCommit)
+	}
+
+	mrs, err := client.MergeRequestsForCommit(ctx, projectID, headCommit)
+	if err != nil {
+		if errors.IsKind(err, gitlab.ErrNotFound) || errors.IsKind(err, gitlab.ErrUnauthorized) {
+			if token == "" {
+				logger.Warn().Msg("The GITLAB_TOKEN environment variable needs to be exported to retrieve merge requests.")
+			} else {
+				logger.Warn().Msg("The provided GitLab token does not have permission to read this project or it does not exists.")
+			}
+			return nil
+		}
+
+		logger.Warn().
+			Err(err).
+			Msg("failed to retrieve merge requests associated with HEAD")
+
+		return nil
+	}
+
+	for _, mr := range mrs {
+		logger.Debug().
+			Str("merge_request_url", mr.WebURL).
+			Msg("found merge request")
+	}
+
+	if len(mrs) == 0 {
+		return nil
+	}
+	return &mrs[0]
+}
+
+// gitlabMergeRequestFromEnv returns the merge request from the predefined
+// variables of merge request pipelines.
+func gitlabMergeRequestFromEnv(iid int, headCommit string) *gitlab.MergeRequest {
+	sha := os.Getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_SHA")
+	if sha == "" {
+		sha = headCommit
+	}
+	return &gitlab.MergeRequest{
+		IID:          iid,
+		Title:        os.Getenv("CI_MERGE_REQUEST_TITLE"),
+		Description:  os.Getenv("CI_MERGE_REQUEST_DESCRIPTION"),
+		WebURL:       os.Getenv("CI_MERGE_REQUEST_PROJECT_URL") + "/-/merge_requests/" + strconv.Itoa(iid),
+		SourceBranch: os.Getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"),
+		TargetBranch: os.Getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME"),
+		SHA:          sha,
+	}
+}
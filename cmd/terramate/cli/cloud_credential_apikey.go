@@ -0,0 +1,158 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/terramate-io/terramate/cloud"
+	"github.com/terramate-io/terramate/cmd/terramate/cli/out"
+	"github.com/terramate-io/terramate/errors"
+)
+
+const apiKeyProviderName = "API Key"
+
+const (
+	apiKeyEnv     = "TM_CLOUD_API_KEY"
+	apiKeyFileEnv = "TM_CLOUD_API_KEY_FILE"
+)
+
+// apiKeyCredential is a long-lived API key read from the TM_CLOUD_API_KEY
+// environment variable or from the file in TM_CLOUD_API_KEY_FILE.
+type apiKeyCredential struct {
+	key    string
+	source string
+
+	isValidated bool
+	orgs        cloud.MemberOrganizations
+
+	output out.O
+}
+
+func newAPIKeyCredential(output out.O) *apiKeyCredential {
+	return &apiKeyCredential{
+		output: output,
+	}
+}
+
+func (a *apiKeyCredential) Load() (bool, error) {
+	key, source, err := loadSecretFromEnv(apiKeyEnv, apiKeyFileEnv)
+	if err != nil || key == "" {
+		return false, err
+	}
+	a.key = key
+	a.source = source
+	return true, nil
+}
+
+func (a *apiKeyCredential) Name() string {
+	return apiKeyProviderName
+}
+
+// IsExpired always returns false because API keys do not expire on the client.
+func (a *apiKeyCredential) IsExpired() bool {
+	return false
+}
+
+// ExpireAt returns the zero time because API keys do not expire on the client.
+func (a *apiKeyCredential) ExpireAt() time.Time {
+	return time.Time{}
+}
+
+func (a *apiKeyCredential) Refresh() error {
+	return nil
+}
+
+func (a *apiKeyCredential) Token() (string, error) {
+	return a.key, nil
+}
+
+func (a *apiKeyCredential) DisplayClaims() []keyValue {
+	return []keyValue{
+		{
+			key:   "key",
+			value: maskSecret(a.key),
+		},
+		{
+			key:   "source",
+			value: a.source,
+		},
+	}
+}
+
+// Validate if the credential is ready to be used.
+func (a *apiKeyCredential) Validate(cloudcfg cloudConfig) error {
+	const apiTimeout = 5 * time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	orgs, err := cloudcfg.client.MemberOrganizations(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.isValidated = true
+	a.orgs = orgs
+	return nil
+}
+
+func (a *apiKeyCredential) Info() {
+	if !a.isValidated {
+		panic(errors.E(errors.ErrInternal, "cred.Info() called for unvalidated credential"))
+	}
+
+	a.output.MsgStdOut("status: signed in")
+	a.output.MsgStdOut("provider: %s", a.Name())
+
+	for _, kv := range a.DisplayClaims() {
+		a.output.MsgStdOut("%s: %s", kv.key, kv.value)
+	}
+
+	if len(a.orgs) > 0 {
+		a.output.MsgStdOut("organizations: %s", a.orgs)
+	}
+	if len(a.orgs) == 0 {
+		a.output.MsgStdErr("Warning: You are not part of an organization. Please visit cloud.terramate.io to create an organization.")
+	}
+}
+
+// organizations returns the list of organizations associated with the credential.
+func (a *apiKeyCredential) organizations() cloud.MemberOrganizations {
+	return a.orgs
+}
+
+// loadSecretFromEnv loads a secret from the envVar environment variable or,
+// if not set, from the file in the fileEnvVar environment variable. It returns
+// an empty secret if none of them are set, and the source of the secret.
+func loadSecretFromEnv(envVar, fileEnvVar string) (secret string, source string, err error) {
+	if secret := strings.TrimSpace(os.Getenv(envVar)); secret != "" {
+		return secret, envVar + " environment variable", nil
+	}
+	file := os.Getenv(fileEnvVar)
+	if file == "" {
+		return "", "", nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", "", errors.E(err, "reading the file in the %s environment variable", fileEnvVar)
+	}
+	secret = strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", "", errors.E("the file %s in the %s environment variable is empty", file, fileEnvVar)
+	}
+	return secret, file, nil
+}
+
+// maskSecret masks all but the last 4 characters of the secret, or all of them
+// for short secrets.
+func maskSecret(secret string) string {
+	const visible = 4
+	if len(secret) <= 2*visible {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", len(secret)-visible) + secret[len(secret)-visible:]
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/terramate-io/terramate/cloud"
+	"github.com/terramate-io/terramate/cmd/terramate/cli/out"
+	"github.com/terramate-io/terramate/errors"
+)
+
+const genericOIDCProviderName = "OIDC"
+
+const (
+	oidcTokenEnv     = "TM_CLOUD_OIDC_TOKEN"
+	oidcTokenFileEnv = "TM_CLOUD_OIDC_TOKEN_FILE"
+)
+
+// genericOIDC exchanges an OIDC token (JWT) issued by any identity provider
+// trusted by Terramate Cloud (eg.: GitLab CI, CircleCI or a Kubernetes
+// service account) for a Terramate Cloud token.
+// The OIDC token is read from the TM_CLOUD_OIDC_TOKEN environment variable or
+// from the file in TM_CLOUD_OIDC_TOKEN_FILE, which is read again on every
+// refresh because it can be rotated (eg.: projected service account tokens).
+type genericOIDC struct {
+	mu       sync.RWMutex
+	token    string
+	expireAt time.Time
+
+	source  string
+	issuer  string
+	subject string
+
+	baseURL    string
+	httpClient *http.Client
+
+	isValidated bool
+	orgs        cloud.MemberOrganizations
+
+	output out.O
+}
+
+// oidcToken is the OIDC token being exchanged, used as the credential of the
+// exchange request.
+type oidcToken string
+
+func (o oidcToken) Token() (string, error) {
+	return string(o), nil
+}
+
+func newGenericOIDC(output out.O, baseURL string, httpClient *http.Client) *genericOIDC {
+	return &genericOIDC{
+		output:     output,
+		baseURL:    baseURL,
+		httpClient: httpClient,
+	}
+}
+
+func (g *genericOIDC) Load() (bool, error) {
+	token, _, err := loadSecretFromEnv(oidcTokenEnv, oidcTokenFileEnv)
+	if err != nil || token == "" {
+		return false, err
+	}
+	err = g.Refresh()
+	return err == nil, err
+}
+
+func (g *genericOIDC) Name() string {
+	return genericOIDCProviderName
+}
+
+func (g *genericOIDC) IsExpired() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return time.Now().After(g.expireAt)
+}
+
+func (g *genericOIDC) ExpireAt() time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.expireAt
+}
+
+func (g *genericOIDC) Refresh() error {
+	subjectToken, source, err := loadSecretFromEnv(oidcTokenEnv, oidcTokenFileEnv)
+	if err != nil {
+		return err
+	}
+	if subjectToken == "" {
+		return errors.E("the OIDC token is not set anymore in %s or %s", oidcTokenEnv, oidcTokenFileEnv)
+	}
+
+	claims, err := tokenClaims(subjectToken)
+	if err != nil {
+		return errors.E(err, "parsing the OIDC token from %s", source)
+	}
+
+	issuer, _ := claims["iss"].(string)
+	subject, _ := claims["sub"].(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCloudTimeout)
+	defer cancel()
+
+	client := cloud.Client{
+		BaseURL:    g.baseURL,
+		HTTPClient: g.httpClient,
+		Credential: oidcToken(subjectToken),
+	}
+	res, err := client.ExchangeOIDCToken(ctx)
+	if err != nil {
+		return errors.E(err, "exchanging the OIDC token from %s", source)
+	}
+
+	expireAt := time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	if res.ExpiresIn == 0 {
+		// the cloud token is valid while the OIDC token is valid.
+		exp, ok := claims["exp"].(float64)
+		if !ok {
+			return errors.E(`OIDC token with no "exp" payload field`)
+		}
+		sec, dec := math.Modf(exp)
+		expireAt = time.Unix(int64(sec), int64(dec*(1e9)))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.token = res.Token
+	g.expireAt = expireAt
+	g.source = source
+	g.issuer = issuer
+	g.subject = subject
+	return nil
+}
+
+func (g *genericOIDC) DisplayClaims() []keyValue {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return []keyValue{
+		{
+			key:   "issuer",
+			value: g.issuer,
+		},
+		{
+			key:   "subject",
+			value: g.subject,
+		},
+		{
+			key:   "source",
+			value: g.source,
+		},
+	}
+}
+
+func (g *genericOIDC) Token() (string, error) {
+	if g.IsExpired() {
+		err := g.Refresh()
+		if err != nil {
+			return "", err
+		}
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.token, nil
+}
+
+// Validate if the credential is ready to be used.
+func (g *genericOIDC) Validate(cloudcfg cloudConfig) error {
+	const apiTimeout = 5 * time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	orgs, err := cloudcfg.client.MemberOrganizations(ctx)
+	if err != nil {
+		return err
+	}
+
+	g.isValidated = true
+	g.orgs = orgs
+	return nil
+}
+
+func (g *genericOIDC) Info() {
+	if !g.isValidated {
+		panic(errors.E(errors.ErrInternal, "cred.Info() called for unvalidated credential"))
+	}
+
+	g.output.MsgStdOut("status: signed in")
+	g.output.MsgStdOut("provider: %s", g.Name())
+
+	for _, kv := range g.DisplayClaims() {
+		g.output.MsgStdOut("%s: %s", kv.key, kv.value)
+	}
+
+	if len(g.orgs) > 0 {
+		g.output.MsgStdOut("organizations: %s", g.orgs)
+	}
+	if len(g.orgs) == 0 {
+		g.output.MsgStdErr("Warning: You are not part of an organization. Please visit cloud.terramate.io to create an organization.")
+	}
+}
+
+// organizations returns the list of organizations associated with the credential.
+func (g *genericOIDC) organizations() cloud.MemberOrganizations {
+	return g.orgs
+}
@@ -0,0 +1,309 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/cloud"
+	"github.com/terramate-io/terramate/cloud/stack"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/run/dag"
+)
+
+// driftExitCode is the exit code of the drift detection command telling the
+// stack has drifted, as in `terraform plan -detailed-exitcode`.
+const driftExitCode = 2
+
+// driftRun is the drift detection state shared by the hooks of the run.
+type driftRun struct {
+	normalizedRepo string
+	commitSHA      string
+	ci             ciMetadata
+
+	// statuses of the stacks in the order they finished.
+	statuses []driftResult
+}
+
+type driftResult struct {
+	stack  *config.Stack
+	status stack.Status
+}
+
+func (c *cli) driftRun() {
+	logger := log.With().
+		Str("action", "driftRun()").
+		Str("workingDir", c.wd()).
+		Logger()
+
+	args := c.parsedArgs.Experimental.Drift.Run
+
+	if len(args.Command) == 0 {
+		logger.Fatal().Msgf("drift run expects a cmd")
+	}
+
+	if args.Parallel < 1 {
+		logger.Fatal().Msg("--parallel must be greater than zero")
+	}
+
+	c.gitSafeguardDefaultBranchIsReachable()
+	c.checkOutdatedGeneratedCode()
+
+	drift := c.setupCloudDrift()
+
+	stacks, err := c.computeSelectedStacks(true)
+	if err != nil {
+		fatal(err, "computing selected stacks")
+	}
+
+	orderedStacks, deps, reason, err := run.SortWithDeps(c.cfg(), stacks)
+	if err != nil {
+		if errors.IsKind(err, dag.ErrCycleDetected) {
+			fatal(err, "cycle detected: %s", reason)
+		} else {
+			fatal(err, "failed to plan execution")
+		}
+	}
+
+	stackEnvs := c.loadRunEnvs(orderedStacks)
+	timeout, _ := c.runTimeoutAndRetry()
+
+	var runStacks []run.ExecContext
+	for _, st := range orderedStacks {
+		runStacks = append(runStacks, run.ExecContext{
+			Stack:     st.Stack,
+			Cmd:       args.Command,
+			Env:       stackEnvs[st.Dir()],
+			DependsOn: deps[st.Dir()],
+			Timeout:   timeout,
+		})
+	}
+
+	if c.cloudEnabled() {
+		var stacksMissingIDs []string
+		for _, run := range runStacks {
+			if run.Stack.ID == "" {
+				stacksMissingIDs = append(stacksMissingIDs, run.Stack.Dir.String())
+			}
+		}
+		if len(stacksMissingIDs) > 0 {
+			for _, stackPath := range stacksMissingIDs {
+				logger.Error().Str("stack", stackPath).Msg("stack is missing the ID field")
+			}
+			logger.Warn().Msg("Stacks are missing IDs. You can use 'terramate create --ensure-stack-ids' to add missing IDs to all stacks.")
+			fatal(errors.E("The drift detection requires that selected stacks contain an ID field"))
+		}
+	}
+
+	stackOutput, err := run.NewOutput(c.stdout, c.stderr, run.OutputMode(args.OutputMode), "")
+	if err != nil {
+		fatal(err, "setting up the stacks output")
+	}
+	output := newCaptureOutput(stackOutput)
+
+	envs := map[*config.Stack][]run.EnvVar{}
+	for _, run := range runStacks {
+		envs[run.Stack] = run.Env
+	}
+
+	afterHook := func(s *config.Stack, err error) {
+		// the output is only released here because it's closed before the
+		// hook is called.
+		changeset := output.release(s)
+
+		var status stack.Status
+		switch {
+		case err == nil:
+			status = stack.OK
+		case errors.IsKind(err, run.ErrCanceled):
+			status = stack.Canceled
+		case driftDetected(err):
+			status = stack.Drifted
+		default:
+			status = stack.Failed
+		}
+
+		drift.statuses = append(drift.statuses, driftResult{
+			stack:  s,
+			status: status,
+		})
+
+		if !c.cloudEnabled() || status == stack.Canceled {
+			return
+		}
+		c.syncCloudDrift(drift, s, envs[s], status, changeset)
+	}
+
+	err = run.ExecAll(
+		c.cfg(),
+		runStacks,
+		c.stdin,
+		output,
+		true,
+		args.Parallel,
+		func(*config.Stack, string) {},
+		afterHook,
+	)
+
+	c.printDriftSummary(drift)
+
+	if err != nil && !onlyDrifted(drift) {
+		fatal(err, "one or more drift detections failed")
+	}
+}
+
+// setupCloudDrift configures the cloud client used to report the drifts.
+// Failures disable the cloud features, so the drift detection still runs.
+func (c *cli) setupCloudDrift() *driftRun {
+	drift := &driftRun{}
+
+	err := c.setupCloudConfig()
+	if err != nil {
+		log.Warn().Err(errors.E(err, "failed to check if credentials work")).
+			Msg(DisablingCloudMessage)
+
+		c.cloud.disabled = true
+		return drift
+	}
+
+	if !c.prj.isRepo {
+		log.Warn().Err(errors.E("drift detection requires a git repository")).
+			Msg(DisablingCloudMessage)
+
+		c.cloud.disabled = true
+		return drift
+	}
+
+	repoURL, err := c.prj.git.wrapper.URL(c.prj.gitcfg().DefaultRemote)
+	if err != nil {
+		log.Warn().Err(errors.E(err, "failed to retrieve repository URL")).
+			Msg(DisablingCloudMessage)
+
+		c.cloud.disabled = true
+		return drift
+	}
+
+	drift.normalizedRepo = cloud.NormalizeGitURI(repoURL)
+	drift.commitSHA = c.prj.headCommit()
+	if drift.normalizedRepo != "local" {
+		drift.ci = c.detectCIMetadata(ciRepository{
+			normalized: drift.normalizedRepo,
+			headCommit: drift.commitSHA,
+		})
+	}
+	return drift
+}
+
+func (c *cli) syncCloudDrift(drift *driftRun, s *config.Stack, env []run.EnvVar, status stack.Status, changeset string) {
+	logger := log.With().
+		Str("organization", c.cloud.run.orgUUID).
+		Str("stack", s.RelPath()).
+		Stringer("status", status).
+		Logger()
+
+	cmd := c.parsedArgs.Experimental.Drift.Run.Command
+
+	payload := cloud.DriftStackPayloadRequest{
+		Repository:      drift.normalizedRepo,
+		Path:            s.Dir.String(),
+		MetaID:          strings.ToLower(s.ID),
+		MetaName:        s.Name,
+		MetaDescription: s.Description,
+		MetaTags:        s.Tags,
+		CommitSHA:       drift.commitSHA,
+		Status:          status,
+		Metadata:        drift.ci.metadata,
+		Command:         run.Redact(env, strings.Join(cmd, " ")),
+	}
+
+	if status == stack.Drifted {
+		payload.Details = &cloud.DriftDetails{
+			Provisioner:    filepath.Base(cmd[0]),
+			ChangesetASCII: run.Redact(env, changeset),
+		}
+	}
+
+	logger.Debug().Msg("syncing drift status")
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCloudTimeout)
+	defer cancel()
+	err := c.cloud.client.CreateStackDrift(ctx, c.cloud.run.orgUUID, payload)
+	if err != nil {
+		logger.Err(err).Msg("failed to sync the drift status")
+	}
+}
+
+func (c *cli) printDriftSummary(drift *driftRun) {
+	if len(drift.statuses) == 0 {
+		return
+	}
+	c.output.MsgStdErr("\nDrift detection summary:")
+	for _, res := range drift.statuses {
+		c.output.MsgStdErr("\t%s: %s", res.stack.Dir, res.status)
+	}
+}
+
+// driftDetected tells if the command failed only because it detected a drift.
+func driftDetected(err error) bool {
+	if errors.IsKind(err, run.ErrTimeout) {
+		return false
+	}
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == driftExitCode
+}
+
+// onlyDrifted tells if all the commands which did not succeed detected a drift.
+func onlyDrifted(drift *driftRun) bool {
+	for _, res := range drift.statuses {
+		if res.status != stack.OK && res.status != stack.Drifted {
+			return false
+		}
+	}
+	return true
+}
+
+// captureOutput is a [run.Output] which also keeps the stdout of each stack,
+// so it can be reported as the changeset of the drift.
+type captureOutput struct {
+	run.Output
+
+	stdouts map[*config.Stack]*bytes.Buffer
+}
+
+func newCaptureOutput(output run.Output) *captureOutput {
+	return &captureOutput{
+		Output:  output,
+		stdouts: map[*config.Stack]*bytes.Buffer{},
+	}
+}
+
+func (o *captureOutput) Open(s *config.Stack) (io.Writer, io.Writer, error) {
+	stdout, stderr, err := o.Output.Open(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the buffer is only written by the command of the stack and read after
+	// the command finished.
+	buf := &bytes.Buffer{}
+	o.stdouts[s] = buf
+	return io.MultiWriter(stdout, buf), stderr, nil
+}
+
+// release returns the captured stdout of the stack and forgets it.
+func (o *captureOutput) release(s *config.Stack) string {
+	buf, ok := o.stdouts[s]
+	if !ok {
+		return ""
+	}
+	delete(o.stdouts, s)
+	return buf.String()
+}
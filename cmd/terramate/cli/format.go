@@ -0,0 +1,135 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"encoding/json"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	prj "github.com/terramate-io/terramate/project"
+)
+
+const formatJSON = "json"
+
+// stackJSON is the JSON representation of a stack used by the commands
+// supporting the --format=json flag.
+type stackJSON struct {
+	Path        string   `json:"path"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	After       []string `json:"after"`
+	Before      []string `json:"before"`
+	Wants       []string `json:"wants"`
+	WantedBy    []string `json:"wanted_by"`
+	Watch       []string `json:"watch"`
+	IsChanged   bool     `json:"is_changed"`
+	Reason      string   `json:"reason,omitempty"`
+
+	// Env is the run environment of the stack, with the sensitive values
+	// redacted. It's only set by run --dry-run.
+	Env []string `json:"env,omitempty"`
+}
+
+type stacksJSON struct {
+	Stacks []stackJSON `json:"stacks"`
+}
+
+func newStackJSON(s *config.Stack) stackJSON {
+	return stackJSON{
+		Path:        s.Dir.String(),
+		ID:          s.ID,
+		Name:        s.Name,
+		Description: s.Description,
+		Tags:        nonNilStrings(s.Tags),
+		After:       nonNilStrings(s.After),
+		Before:      nonNilStrings(s.Before),
+		Wants:       nonNilStrings(s.Wants),
+		WantedBy:    nonNilStrings(s.WantedBy),
+		Watch:       prj.Paths(s.Watch).Strings(),
+		IsChanged:   s.IsChanged,
+	}
+}
+
+func sortableToStackJSON(stacks config.List[*config.SortableStack]) []stackJSON {
+	res := make([]stackJSON, 0, len(stacks))
+	for _, s := range stacks {
+		res = append(res, newStackJSON(s.Stack))
+	}
+	return res
+}
+
+func (c *cli) printStacksJSON(stacks []stackJSON) {
+	if stacks == nil {
+		stacks = []stackJSON{}
+	}
+	data, err := json.MarshalIndent(stacksJSON{Stacks: stacks}, "", "\t")
+	if err != nil {
+		fatal(err, "encoding stacks as JSON")
+	}
+	c.output.MsgStdOut(string(data))
+}
+
+// generateReportJSON is the JSON representation of the code generation
+// report used by the generate command.
+type generateReportJSON struct {
+	Successes   []generateResultJSON `json:"successes"`
+	Failures    []generateResultJSON `json:"failures"`
+	ManualEdits []string             `json:"manual_edits,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+type generateResultJSON struct {
+	Dir     string   `json:"dir"`
+	Created []string `json:"created"`
+	Changed []string `json:"changed"`
+	Deleted []string `json:"deleted"`
+	Error   string   `json:"error,omitempty"`
+}
+
+func newGenerateResultJSON(res generate.Result) generateResultJSON {
+	return generateResultJSON{
+		Dir:     res.Dir.String(),
+		Created: nonNilStrings(res.Created),
+		Changed: nonNilStrings(res.Changed),
+		Deleted: nonNilStrings(res.Deleted),
+	}
+}
+
+func (c *cli) printGenerateReportJSON(report generate.Report) {
+	res := generateReportJSON{
+		Successes: []generateResultJSON{},
+		Failures:  []generateResultJSON{},
+	}
+	for _, success := range report.Successes {
+		res.Successes = append(res.Successes, newGenerateResultJSON(success))
+	}
+	for _, failure := range report.Failures {
+		failureJSON := newGenerateResultJSON(failure.Result)
+		failureJSON.Error = failure.Error.Error()
+		res.Failures = append(res.Failures, failureJSON)
+	}
+	for _, path := range report.ManualEdits {
+		res.ManualEdits = append(res.ManualEdits, path.String())
+	}
+	if err := errors.L(report.BootstrapErr, report.CleanupErr).AsError(); err != nil {
+		res.Error = err.Error()
+	}
+
+	data, err := json.MarshalIndent(res, "", "\t")
+	if err != nil {
+		fatal(err, "encoding generate report as JSON")
+	}
+	c.output.MsgStdOut(string(data))
+}
+
+func nonNilStrings(vals []string) []string {
+	if vals == nil {
+		return []string{}
+	}
+	return vals
+}
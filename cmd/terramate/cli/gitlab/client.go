@@ -0,0 +1,144 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+// Package gitlab implements a client SDK for the GitLab API.
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/terramate-io/terramate/errors"
+)
+
+const (
+	// ErrNotFound indicates the resource does not exists.
+	ErrNotFound errors.Kind = "resource not found (HTTP Status: 404)"
+	// ErrUnauthorized indicates the request was not authenticated or the
+	// token has no access to the resource.
+	ErrUnauthorized errors.Kind = "unauthorized request (HTTP Status: 401)"
+)
+
+const (
+	// Domain is the default GitLab domain.
+	Domain = "gitlab.com"
+	// APIBaseURL is the default base url for the GitLab API.
+	APIBaseURL = "https://" + Domain + "/api/v4"
+)
+
+type (
+	// Client is a GitLab HTTP client wrapper.
+	Client struct {
+		// BaseURL is the base URL used to construct the final URL of endpoints.
+		// If not set, then https://gitlab.com/api/v4 is used.
+		BaseURL string
+
+		// HTTPClient sets the HTTP client used and then allows for advanced
+		// connection reuse schemes. If not set, a new http.Client is used.
+		HTTPClient *http.Client
+
+		// Token is a personal, project or group access token (usually provided
+		// by the GITLAB_TOKEN environment variable).
+		Token string
+
+		// JobToken is the CI job token (provided by the CI_JOB_TOKEN
+		// environment variable in GitLab CI). It's only used if Token is not set.
+		JobToken string
+	}
+)
+
+// MergeRequestsForCommit returns a list of merge request objects associated
+// with the given commit SHA.
+func (c *Client) MergeRequestsForCommit(ctx context.Context, projectID, commit string) (mrs []MergeRequest, err error) {
+	if projectID == "" {
+		return nil, errors.E("expects a valid GitLab project ID")
+	}
+	endpoint := fmt.Sprintf("%s/projects/%s/repository/commits/%s/merge_requests",
+		c.baseURL(), url.PathEscape(projectID), commit)
+	data, err := c.doGet(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &mrs)
+	if err != nil {
+		return nil, errors.E(err, "unmarshaling merge request list")
+	}
+	return mrs, nil
+}
+
+// MergeRequest retrieves the merge request with the given internal ID.
+func (c *Client) MergeRequest(ctx context.Context, projectID string, iid int) (*MergeRequest, error) {
+	if projectID == "" {
+		return nil, errors.E("expects a valid GitLab project ID")
+	}
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%d", c.baseURL(), url.PathEscape(projectID), iid)
+	data, err := c.doGet(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var mr MergeRequest
+	err = json.Unmarshal(data, &mr)
+	if err != nil {
+		return nil, errors.E(err, "unmarshaling merge request")
+	}
+	return &mr, nil
+}
+
+func (c *Client) doGet(ctx context.Context, url string) (data []byte, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, errors.E(err, "creating request")
+	}
+
+	if c.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.Token)
+	} else if c.JobToken != "" {
+		req.Header.Set("JOB-TOKEN", c.JobToken)
+	}
+
+	client := c.httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.E(err, "requesting GET %s", url)
+	}
+
+	defer func() {
+		err = errors.L(err, resp.Body.Close()).AsError()
+	}()
+
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.E(err, "reading response body")
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.E(ErrNotFound, "retrieving %s", url)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, errors.E(ErrUnauthorized, "retrieving %s", url)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.E("unexpected status code: %s while getting %s", resp.Status, url)
+	}
+	return data, nil
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		c.BaseURL = APIBaseURL
+	}
+	return c.BaseURL
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{}
+	}
+	return c.HTTPClient
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package gitlab_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/cmd/terramate/cli/gitlab"
+	"github.com/terramate-io/terramate/errors"
+	errtest "github.com/terramate-io/terramate/test/errors"
+)
+
+const mergeRequestJSON = `{
+	"id": 1000,
+	"iid": 7,
+	"project_id": 42,
+	"title": "Add stacks",
+	"description": "some description",
+	"state": "opened",
+	"web_url": "https://gitlab.com/group/project/-/merge_requests/7",
+	"source_branch": "feature",
+	"target_branch": "main",
+	"sha": "a1b2c3",
+	"author": {
+		"id": 1,
+		"username": "batman",
+		"name": "Bruce Wayne",
+		"avatar_url": "https://gitlab.com/avatar.png"
+	},
+	"created_at": "2023-08-01T10:00:00Z",
+	"updated_at": "2023-08-02T10:00:00Z",
+	"merged_at": null
+}`
+
+func TestMergeRequestsForCommit(t *testing.T) {
+	t.Parallel()
+
+	var gotPath, gotToken string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotToken = r.Header.Get("PRIVATE-TOKEN")
+		_, _ = w.Write([]byte("[" + mergeRequestJSON + "]"))
+	}))
+	defer s.Close()
+
+	client := gitlab.Client{
+		BaseURL:  s.URL,
+		Token:    "secret",
+		JobToken: "job-secret",
+	}
+	mrs, err := client.MergeRequestsForCommit(context.Background(), "group/project", "a1b2c3")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "/projects/group%2Fproject/repository/commits/a1b2c3/merge_requests", gotPath)
+	assert.EqualStrings(t, "secret", gotToken)
+	assert.EqualInts(t, 1, len(mrs))
+
+	mr := mrs[0]
+	assert.EqualInts(t, 7, mr.IID)
+	assert.EqualStrings(t, "Add stacks", mr.Title)
+	assert.EqualStrings(t, "https://gitlab.com/group/project/-/merge_requests/7", mr.WebURL)
+	assert.EqualStrings(t, "batman", mr.Author.Username)
+	assert.IsTrue(t, mr.CreatedAt != nil)
+	assert.IsTrue(t, mr.MergedAt == nil)
+}
+
+func TestMergeRequestWithJobToken(t *testing.T) {
+	t.Parallel()
+
+	var gotPath, gotToken string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotToken = r.Header.Get("JOB-TOKEN")
+		_, _ = w.Write([]byte(mergeRequestJSON))
+	}))
+	defer s.Close()
+
+	client := gitlab.Client{
+		BaseURL:  s.URL,
+		JobToken: "job-secret",
+	}
+	mr, err := client.MergeRequest(context.Background(), "42", 7)
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "/projects/42/merge_requests/7", gotPath)
+	assert.EqualStrings(t, "job-secret", gotToken)
+	assert.EqualStrings(t, "feature", mr.SourceBranch)
+	assert.EqualStrings(t, "main", mr.TargetBranch)
+}
+
+func TestClientErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		status int
+		want   error
+	}{
+		{status: http.StatusNotFound, want: errors.E(gitlab.ErrNotFound)},
+		{status: http.StatusUnauthorized, want: errors.E(gitlab.ErrUnauthorized)},
+		{status: http.StatusForbidden, want: errors.E(gitlab.ErrUnauthorized)},
+	} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+		}))
+
+		client := gitlab.Client{BaseURL: s.URL}
+		_, err := client.MergeRequest(context.Background(), "42", 7)
+		errtest.Assert(t, err, tc.want)
+		s.Close()
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package gitlab
+
+import "time"
+
+type (
+	// MergeRequest represents a merge request object.
+	MergeRequest struct {
+		ID           int        `json:"id"`
+		IID          int        `json:"iid"`
+		ProjectID    int        `json:"project_id"`
+		Title        string     `json:"title"`
+		Description  string     `json:"description"`
+		State        string     `json:"state"`
+		WebURL       string     `json:"web_url"`
+		SourceBranch string     `json:"source_branch"`
+		TargetBranch string     `json:"target_branch"`
+		SHA          string     `json:"sha"`
+		Author       User       `json:"author"`
+		CreatedAt    *time.Time `json:"created_at"`
+		UpdatedAt    *time.Time `json:"updated_at"`
+		MergedAt     *time.Time `json:"merged_at"`
+
+		// rest of the fields aren't important for the cli.
+	}
+
+	// User represents the GitLab user.
+	User struct {
+		ID        int    `json:"id"`
+		Username  string `json:"username"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+		WebURL    string `json:"web_url"`
+
+		// rest of the fields aren't important for the cli.
+	}
+)
@@ -0,0 +1,145 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestCloudInfoCredentials(t *testing.T) {
+	type testcase struct {
+		name  string
+		env   func(dir string) []string
+		files map[string]string
+		want  runExpected
+	}
+
+	oidcToken := func(t *testing.T) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(1 * time.Hour).Unix(),
+			Issuer:    "https://gitlab.example.com",
+			Subject:   "project_path:group/project:ref_type:branch:ref:main",
+		})
+		signed, err := token.SignedString([]byte("test"))
+		assert.NoError(t, err)
+		return signed
+	}(t)
+
+	startFakeTMCServer(t)
+
+	for _, tc := range []testcase{
+		{
+			name: "api key from env",
+			env: func(string) []string {
+				return []string{"TM_CLOUD_API_KEY=tmco_abcdef123456"}
+			},
+			want: runExpected{
+				Stdout: `status: signed in
+provider: API Key
+key: *************3456
+source: TM_CLOUD_API_KEY environment variable
+organizations: terramate-io
+`,
+			},
+		},
+		{
+			name: "api key from file",
+			env: func(dir string) []string {
+				return []string{"TM_CLOUD_API_KEY_FILE=" + filepath.Join(dir, "apikey")}
+			},
+			files: map[string]string{"apikey": "tmco_abcdef123456\n"},
+			want: runExpected{
+				StdoutRegex: "provider: API Key\nkey: \\*+3456\nsource: .*apikey\n",
+			},
+		},
+		{
+			name: "api key has precedence over the oidc token",
+			env: func(string) []string {
+				return []string{
+					"TM_CLOUD_API_KEY=tmco_abcdef123456",
+					"TM_CLOUD_OIDC_TOKEN=" + oidcToken,
+				}
+			},
+			want: runExpected{
+				StdoutRegex: "provider: API Key",
+			},
+		},
+		{
+			name: "missing api key file fails",
+			env: func(dir string) []string {
+				return []string{"TM_CLOUD_API_KEY_FILE=" + filepath.Join(dir, "missing")}
+			},
+			want: runExpected{
+				Status:      1,
+				StderrRegex: "reading the file in the TM_CLOUD_API_KEY_FILE environment variable",
+			},
+		},
+		{
+			name: "empty api key file fails",
+			env: func(dir string) []string {
+				return []string{"TM_CLOUD_API_KEY_FILE=" + filepath.Join(dir, "apikey")}
+			},
+			files: map[string]string{"apikey": "\n"},
+			want: runExpected{
+				Status:      1,
+				StderrRegex: "TM_CLOUD_API_KEY_FILE environment variable is empty",
+			},
+		},
+		{
+			name: "oidc token from env",
+			env: func(string) []string {
+				return []string{"TM_CLOUD_OIDC_TOKEN=" + oidcToken}
+			},
+			want: runExpected{
+				Stdout: `status: signed in
+provider: OIDC
+issuer: https://gitlab.example.com
+subject: project_path:group/project:ref_type:branch:ref:main
+source: TM_CLOUD_OIDC_TOKEN environment variable
+organizations: terramate-io
+`,
+			},
+		},
+		{
+			name: "oidc token from file",
+			env: func(dir string) []string {
+				return []string{"TM_CLOUD_OIDC_TOKEN_FILE=" + filepath.Join(dir, "token")}
+			},
+			files: map[string]string{"token": oidcToken},
+			want: runExpected{
+				StdoutRegex: "provider: OIDC\nissuer: https://gitlab.example.com\n",
+			},
+		},
+		{
+			name: "invalid oidc token fails",
+			env: func(string) []string {
+				return []string{"TM_CLOUD_OIDC_TOKEN=not-a-jwt"}
+			},
+			want: runExpected{
+				Status:      1,
+				StderrRegex: "parsing the OIDC token from TM_CLOUD_OIDC_TOKEN environment variable",
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := sandbox.New(t)
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				test.WriteFile(t, dir, name, content)
+			}
+
+			tm := newCLI(t, s.RootDir())
+			tm.appendEnv = append(tm.appendEnv, tc.env(dir)...)
+			assertRunResult(t, tm.run("experimental", "cloud", "info"), tc.want)
+		})
+	}
+}
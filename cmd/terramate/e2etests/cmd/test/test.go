@@ -44,6 +44,8 @@ func main() {
 		cat(os.Args[2])
 	case "stack-abs-path":
 		stackAbsPath(os.Args[2])
+	case "plan":
+		plan(os.Args[2])
 	default:
 		log.Fatalf("unknown command %s", os.Args[1])
 	}
@@ -101,3 +103,20 @@ func stackAbsPath(base string) {
 	}
 	fmt.Println("/" + filepath.ToSlash(rel))
 }
+
+// plan mimics `terraform plan -detailed-exitcode` by printing the changes in
+// the given file. It exits with 2 if there are changes, 0 if the file is
+// empty and 1 if the file does not exist.
+func plan(fname string) {
+	bytes, err := os.ReadFile(fname)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if len(bytes) == 0 {
+		fmt.Println("No changes.")
+		os.Exit(0)
+	}
+	fmt.Printf("%s", string(bytes))
+	os.Exit(2)
+}
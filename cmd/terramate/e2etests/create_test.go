@@ -276,6 +276,23 @@ func TestCreateWithAllTerraformModuleAtRoot(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestCreateWithAllTerraformJSONAndOpenTofu(t *testing.T) {
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`f:json/main.tf.json:{"terraform": {"backend": {"s3": {"bucket": "b"}}}}`,
+		`f:tofu/main.tofu:provider "aws" {}`,
+		`f:tofu-json/main.tofu.json:{"provider": {"aws": {}}}`,
+		`f:module/main.tf.json:{"variable": {"a": {}}}`,
+	})
+	tm := newCLI(t, s.RootDir())
+	assertRunResult(t,
+		tm.run("create", "--all-terraform"),
+		runExpected{
+			Stdout: "Created stack /json\nCreated stack /tofu\nCreated stack /tofu-json\n",
+		},
+	)
+}
+
 func TestCreateWithAllTerraformModuleDeepDownInTheTree(t *testing.T) {
 	testCase := func(t *testing.T, generate bool) {
 		s := sandbox.New(t)
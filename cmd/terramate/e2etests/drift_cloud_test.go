@@ -0,0 +1,227 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/cloud"
+	"github.com/terramate-io/terramate/cloud/stack"
+	"github.com/terramate-io/terramate/cloud/testserver"
+	cloudtest "github.com/terramate-io/terramate/test/cloud"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestCloudDriftRun(t *testing.T) {
+	type want struct {
+		run    runExpected
+		drifts map[string]cloud.Drifts
+		// unhealthy are the stacks listed by list --experimental-status=unhealthy
+		unhealthy string
+	}
+	type testcase struct {
+		name      string
+		layout    []string
+		skipIDGen bool
+		want      want
+	}
+
+	const (
+		repository = "gitlab.com/terramate-io/terramate"
+		changes    = "+ resource \"null_resource\" \"drift\"\n"
+	)
+
+	cmd := []string{testHelperBin, "plan", "changes.txt"}
+	cmdStr := strings.Join(cmd, " ")
+	provisioner := filepath.Base(testHelperBin)
+
+	startFakeTMCServer(t)
+
+	for _, tc := range []testcase{
+		{
+			name: "all stacks must have ids",
+			layout: []string{
+				"s:s1",
+				"f:s1/changes.txt:" + changes,
+			},
+			skipIDGen: true,
+			want: want{
+				run: runExpected{
+					Status:      1,
+					StderrRegex: "drift detection requires that selected stacks contain an ID field",
+				},
+			},
+		},
+		{
+			name: "stack without changes is ok",
+			layout: []string{
+				"s:s1",
+				"f:s1/changes.txt:",
+			},
+			want: want{
+				run: runExpected{
+					Stdout:      "No changes.\n",
+					StderrRegex: "/s1: ok",
+				},
+				drifts: map[string]cloud.Drifts{
+					"s1": {
+						{
+							Status:  stack.OK,
+							Command: cmdStr,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "stack with changes is drifted",
+			layout: []string{
+				"s:s1",
+				"s:s2",
+				"f:s1/changes.txt:" + changes,
+				"f:s2/changes.txt:",
+			},
+			want: want{
+				run: runExpected{
+					Stdout:      changes + "No changes.\n",
+					StderrRegex: "/s1: drifted",
+				},
+				drifts: map[string]cloud.Drifts{
+					"s1": {
+						{
+							Status: stack.Drifted,
+							Details: &cloud.DriftDetails{
+								Provisioner:    provisioner,
+								ChangesetASCII: changes,
+							},
+							Command: cmdStr,
+						},
+					},
+					"s2": {
+						{
+							Status:  stack.OK,
+							Command: cmdStr,
+						},
+					},
+				},
+				unhealthy: "s1\n",
+			},
+		},
+		{
+			name: "failed detection does not stop other stacks",
+			layout: []string{
+				"s:s1",
+				"s:s2",
+				"f:s2/changes.txt:" + changes,
+			},
+			want: want{
+				run: runExpected{
+					Status:      1,
+					Stdout:      changes,
+					StderrRegex: "one or more drift detections failed",
+				},
+				drifts: map[string]cloud.Drifts{
+					"s1": {
+						{
+							Status:  stack.Failed,
+							Command: cmdStr,
+						},
+					},
+					"s2": {
+						{
+							Status: stack.Drifted,
+							Details: &cloud.DriftDetails{
+								Provisioner:    provisioner,
+								ChangesetASCII: changes,
+							},
+							Command: cmdStr,
+						},
+					},
+				},
+				// failed detections keep the unknown status of new stacks.
+				unhealthy: "s1\ns2\n",
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := sandbox.New(t)
+
+			ids := map[string]string{}
+			var layout []string
+			for _, spec := range tc.layout {
+				if spec[0] == 's' && !tc.skipIDGen {
+					name := spec[2:]
+					id := strings.ToLower(strings.Replace(name+"-id-"+t.Name(), "/", "-", -1))
+					if len(id) > 64 {
+						id = id[:64]
+					}
+					ids[name] = id
+					spec += ":id=" + id
+				}
+				layout = append(layout, spec)
+			}
+
+			s.BuildTree(layout)
+			s.Git().SetRemoteURL("origin", repository)
+			s.Git().CommitAll("all stacks committed")
+
+			cli := newCLI(t, s.RootDir())
+			cli.appendEnv = []string{"TM_DISABLE_CHECK_GIT_REMOTE=1"}
+
+			args := append([]string{"experimental", "drift", "run", "--"}, cmd...)
+			assertRunResult(t, cli.run(args...), tc.want.run)
+
+			for name, want := range tc.want.drifts {
+				got := stackDrifts(t, repository, ids[name])
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Fatalf("stack %s drifts mismatch (-want +got):\n%s", name, diff)
+				}
+			}
+
+			if tc.want.unhealthy != "" {
+				assertRunResult(t,
+					cli.run("list", "--experimental-status=unhealthy"),
+					runExpected{Stdout: tc.want.unhealthy},
+				)
+			}
+		})
+	}
+}
+
+// stackDrifts returns the drifts of the stack, ignoring their IDs.
+func stackDrifts(t *testing.T, repository, metaID string) cloud.Drifts {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := &cloud.Client{
+		BaseURL:    cloudtest.TestEndpoint,
+		Credential: &credential{},
+	}
+
+	stacks, err := client.Stacks(ctx, testserver.DefaultOrgUUID, stack.NoFilter)
+	assert.NoError(t, err)
+
+	for _, st := range stacks.Stacks {
+		if st.Repository != repository || st.MetaID != metaID {
+			continue
+		}
+		drifts, err := client.StackDrifts(ctx, testserver.DefaultOrgUUID, st.ID)
+		assert.NoError(t, err)
+		for i := range drifts {
+			drifts[i].ID = 0
+		}
+		return drifts
+	}
+	t.Fatalf("stack %s not found in the cloud", metaID)
+	return nil
+}
@@ -214,3 +214,44 @@ func TestGenerateDebugWithChanged(t *testing.T) {
 	ts = newCLI(t, filepath.Join(s.RootDir(), "no-stack"))
 	assertRunResult(t, ts.run("experimental", "generate", "debug", "--changed"), runExpected{})
 }
+
+func TestGenerateOrigin(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:gen.tm:generate_file "file.txt" {
+  content = "data"
+}
+`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("generate"), runExpected{IgnoreStdout: true})
+
+	stackcli := newCLI(t, filepath.Join(s.RootDir(), "stack"))
+	assertRunResult(t, stackcli.run("experimental", "generate", "origin", "file.txt"), runExpected{
+		Stdout: `file:   /stack/file.txt
+block:  generate_file "file.txt"
+origin: /gen.tm:1,1-3,2
+stack:  /stack
+status: unmodified
+`,
+	})
+
+	s.DirEntry("stack").CreateFile("file.txt", "edited")
+	assertRunResult(t, cli.run("experimental", "generate", "origin", "stack/file.txt"), runExpected{
+		StdoutRegex: "status: modified",
+	})
+
+	s.DirEntry("stack").RemoveFile("file.txt")
+	assertRunResult(t, cli.run("experimental", "generate", "origin", "stack/file.txt"), runExpected{
+		StdoutRegex: "status: missing",
+	})
+
+	assertRunResult(t, cli.run("experimental", "generate", "origin", "gen.tm"), runExpected{
+		Status:      1,
+		StderrRegex: "not recorded as generated",
+	})
+}
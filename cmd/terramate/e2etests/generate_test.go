@@ -5,9 +5,11 @@ package e2etest
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/madlambda/spells/assert"
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/generate"
 	"github.com/terramate-io/terramate/modvendor"
@@ -316,29 +318,21 @@ Hint: '+', '~' and '-' means the file was created, changed and deleted, respecti
 	}
 }
 
-func TestGenerateIgnoresWorkingDirectory(t *testing.T) {
-	wantStdout := generate.Report{
-		Successes: []generate.Result{
-			{
-				Dir: project.NewPath("/"),
-				Created: []string{
-					"root.stacks.txt",
-				},
-			},
-			{
-				Dir: project.NewPath("/stacks/stack-1"),
-				Created: []string{
-					"stack.hcl", "stack.name.txt",
-				},
-			},
-			{
-				Dir: project.NewPath("/stacks/stack-2"),
-				Created: []string{
-					"stack.hcl", "stack.name.txt",
-				},
+func TestGenerateHonoursWorkingDirectory(t *testing.T) {
+	stackResult := func(dir string) generate.Result {
+		return generate.Result{
+			Dir: project.NewPath(dir),
+			Created: []string{
+				"stack.hcl", "stack.name.txt",
 			},
+		}
+	}
+	rootResult := generate.Result{
+		Dir: project.NewPath("/"),
+		Created: []string{
+			"root.stacks.txt",
 		},
-	}.Full() + "\n"
+	}
 
 	configStr := Doc(
 		GenerateFile(
@@ -359,8 +353,8 @@ func TestGenerateIgnoresWorkingDirectory(t *testing.T) {
 		),
 	).String()
 
-	runFromDir := func(t *testing.T, wd string) {
-		t.Run(fmt.Sprintf("terramate -C %s generate", wd), func(t *testing.T) {
+	runFromDir := func(t *testing.T, wd string, args []string, want ...generate.Result) {
+		t.Run(fmt.Sprintf("terramate -C %s generate %v", wd, args), func(t *testing.T) {
 			s := sandbox.New(t)
 			s.BuildTree([]string{
 				"s:stacks/stack-1",
@@ -373,17 +367,168 @@ func TestGenerateIgnoresWorkingDirectory(t *testing.T) {
 			)
 
 			tmcli := newCLI(t, filepath.Join(s.RootDir(), wd))
-			res := tmcli.run("generate")
+			res := tmcli.run(append([]string{"generate"}, args...)...)
 			expected := runExpected{
-				Stdout: wantStdout,
+				Stdout: generate.Report{Successes: want}.Full() + "\n",
 			}
 			assertRunResult(t, res, expected)
 		})
 	}
 
-	runFromDir(t, "/")
-	runFromDir(t, "/stacks")
-	runFromDir(t, "/stacks/stack-1")
+	runFromDir(t, "/", nil,
+		rootResult, stackResult("/stacks/stack-1"), stackResult("/stacks/stack-2"))
+	runFromDir(t, "/stacks", nil,
+		stackResult("/stacks/stack-1"), stackResult("/stacks/stack-2"))
+	runFromDir(t, "/stacks/stack-1", nil,
+		stackResult("/stacks/stack-1"))
+	runFromDir(t, "/stacks/stack-1", []string{"--include-root-context"},
+		rootResult, stackResult("/stacks/stack-1"))
+}
+
+func TestGenerateSelectedStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/a:tags=["app"]`,
+		`s:stacks/b:tags=["db"]`,
+		`f:gen.tm:generate_file "stack.txt" {
+  content = terramate.stack.name
+}
+`,
+	})
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-a")
+
+	tmcli := newCLI(t, s.RootDir())
+	assertRunResult(t, tmcli.run("generate", "--tags", "db"), runExpected{
+		Stdout: generate.Report{
+			Successes: []generate.Result{
+				{
+					Dir:     project.NewPath("/stacks/b"),
+					Created: []string{"stack.txt"},
+				},
+			},
+		}.Full() + "\n",
+	})
+
+	s.DirEntry("stacks/a").CreateFile("main.tf", "# changed")
+	git.CommitAll("change stack a")
+
+	assertRunResult(t, tmcli.run("generate", "--changed"), runExpected{
+		Stdout: generate.Report{
+			Successes: []generate.Result{
+				{
+					Dir:     project.NewPath("/stacks/a"),
+					Created: []string{"stack.txt"},
+				},
+			},
+		}.Full() + "\n",
+	})
+}
+
+func TestGenerateCheck(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:stack/gen.tm:generate_file "a.txt" {
+  content = "a\nb\n"
+}
+`,
+	})
+	stack := s.DirEntry("stack")
+	tmcli := newCLI(t, s.RootDir())
+
+	for _, flag := range []string{"--check", "--dry-run"} {
+		assertRunResult(t, tmcli.run("generate", flag), runExpected{
+			Stdout: `--- /dev/null
++++ b/stack/a.txt
+@@ -0,0 +1,2 @@
++a
++b
+`,
+			Status: 1,
+		})
+	}
+
+	_, err := os.Stat(filepath.Join(stack.Path(), "a.txt"))
+	assert.IsTrue(t, os.IsNotExist(err), "generate --check must not create files")
+
+	assertRunResult(t, tmcli.run("generate"), runExpected{IgnoreStdout: true})
+	assertRunResult(t, tmcli.run("generate", "--check"), runExpected{
+		Stdout: "Nothing to do, generated code is up to date\n",
+	})
+
+	stack.CreateFile("a.txt", "a\nc\n")
+	assertRunResult(t, tmcli.run("generate", "--check"), runExpected{
+		Stdout: `--- a/stack/a.txt
++++ b/stack/a.txt
+@@ -1,2 +1,2 @@
+ a
+-c
++b
+`,
+		Status: 1,
+	})
+	assertRunResult(t, tmcli.run("generate", "--check", "--format", "json"), runExpected{
+		Stdout: `{
+	"successes": [
+		{
+			"dir": "/stack",
+			"created": [],
+			"changed": [
+				"a.txt"
+			],
+			"deleted": []
+		}
+	],
+	"failures": [],
+	"manual_edits": [
+		"/stack/a.txt"
+	]
+}
+`,
+		Status: 1,
+	})
+	assert.EqualStrings(t, "a\nc\n", string(stack.ReadFile("a.txt")))
+}
+
+func TestGenerateCheckFailsOnCleanupError(t *testing.T) {
+	t.Parallel()
+
+	// the manifest records a generated file of a deleted stack at a path
+	// which is now a directory, so checking the orphaned file fails.
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"d:dir",
+		`f:.terramate/generated.json:{
+  "version": 1,
+  "files": {
+    "/dir": {
+      "sha256": "",
+      "stack": "/deleted",
+      "block": "generate_file",
+      "label": "dir",
+      "origin": {"file": "", "start": {"line": 0, "column": 0}, "end": {"line": 0, "column": 0}}
+    }
+  }
+}
+`,
+	})
+	tmcli := newCLI(t, s.RootDir())
+
+	assertRunResult(t, tmcli.run("generate", "--check"), runExpected{
+		StderrRegex: "Fatal failure while cleaning up generated code",
+		Status:      1,
+	})
+	assertRunResult(t, tmcli.run("generate", "--check", "--format", "json"), runExpected{
+		StdoutRegex: `"error": ".*is a directory"`,
+		Status:      1,
+	})
 }
 
 type str string
@@ -0,0 +1,109 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+type stacksJSON struct {
+	Stacks []stackJSON `json:"stacks"`
+}
+
+type stackJSON struct {
+	Path        string   `json:"path"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	After       []string `json:"after"`
+	Before      []string `json:"before"`
+	Wants       []string `json:"wants"`
+	WantedBy    []string `json:"wanted_by"`
+	Watch       []string `json:"watch"`
+	IsChanged   bool     `json:"is_changed"`
+	Reason      string   `json:"reason"`
+}
+
+func TestListAndOrderFormatJSON(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a:id=stack-a;description=desc;tags=["app","prod"]`,
+		`s:stack-b:id=stack-b;before=["/stack-a"];watch=["/file.txt"]`,
+		`f:file.txt:watched`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-stack-a")
+	s.StackEntry("stack-a").CreateFile("main.tf", "# changed")
+	git.CommitAll("stack-a changed")
+
+	stackA := stackJSON{
+		Path:        "/stack-a",
+		ID:          "stack-a",
+		Name:        "stack-a",
+		Description: "desc",
+		Tags:        []string{"app", "prod"},
+		After:       []string{},
+		Before:      []string{},
+		Wants:       []string{},
+		WantedBy:    []string{},
+		Watch:       []string{},
+	}
+	stackB := stackJSON{
+		Path:        "/stack-b",
+		ID:          "stack-b",
+		Name:        "stack-b",
+		Description: "",
+		Tags:        []string{},
+		After:       []string{},
+		Before:      []string{"/stack-a"},
+		Wants:       []string{},
+		WantedBy:    []string{},
+		Watch:       []string{"/file.txt"},
+	}
+
+	cli := newCLI(t, s.RootDir())
+
+	assertStacks := func(res runResult, want ...stackJSON) {
+		t.Helper()
+
+		assertRunResult(t, res, runExpected{IgnoreStdout: true})
+
+		var got stacksJSON
+		if err := json.Unmarshal([]byte(res.Stdout), &got); err != nil {
+			t.Fatalf("invalid JSON output %q: %v", res.Stdout, err)
+		}
+		if want == nil {
+			want = []stackJSON{}
+		}
+		if diff := cmp.Diff(want, got.Stacks); diff != "" {
+			t.Fatalf("-(want) +(got):\n%s", diff)
+		}
+	}
+
+	assertStacks(cli.run("list", "--format=json"), stackA, stackB)
+	assertStacks(cli.run("experimental", "run-order", "--format=json"), stackB, stackA)
+	assertStacks(cli.run("run", "--dry-run", "--format=json", "--", "echo"), stackB, stackA)
+
+	changedA := stackA
+	changedA.IsChanged = true
+	assertStacks(cli.run("list", "--changed", "--format=json"), changedA)
+
+	changedA.Reason = "stack has unmerged changes"
+	assertStacks(cli.run("list", "--changed", "--why", "--format=json"), changedA)
+
+	assertRunResult(t, cli.run("run", "--format=json", "--", "echo"), runExpected{
+		Status:      1,
+		StderrRegex: "--format=json can only be used together with --dry-run",
+	})
+}
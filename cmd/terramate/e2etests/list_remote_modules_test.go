@@ -0,0 +1,118 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestListChangedModuleFromSameRepository(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	git := s.Git()
+	modsrc := fmt.Sprintf(
+		"git::file://%s//modules/mod?ref=v1",
+		filepath.ToSlash(git.BareRepoAbsPath()),
+	)
+
+	s.BuildTree([]string{
+		`s:stack`,
+		`s:other-stack`,
+		`f:modules/mod/main.tf:# module`,
+		fmt.Sprintf("f:stack/main.tf:module \"mod\" {\n  source = %q\n}\n", modsrc),
+		`f:other-stack/main.tf:module "mod" {
+  source = "git::https://example.com/other/repo.git//modules/mod?ref=v1"
+}
+`,
+	})
+
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-module")
+
+	s.RootEntry().CreateFile("modules/mod/main.tf", "# changed module")
+	git.CommitAll("change module")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: "stack\n",
+	})
+	assertRunResult(t, cli.run("list", "--changed", "--why"), runExpected{
+		StdoutRegex: `^stack - .*same repository at /modules/mod.* has unmerged changes`,
+	})
+}
+
+func TestListChangedVendoredModule(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+		`s:other-stack`,
+		`f:modules/github.com/terramate-io/example/v1/sub/main.tf:# module`,
+		`f:stack/main.tf:module "mod" {
+  source = "github.com/terramate-io/example//sub?ref=v1"
+}
+`,
+		`f:other-stack/main.tf:module "mod" {
+  source = "github.com/terramate-io/example//sub?ref=v2"
+}
+`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-vendored-module")
+
+	s.RootEntry().CreateFile("modules/github.com/terramate-io/example/v1/sub/main.tf", "# changed")
+	git.CommitAll("change vendored module")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: "stack\n",
+	})
+	assertRunResult(t, cli.run("list", "--changed", "--why"), runExpected{
+		StdoutRegex: `^stack - .*vendored at /modules/github.com/terramate-io/example/v1/sub.* has unmerged changes`,
+	})
+}
+
+func TestListChangedModuleFromJSONAndOpenTofuFiles(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:json-stack`,
+		`s:tofu-stack`,
+		`s:not-changed-stack`,
+		`f:modules/json/main.tf.json:{"variable": {"a": {}}}`,
+		`f:modules/tofu/main.tofu:# module`,
+		`f:modules/other/main.tf:# module`,
+		`f:json-stack/main.tf.json:{"module": {"mod": {"source": "../modules/json"}}}`,
+		`f:tofu-stack/main.tofu:module "mod" {
+  source = "../modules/tofu"
+}
+`,
+		`f:not-changed-stack/main.tofu.json:{"module": {"mod": {"source": "../modules/other"}}}`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-modules")
+
+	s.RootEntry().CreateFile("modules/json/main.tf.json", `{"variable": {"b": {}}}`)
+	s.RootEntry().CreateFile("modules/tofu/main.tofu", "# changed")
+	git.CommitAll("change modules")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: listStacks("json-stack", "tofu-stack"),
+	})
+}
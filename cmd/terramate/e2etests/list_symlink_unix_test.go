@@ -0,0 +1,84 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build aix || android || darwin || dragonfly || freebsd || hurd || illumos || ios || linux || netbsd || openbsd || solaris
+
+package e2etest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestListChangedThroughSymlinks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:file-link-stack`,
+		`s:module-link-stack`,
+		`s:linked-module-stack`,
+		`s:chain-link-stack`,
+		`s:not-changed-stack`,
+		`s:loop-stack`,
+		`s:hidden-module-stack`,
+		`f:shared/common.tf:# common`,
+		`f:shared/other.tf:# other`,
+		`f:modules/mod/main.tf:# module`,
+		`f:module-link-stack/main.tf:module "mod" {
+  source = "./mod"
+}
+`,
+		`f:linked-module-stack/main.tf:module "mod" {
+  source = "../modules/link"
+}
+`,
+		`f:.modules/mod/main.tf:# hidden module`,
+		`f:hidden-module-stack/main.tf:module "mod" {
+  source = "../.modules/mod"
+}
+`,
+	})
+
+	symlink := func(target, link string) {
+		t.Helper()
+		assert.NoError(t, os.Symlink(target, filepath.Join(s.RootDir(), link)))
+	}
+
+	symlink("../shared/common.tf", "file-link-stack/common.tf")
+	symlink("../modules/mod", "module-link-stack/mod")
+	symlink("shared", "shared-link")
+	symlink("mod", "modules/link")
+	symlink("../shared-link/common.tf", "chain-link-stack/common.tf")
+	symlink("../shared/other.tf", "not-changed-stack/other.tf")
+	symlink("loop-b", "loop-stack/loop-a")
+	symlink("loop-a", "loop-stack/loop-b")
+	symlink("../../shared/common.tf", ".modules/mod/common.tf")
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-shared")
+
+	s.RootEntry().CreateFile("shared/common.tf", "# changed")
+	s.RootEntry().CreateFile("modules/mod/main.tf", "# changed")
+	git.CommitAll("change shared files")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: listStacks("chain-link-stack", "file-link-stack", "hidden-module-stack", "linked-module-stack", "module-link-stack"),
+	})
+	assertRunResult(t, cli.run("list", "--changed", "--why"), runExpected{
+		Stdout: listStacks(
+			"chain-link-stack - stack changed because symlink /chain-link-stack/common.tf -> /shared-link/common.tf -> /shared/common.tf changed",
+			"file-link-stack - stack changed because symlink /file-link-stack/common.tf -> /shared/common.tf changed",
+			`hidden-module-stack - stack changed because "../.modules/mod" changed because module "../.modules/mod" changed because symlink /.modules/mod/common.tf -> /shared/common.tf changed`,
+			`linked-module-stack - stack changed because "../modules/link" changed because module "../modules/link" (symlink /modules/link -> /modules/mod) has unmerged changes`,
+			"module-link-stack - stack changed because symlink /module-link-stack/mod -> /modules/mod changed",
+		),
+	})
+}
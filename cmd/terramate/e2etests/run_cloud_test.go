@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -541,3 +542,110 @@ type eventsResponse map[string][]string
 func (res eventsResponse) Validate() error {
 	return nil
 }
+
+func TestRunGitlabMetadata(t *testing.T) {
+	s := sandbox.New(t)
+	git := s.Git()
+	git.SetRemoteURL("origin", "https://gitlab.com/group/project")
+
+	s.BuildTree([]string{
+		"s:s1:id=s1",
+	})
+
+	git.CommitAll("all files")
+
+	gitlabAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "abcd" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.EscapedPath() != "/projects/42/repository/commits/"+git.RevParse("HEAD")+"/merge_requests" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[{
+			"iid": 7,
+			"title": "Add stacks",
+			"web_url": "https://gitlab.com/group/project/-/merge_requests/7",
+			"author": {"username": "batman"}
+		}]`))
+	}))
+	defer gitlabAPI.Close()
+
+	startFakeTMCServer(t)
+
+	gitlabEnv := []string{
+		"GITLAB_CI=true",
+		"CI_PROJECT_ID=42",
+		"CI_API_V4_URL=" + gitlabAPI.URL,
+		"CI_PIPELINE_URL=https://gitlab.com/group/project/-/pipelines/1",
+	}
+
+	t.Run("merge request from the API", func(t *testing.T) {
+		tm := newCLI(t, s.RootDir())
+		tm.loglevel = "debug"
+		tm.appendEnv = append(tm.appendEnv, gitlabEnv...)
+		tm.appendEnv = append(tm.appendEnv, "GITLAB_TOKEN=abcd")
+
+		result := tm.run("run",
+			"--disable-check-git-remote",
+			"--cloud-sync-deployment", "--", testHelperBin, "true")
+		assertRunResult(t, result, runExpected{
+			Status: 0,
+			StderrRegex: "(?s)detected CI/CD provider provider=\"GitLab CI\"" +
+				".*merge_request_url=https://gitlab.com/group/project/-/merge_requests/7" +
+				".*deployment_url=https://gitlab.com/group/project/-/pipelines/1",
+		})
+	})
+
+	t.Run("merge request pipeline variables without API access", func(t *testing.T) {
+		tm := newCLI(t, s.RootDir())
+		tm.loglevel = "debug"
+		tm.appendEnv = append(tm.appendEnv, gitlabEnv...)
+		tm.appendEnv = append(tm.appendEnv,
+			"CI_MERGE_REQUEST_IID=8",
+			"CI_MERGE_REQUEST_PROJECT_URL=https://gitlab.com/group/project",
+		)
+
+		result := tm.run("run",
+			"--disable-check-git-remote",
+			"--cloud-sync-deployment", "--", testHelperBin, "true")
+		assertRunResult(t, result, runExpected{
+			Status:      0,
+			StderrRegex: "merge_request_url=https://gitlab.com/group/project/-/merge_requests/8",
+		})
+	})
+}
+
+func TestRunBitbucketMetadata(t *testing.T) {
+	s := sandbox.New(t)
+	git := s.Git()
+	git.SetRemoteURL("origin", "https://bitbucket.org/workspace/repo")
+
+	s.BuildTree([]string{
+		"s:s1:id=s1",
+	})
+
+	git.CommitAll("all files")
+
+	startFakeTMCServer(t)
+
+	tm := newCLI(t, s.RootDir())
+	tm.loglevel = "debug"
+	tm.appendEnv = append(tm.appendEnv,
+		"BITBUCKET_BUILD_NUMBER=12",
+		"BITBUCKET_WORKSPACE=workspace",
+		"BITBUCKET_REPO_SLUG=repo",
+		"BITBUCKET_REPO_FULL_NAME=workspace/repo",
+		"BITBUCKET_PR_ID=3",
+	)
+
+	result := tm.run("run",
+		"--disable-check-git-remote",
+		"--cloud-sync-deployment", "--", testHelperBin, "true")
+	assertRunResult(t, result, runExpected{
+		Status: 0,
+		StderrRegex: "(?s)pull_request_url=https://bitbucket.org/workspace/repo/pull-requests/3" +
+			".*deployment_url=https://bitbucket.org/workspace/repo/pipelines/results/12",
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunParallelRespectsOrder(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b:after=["/stack-a"]`,
+		`s:stack-c:after=["/stack-b"]`,
+		`s:stack-d:before=["/stack-a"]`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run", "--parallel", "4", testHelperBin, "stack-abs-path", s.RootDir(),
+	), runExpected{
+		Stdout: listStacks("/stack-d", "/stack-a", "/stack-b", "/stack-c"),
+	})
+
+	assertRunResult(t, cli.run(
+		"run", "--reverse", "--parallel", "4", testHelperBin, "stack-abs-path", s.RootDir(),
+	), runExpected{
+		Stdout: listStacks("/stack-c", "/stack-b", "/stack-a", "/stack-d"),
+	})
+}
+
+func TestRunParallelExecutesIndependentStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:parent`,
+		`s:parent/child-1`,
+		`s:parent/child-2`,
+		`s:parent/child-3`,
+		`s:other`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLI(t, s.RootDir())
+	res := cli.run("run", "--parallel", "3", testHelperBin, "stack-abs-path", s.RootDir())
+	assertRunResult(t, res, runExpected{IgnoreStdout: true})
+
+	got := strings.Split(strings.TrimSpace(res.Stdout), "\n")
+	if len(got) != 5 {
+		t.Fatalf("expected 5 stacks executed, got %d: %v", len(got), got)
+	}
+
+	parentPos := -1
+	for i, stackdir := range got {
+		if stackdir == "/parent" {
+			parentPos = i
+		}
+	}
+	for i, stackdir := range got {
+		if strings.HasPrefix(stackdir, "/parent/") && i < parentPos {
+			t.Errorf("stack %s executed before its parent: %v", stackdir, got)
+		}
+	}
+
+	sort.Strings(got)
+	want := []string{"/other", "/parent", "/parent/child-1", "/parent/child-2", "/parent/child-3"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("want stacks %v but got %v", want, got)
+		}
+	}
+}
+
+func TestRunParallelStopsOnError(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b:after=["/stack-a"]`,
+		`f:stack-b/main.tf:# stack-b`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("run", "--parallel", "2", testHelperBin, "cat", "main.tf"), runExpected{
+		StderrRegex: "one or more commands failed",
+		Status:      1,
+	})
+
+	assertRunResult(t, cli.run("run", "--parallel", "2", "--continue-on-error", testHelperBin, "cat", "main.tf"), runExpected{
+		IgnoreStderr: true,
+		Stdout:       "# stack-b",
+		Status:       1,
+	})
+}
+
+func TestRunOutputModes(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+		"f:stack-a/file.txt:a1\na2\n",
+		`f:stack-b/file.txt:b1`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run", "--output-mode=prefix", testHelperBin, "cat", "file.txt",
+	), runExpected{
+		Stdout: "[/stack-a] a1\n[/stack-a] a2\n[/stack-b] b1\n",
+	})
+
+	assertRunResult(t, cli.run(
+		"run", "--output-mode=group", testHelperBin, "cat", "file.txt",
+	), runExpected{
+		Stdout: "a1\na2\nb1",
+	})
+
+	outdir := t.TempDir()
+	assertRunResult(t, cli.run(
+		"run", "--output-dir", outdir, testHelperBin, "cat", "file.txt",
+	), runExpected{
+		Stdout: "a1\na2\nb1",
+	})
+
+	assertFileContents := func(stack, want string) {
+		t.Helper()
+		got := string(test.ReadFile(t, filepath.Join(outdir, stack), "stdout.log"))
+		if got != want {
+			t.Errorf("stack %s: want output %q but got %q", stack, want, got)
+		}
+	}
+
+	assertFileContents("stack-a", "a1\na2\n")
+	assertFileContents("stack-b", "b1")
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"path/filepath"
+	"testing"
+
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunSummary(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b:after=["/stack-a"]`,
+		`f:stack-a/main.tf:# stack-a`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run", "--summary", testHelperBin, "true",
+	), runExpected{
+		StderrRegex: `(?s)STACK +STATUS +EXIT CODE +DURATION\n/stack-a +success +0 .*\n/stack-b +success +0 .*2 succeeded, 0 failed, 0 canceled, 0 skipped`,
+	})
+
+	assertRunResult(t, cli.run(
+		"run", "--summary", testHelperBin, "cat", "main.tf",
+	), runExpected{
+		Stdout:      "# stack-a",
+		StderrRegex: `(?s)/stack-a +success.*/stack-b +failed .*1 succeeded, 1 failed, 0 canceled, 0 skipped`,
+		Status:      1,
+	})
+}
+
+func TestRunReportFile(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b:after=["/stack-a"]`,
+		`s:stack-c:after=["/stack-b"]`,
+		`f:stack-a/main.tf:# stack-a`,
+		`f:stack-c/main.tf:# stack-c`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	reportDir := t.TempDir()
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run", "--report-file", filepath.Join(reportDir, "report.json"),
+		testHelperBin, "cat", "main.tf",
+	), runExpected{
+		Stdout:       "# stack-a",
+		IgnoreStderr: true,
+		Status:       1,
+	})
+
+	var report run.Report
+	data := test.ReadFile(t, reportDir, "report.json")
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("unmarshaling report: %v: %s", err, data)
+	}
+
+	want := []struct {
+		stack  string
+		status run.StackStatus
+	}{
+		{"/stack-a", run.StatusSuccess},
+		{"/stack-b", run.StatusFailed},
+		{"/stack-c", run.StatusSkipped},
+	}
+	if len(report.Results) != len(want) {
+		t.Fatalf("want %d stacks in report, got %d: %s", len(want), len(report.Results), data)
+	}
+	for i, w := range want {
+		got := report.Results[i]
+		if got.Stack != w.stack || got.Status != w.status {
+			t.Errorf("want stack %s with status %s, got %s with %s",
+				w.stack, w.status, got.Stack, got.Status)
+		}
+	}
+	if report.Results[0].ExitCode != 0 {
+		t.Errorf("want exit code 0 for successful stack, got %d", report.Results[0].ExitCode)
+	}
+	if report.Results[1].ExitCode <= 0 {
+		t.Errorf("want non-zero exit code for failed stack, got %d", report.Results[1].ExitCode)
+	}
+	if report.Results[2].ExitCode != -1 {
+		t.Errorf("want exit code -1 for skipped stack, got %d", report.Results[2].ExitCode)
+	}
+
+	assertRunResult(t, cli.run(
+		"run", "--continue-on-error", "--report-file", filepath.Join(reportDir, "report.xml"),
+		testHelperBin, "cat", "main.tf",
+	), runExpected{
+		IgnoreStdout: true,
+		IgnoreStderr: true,
+		Status:       1,
+	})
+
+	var junit struct {
+		Suites []struct {
+			Tests    int `xml:"tests,attr"`
+			Failures int `xml:"failures,attr"`
+			Skipped  int `xml:"skipped,attr"`
+			Cases    []struct {
+				Name    string    `xml:"name,attr"`
+				Failure *struct{} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	data = test.ReadFile(t, reportDir, "report.xml")
+	if err := xml.Unmarshal(data, &junit); err != nil {
+		t.Fatalf("unmarshaling junit report: %v: %s", err, data)
+	}
+	if len(junit.Suites) != 1 {
+		t.Fatalf("want 1 test suite, got %d: %s", len(junit.Suites), data)
+	}
+	suite := junit.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 0 {
+		t.Fatalf("unexpected junit suite counters: %s", data)
+	}
+	if suite.Cases[1].Name != "/stack-b" || suite.Cases[1].Failure == nil {
+		t.Fatalf("want /stack-b as failed test case: %s", data)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunResume(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b:after=["/stack-a"]`,
+		`s:stack-c:after=["/stack-b"]`,
+		`f:stack-a/file.txt:a`,
+		`f:stack-c/file.txt:c`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("run", testHelperBin, "cat", "file.txt"), runExpected{
+		Stdout:      "a",
+		StderrRegex: "one or more commands failed",
+		Status:      1,
+	})
+
+	// fixing stack-b doesn't change the commit, so the resumed run only
+	// executes the stacks not completed before.
+	s.RootEntry().CreateFile("stack-b/file.txt", "b")
+
+	assertRunResult(t, cli.run("run", "--resume", "--dry-run", "--format=json", testHelperBin, "cat", "file.txt"), runExpected{
+		StdoutRegex: `(?s)"path": "/stack-b".*"path": "/stack-c"`,
+	})
+	assertRunResult(t, cli.run("run", "--disable-check-git-untracked", "--resume", testHelperBin, "cat", "file.txt"), runExpected{
+		Stdout: "bc",
+	})
+
+	// a different command ignores the journal.
+	assertRunResult(t, cli.run("run", "--disable-check-git-untracked", "--resume", testHelperBin, "cat", "./file.txt"), runExpected{
+		Stdout: "abc",
+	})
+
+	// without --resume everything is executed.
+	assertRunResult(t, cli.run("run", "--disable-check-git-untracked", testHelperBin, "cat", "file.txt"), runExpected{
+		Stdout: "abc",
+	})
+
+	// a new commit invalidates the journal.
+	git.CommitAll("add stack-b file")
+	git.Push("main")
+	assertRunResult(t, cli.run("run", "--resume", testHelperBin, "cat", "file.txt"), runExpected{
+		Stdout: "abc",
+	})
+}
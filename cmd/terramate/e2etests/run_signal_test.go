@@ -18,6 +18,17 @@ import (
 func TestRunSendsSigkillIfCmdIgnoresInterruptionSignals(t *testing.T) {
 	t.Parallel()
 
+	testRunSendsSigkill(t, "run", testHelperBin, "hang")
+}
+
+func TestRunSendsSigkillToProcessGroupIfCmdWithTimeoutIgnoresInterruptionSignals(t *testing.T) {
+	t.Parallel()
+
+	// stacks with a timeout run on their own process group.
+	testRunSendsSigkill(t, "run", "--timeout", "1m", testHelperBin, "hang")
+}
+
+func testRunSendsSigkill(t *testing.T, args ...string) {
 	s := sandbox.New(t)
 	s.CreateStack("stack-1")
 
@@ -26,7 +37,7 @@ func TestRunSendsSigkillIfCmdIgnoresInterruptionSignals(t *testing.T) {
 	git.CommitAll("first commit")
 
 	tm := newCLIWithLogLevel(t, s.RootDir(), "trace")
-	cmd := tm.newCmd("run", testHelperBin, "hang")
+	cmd := tm.newCmd(args...)
 	// To simulate something similar to a terminal we run
 	// terramate in a separate pgid here and then send a signal to
 	// the whole group. The test process must not be part of this group.
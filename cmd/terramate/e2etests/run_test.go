@@ -2293,6 +2293,10 @@ func TestRunWitCustomizedEnv(t *testing.T) {
 	wantenv := append(hostenv,
 		"ACTIONS_ID_TOKEN_REQUEST_URL=",
 		"ACTIONS_ID_TOKEN_REQUEST_TOKEN=",
+		"TM_CLOUD_API_KEY=",
+		"TM_CLOUD_API_KEY_FILE=",
+		"TM_CLOUD_OIDC_TOKEN=",
+		"TM_CLOUD_OIDC_TOKEN_FILE=",
 		"CHECKPOINT_DISABLE=1", // e2e tests have telemetry disabled
 		fmt.Sprintf("FROM_META=%s", stackName),
 		fmt.Sprintf("FROM_GLOBAL=%s", stackGlobal),
@@ -2317,10 +2321,10 @@ func TestRunWitCustomizedEnv(t *testing.T) {
 	t.Run("ExperimentalRunEnv", func(t *testing.T) {
 		want := fmt.Sprintf(`
 stack "/stack":
-	FROM_ENV=%s
-	FROM_GLOBAL=%s
-	FROM_META=%s
-	TERRAMATE_OVERRIDDEN=%s
+	FROM_ENV=%s	# /env.tm:7,9-50
+	FROM_GLOBAL=%s	# /env.tm:6,9-42
+	FROM_META=%s	# /env.tm:5,9-52
+	TERRAMATE_OVERRIDDEN=%s	# /env.tm:8,9-42
 `, exportedTerramateTest, stackGlobal, stackName, newTerramateOverriden)
 
 		assertRunResult(t, tm.run("experimental", "run-env"), runExpected{
@@ -2328,6 +2332,143 @@ stack "/stack":
 	})
 }
 
+func TestRunWithHierarchicalEnv(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:accounts/dev/stack",
+		"s:accounts/prod/stack",
+		`f:terramate.tm:terramate {
+  config {
+    run {
+      env {
+        AWS_PROFILE = "default"
+        STACK       = terramate.stack.name
+      }
+    }
+  }
+}
+`,
+		`f:accounts/dev/env.tm:env {
+  AWS_PROFILE = "dev"
+}
+`,
+		`f:accounts/prod/env.tm:globals {
+  profile = "prod"
+}
+
+env {
+  AWS_PROFILE = global.profile
+}
+`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	tm := newCLI(t, s.RootDir())
+	assertRunResult(t, tm.run("run", "--quiet", testHelperBin, "env"), runExpected{
+		IgnoreStdout: true,
+	})
+
+	for _, tc := range []struct {
+		stack   string
+		profile string
+	}{
+		{stack: "accounts/dev/stack", profile: "dev"},
+		{stack: "accounts/prod/stack", profile: "prod"},
+	} {
+		res := newCLI(t, filepath.Join(s.RootDir(), tc.stack)).run("run", "--quiet", testHelperBin, "env")
+		assertRunResult(t, res, runExpected{IgnoreStdout: true})
+		gotenv := strings.Split(res.Stdout, "\n")
+		assertContains := func(want string) {
+			t.Helper()
+			for _, env := range gotenv {
+				if env == want {
+					return
+				}
+			}
+			t.Errorf("%s: env %q not found in:\n%s", tc.stack, want, res.Stdout)
+		}
+		assertContains("AWS_PROFILE=" + tc.profile)
+		assertContains("STACK=stack")
+	}
+
+	assertRunResult(t, tm.run("experimental", "run-env"), runExpected{
+		Stdout: `
+stack "/accounts/dev/stack":
+	AWS_PROFILE=dev	# /accounts/dev/env.tm:2,3-22
+	STACK=stack	# /terramate.tm:6,9-43
+
+stack "/accounts/prod/stack":
+	AWS_PROFILE=prod	# /accounts/prod/env.tm:6,3-31
+	STACK=stack	# /terramate.tm:6,9-43
+`,
+	})
+}
+
+func TestRunWithSensitiveEnv(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"f:secrets/token:s3cr3t\n",
+		`f:env.tm:env {
+  PLAIN = "plain"
+  TOKEN = {
+    from_file = "/secrets/token"
+    sensitive = true
+  }
+}
+`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	tm := newCLI(t, s.RootDir())
+
+	res := tm.run("run", "--quiet", testHelperBin, "env")
+	assertRunResult(t, res, runExpected{IgnoreStdout: true})
+	if !strings.Contains(res.Stdout, "\nTOKEN=s3cr3t\n") {
+		t.Errorf("sensitive value not exported to the command:\n%s", res.Stdout)
+	}
+
+	assertRunResult(t, tm.run("experimental", "run-env"), runExpected{
+		Stdout: `
+stack "/stack":
+	PLAIN=plain	# /env.tm:2,3-18
+	TOKEN=(sensitive value)	# /env.tm:3,3-6,4
+`,
+	})
+
+	assertRunResult(t, tm.run("run", "--dry-run", testHelperBin, "env"), runExpected{
+		Stdout: `The stacks will be executed using order below:
+	0. stack (stack)
+		PLAIN=plain
+		TOKEN=(sensitive value)
+`,
+	})
+
+	res = tm.run("run", "--dry-run", "--format=json", testHelperBin, "env")
+	assertRunResult(t, res, runExpected{IgnoreStdout: true})
+	if !strings.Contains(res.Stdout, `"TOKEN=(sensitive value)"`) {
+		t.Errorf("sensitive value not redacted from the dry run:\n%s", res.Stdout)
+	}
+
+	res = tm.run("run", "--log-level=info", testHelperBin, "false", "s3cr3t")
+	assertRunResult(t, res, runExpected{
+		Status:       1,
+		IgnoreStdout: true,
+		StderrRegex:  `false \(sensitive value\)`,
+	})
+	if strings.Contains(res.Stderr, "s3cr3t") {
+		t.Errorf("sensitive value not redacted from the output:\n%s", res.Stderr)
+	}
+}
+
 func listStacks(stacks ...string) string {
 	return strings.Join(stacks, "\n") + "\n"
 }
@@ -0,0 +1,129 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("run", "--timeout", "1s", testHelperBin, "sleep", "1m"), runExpected{
+		Stdout:      "ready\n",
+		StderrRegex: "execution timed out",
+		Status:      1,
+	})
+
+	// the timeout bounds the whole run, so the second stack is not started.
+	assertRunResult(t, cli.run(
+		"run", "--timeout", "1s", "--continue-on-error", testHelperBin, "sleep", "1m",
+	), runExpected{
+		Stdout:      "ready\n",
+		StderrRegex: "stack /stack-b not started: the run exceeded its timeout",
+		Status:      1,
+	})
+
+	assertRunResult(t, cli.run(
+		"run", "--timeout", "2s", "--continue-on-error", testHelperBin, "sleep", "1500ms",
+	), runExpected{
+		Stdout:      "ready\nready\n",
+		StderrRegex: "exceeded the timeout of the run",
+		Status:      1,
+	})
+}
+
+func TestRunTimeoutFromConfig(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+		`f:terramate.tm.hcl:terramate {
+		  config {
+		    run {
+		      timeout = "1s"
+		    }
+		  }
+		}`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("run", testHelperBin, "sleep", "1m"), runExpected{
+		Stdout:      "ready\n",
+		StderrRegex: "execution timed out",
+		Status:      1,
+	})
+
+	// the timeout of the run does not override the timeout of each stack.
+	assertRunResult(t, cli.run("run", "--timeout", "1m", testHelperBin, "sleep", "1m"), runExpected{
+		Stdout:      "ready\n",
+		StderrRegex: "exceeded the timeout of 1s",
+		Status:      1,
+	})
+
+	assertRunResult(t, cli.run("run", testHelperBin, "sleep", "10ms"), runExpected{
+		Stdout: "ready\n",
+	})
+}
+
+func TestRunRetry(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+		`f:terramate.tm.hcl:terramate {
+		  config {
+		    run {
+		      retry {
+		        attempts   = 2
+		        exit_codes = [1]
+		        backoff    = "10ms"
+		      }
+		    }
+		  }
+		}`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+
+	cli := newCLIWithLogLevel(t, s.RootDir(), "info")
+	res := cli.run("run", testHelperBin, "false")
+	assertRunResult(t, res, runExpected{
+		IgnoreStderr: true,
+		Status:       1,
+	})
+
+	if got := strings.Count(res.Stderr, "command failed, retrying"); got != 2 {
+		t.Fatalf("want 2 retries but got %d: %s", got, res.Stderr)
+	}
+
+	res = cli.run("run", testHelperBin, "true")
+	assertRunResult(t, res, runExpected{IgnoreStderr: true})
+
+	if strings.Contains(res.Stderr, "command failed, retrying") {
+		t.Fatalf("unexpected retry: %s", res.Stderr)
+	}
+}
@@ -73,6 +73,10 @@ func newCLI(t *testing.T, chdir string, env ...string) tmcli {
 		"TM_CLI_CONFIG_FILE="+cliConfigPath,
 		"ACTIONS_ID_TOKEN_REQUEST_URL=",
 		"ACTIONS_ID_TOKEN_REQUEST_TOKEN=",
+		"TM_CLOUD_API_KEY=",
+		"TM_CLOUD_API_KEY_FILE=",
+		"TM_CLOUD_OIDC_TOKEN=",
+		"TM_CLOUD_OIDC_TOKEN_FILE=",
 	)
 	tm.environ = env
 	return tm
@@ -231,6 +231,8 @@ func (root *Root) LoadSubTree(cfgdir project.Path) error {
 	} else {
 		node.Parent = parentNode
 		parentNode.Children[nextComponent] = node
+		// the runtime lists the stacks of the project.
+		root.initRuntime()
 	}
 	return nil
 }
@@ -224,6 +224,7 @@ export default defineConfig({
           { text: 'cloud login', link: 'cmdline/cloud-login' },
           { text: 'cloud info', link: 'cmdline/cloud-info' },
           { text: 'create', link: 'cmdline/create' },
+          { text: 'drift run', link: 'cmdline/drift-run' },
           { text: 'eval', link: 'cmdline/eval' },
           { text: 'fmt', link: 'cmdline/fmt' },
           { text: 'generate', link: 'cmdline/generate' },
@@ -0,0 +1,317 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// GlobalsCacheDir is the directory, relative to the project root, where the
+// evaluated globals of the stacks are cached. The directory is ignored by
+// Terramate and by git.
+const GlobalsCacheDir = ".terramate/cache/globals"
+
+// globalsCacheVersion must be changed whenever the cache key or the format of
+// the cache entries changes.
+const globalsCacheVersion = "1"
+
+// impureFuncCall matches calls to functions whose result depends on something
+// other than the configuration, like files, the home directory, the absolute
+// path of the project or the current time. Globals
+// defined in files calling them are never cached.
+var impureFuncCall = regexp.MustCompile(
+	`\btm_(abspath|pathexpand|file|fileexists|fileset|filebase64|filebase64sha256|` +
+		`filebase64sha512|filemd5|filesha1|filesha256|filesha512|templatefile|` +
+		`timestamp|plantimestamp|uuid|bcrypt)\s*\(`)
+
+// globalsCache caches the evaluated globals of each stack directory across
+// invocations. An entry is keyed on the hash of the files defining the
+// globals of the stack and of the metadata available to them, so it's only
+// used while none of them changes.
+type globalsCache struct {
+	rootdir  string
+	enabled  bool
+	readOnly bool
+
+	initOnce sync.Once
+	initErr  error
+}
+
+type globalsCacheEntry struct {
+	Dir     string      `json:"dir"`
+	Key     string      `json:"key"`
+	Globals cachedValue `json:"globals"`
+}
+
+// cachedValue is an evaluated global. Objects have no type and value.
+type cachedValue struct {
+	Dir       string                 `json:"dir"`
+	DefinedAt string                 `json:"defined_at"`
+	Keys      map[string]cachedValue `json:"keys,omitempty"`
+	Type      json.RawMessage        `json:"type,omitempty"`
+	Value     json.RawMessage        `json:"value,omitempty"`
+}
+
+// newGlobalsCache creates the globals cache of the project, which is enabled
+// by the terramate.config.generate.globals_cache attribute. A read-only cache
+// never writes new entries.
+func newGlobalsCache(root *config.Root, readOnly bool) *globalsCache {
+	cfg := root.Tree().Node.Terramate
+	return &globalsCache{
+		rootdir: root.HostDir(),
+		enabled: cfg != nil && cfg.Config != nil && cfg.Config.Generate != nil &&
+			cfg.Config.Generate.GlobalsCache,
+		readOnly: readOnly,
+	}
+}
+
+// forStack returns the globals of the stack, evaluating them only if they are
+// not cached. Globals with evaluation errors are never cached.
+func (c *globalsCache) forStack(root *config.Root, st *config.Stack) globals.EvalReport {
+	if !c.enabled {
+		return globals.ForStack(root, st)
+	}
+
+	logger := log.With().
+		Str("action", "generate.globalsCache.forStack()").
+		Stringer("stack", st.Dir).
+		Logger()
+
+	key, ok := c.key(root, st)
+	if !ok {
+		logger.Debug().Msg("globals are not cacheable")
+		return globals.ForStack(root, st)
+	}
+
+	if cached, ok := c.load(st.Dir, key); ok {
+		logger.Debug().Msg("using cached globals")
+		report := globals.NewEvalReport()
+		report.Globals = cached
+		return report
+	}
+
+	report := globals.ForStack(root, st)
+	if report.AsError() != nil || c.readOnly {
+		return report
+	}
+	if err := c.store(st.Dir, key, report.Globals); err != nil {
+		logger.Debug().Err(err).Msg("caching globals")
+	}
+	return report
+}
+
+// key computes the cache key of the stack globals. It returns false if the
+// globals can't be cached.
+func (c *globalsCache) key(root *config.Root, st *config.Stack) (string, bool) {
+	tree, ok := root.Lookup(st.Dir)
+	if !ok {
+		return "", false
+	}
+	exprs, err := globals.LoadExprs(tree)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	write := func(data []byte) {
+		// the length prefix avoids ambiguous concatenations.
+		_ = binary.Write(h, binary.BigEndian, uint64(len(data)))
+		_, _ = h.Write(data)
+	}
+
+	write([]byte(globalsCacheVersion))
+	write([]byte(st.Dir.String()))
+
+	runtime := root.Runtime()
+	runtime.Merge(st.RuntimeValues(root))
+	metadata := cty.ObjectVal(runtime)
+	if !metadata.IsWhollyKnown() {
+		return "", false
+	}
+	data, err := ctyjson.Marshal(metadata, metadata.Type())
+	if err != nil {
+		return "", false
+	}
+	write(data)
+
+	for _, file := range exprs.Files() {
+		content, err := os.ReadFile(file)
+		if err != nil || impureFuncCall.Match(content) {
+			return "", false
+		}
+		sum := sha256.Sum256(content)
+		write([]byte(file))
+		write(sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+func (c *globalsCache) load(dir project.Path, key string) (*eval.Object, bool) {
+	data, err := os.ReadFile(c.entryPath(dir))
+	if err != nil {
+		return nil, false
+	}
+	var entry globalsCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+	val, err := decodeCachedValue(entry.Globals)
+	if err != nil {
+		return nil, false
+	}
+	obj, ok := val.(*eval.Object)
+	return obj, ok
+}
+
+func (c *globalsCache) store(dir project.Path, key string, obj *eval.Object) error {
+	c.initOnce.Do(func() {
+		c.initErr = c.init()
+	})
+	if c.initErr != nil {
+		return c.initErr
+	}
+
+	val, err := encodeCachedValue(obj)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(globalsCacheEntry{
+		Dir:     dir.String(),
+		Key:     key,
+		Globals: val,
+	})
+	if err != nil {
+		return errors.E(err, "encoding cache entry")
+	}
+
+	path := c.entryPath(dir)
+	tmpfile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.E(err, "creating cache entry")
+	}
+	_, err = tmpfile.Write(data)
+	errs := errors.L(err, tmpfile.Close())
+	if err := errs.AsError(); err != nil {
+		_ = os.Remove(tmpfile.Name())
+		return errors.E(err, "writing cache entry")
+	}
+	if err := os.Rename(tmpfile.Name(), path); err != nil {
+		_ = os.Remove(tmpfile.Name())
+		return errors.E(err, "writing cache entry")
+	}
+	return nil
+}
+
+func (c *globalsCache) init() error {
+	dir := filepath.Join(c.rootdir, filepath.FromSlash(GlobalsCacheDir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.E(err, "creating globals cache dir")
+	}
+
+	// the cache is local state and must never be committed nor be detected
+	// as untracked files by the git safeguards.
+	gitignore := filepath.Join(filepath.Dir(dir), ".gitignore")
+	if _, err := os.Stat(gitignore); os.IsNotExist(err) {
+		if err := os.WriteFile(gitignore, []byte("*\n"), 0644); err != nil {
+			return errors.E(err, "creating globals cache dir")
+		}
+	}
+	return nil
+}
+
+// entryPath returns the path of the cache entry of the directory. There's a
+// single entry per directory, replaced whenever its key changes.
+func (c *globalsCache) entryPath(dir project.Path) string {
+	sum := sha256.Sum256([]byte(dir.String()))
+	return filepath.Join(c.rootdir, filepath.FromSlash(GlobalsCacheDir),
+		hex.EncodeToString(sum[:])+".json")
+}
+
+func encodeCachedValue(v eval.Value) (cachedValue, error) {
+	res := cachedValue{
+		Dir:       v.Info().Dir.String(),
+		DefinedAt: v.Info().DefinedAt.String(),
+	}
+	switch vv := v.(type) {
+	case *eval.Object:
+		res.Keys = map[string]cachedValue{}
+		for k, kv := range vv.Keys {
+			encoded, err := encodeCachedValue(kv)
+			if err != nil {
+				return cachedValue{}, err
+			}
+			res.Keys[k] = encoded
+		}
+		return res, nil
+	case eval.CtyValue:
+		val, _ := vv.Raw().UnmarkDeep()
+		if !val.IsWhollyKnown() {
+			return cachedValue{}, errors.E("unknown values can't be cached")
+		}
+		typ, err := ctyjson.MarshalType(val.Type())
+		if err != nil {
+			return cachedValue{}, errors.E(err, "encoding value type")
+		}
+		data, err := ctyjson.Marshal(val, val.Type())
+		if err != nil {
+			return cachedValue{}, errors.E(err, "encoding value")
+		}
+		res.Type = typ
+		res.Value = data
+		return res, nil
+	default:
+		return cachedValue{}, errors.E(errors.ErrInternal, "unexpected value type %T", v)
+	}
+}
+
+func decodeCachedValue(v cachedValue) (eval.Value, error) {
+	info := eval.Info{
+		Dir:       decodeCachedPath(v.Dir),
+		DefinedAt: decodeCachedPath(v.DefinedAt),
+	}
+	if v.Type == nil {
+		obj := eval.NewObject(info)
+		for k, kv := range v.Keys {
+			val, err := decodeCachedValue(kv)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(k, val)
+		}
+		return obj, nil
+	}
+
+	typ, err := ctyjson.UnmarshalType(v.Type)
+	if err != nil {
+		return nil, errors.E(err, "decoding value type")
+	}
+	val, err := ctyjson.Unmarshal(v.Value, typ)
+	if err != nil {
+		return nil, errors.E(err, "decoding value")
+	}
+	return eval.NewCtyValue(val, info), nil
+}
+
+func decodeCachedPath(p string) project.Path {
+	if p == "" {
+		return project.Path{}
+	}
+	return project.NewPath(p)
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateGlobalsCache(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/cached",
+		"s:stacks/impure",
+		`f:terramate.tm:terramate {
+  config {
+    generate {
+      parallel      = 2
+      globals_cache = true
+    }
+  }
+}
+
+generate_file "global.txt" {
+  content = global.value
+}
+`,
+		`f:stacks/cached/globals.tm:globals {
+  value = "a"
+}
+`,
+		"f:stacks/impure/data.txt:data",
+		`f:stacks/impure/globals.tm:globals {
+  value = tm_file("data.txt")
+}
+`,
+	})
+
+	report := s.Generate()
+	assert.IsTrue(t, !report.HasFailures(), "unexpected failures: %s", report.Full())
+
+	cachedir := filepath.Join(s.RootDir(), filepath.FromSlash(generate.GlobalsCacheDir))
+	entries, err := os.ReadDir(cachedir)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 1, len(entries), "only the globals without tm_file must be cached")
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(cachedir), ".gitignore"))
+	assert.NoError(t, err, "the cache dir must be ignored by git")
+
+	// the cached value is changed behind the cache back, so it's possible to
+	// tell when the cache is used.
+	entryPath := filepath.Join(cachedir, entries[0].Name())
+	var entry map[string]interface{}
+	data, err := os.ReadFile(entryPath)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &entry))
+	assert.EqualStrings(t, "/stacks/cached", entry["dir"].(string))
+	value := entry["globals"].(map[string]interface{})["keys"].(map[string]interface{})["value"].(map[string]interface{})
+	value["value"] = "cached"
+	data, err = json.Marshal(entry)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(entryPath, data, 0644))
+
+	generatedContent := func(stack string) string {
+		report := generate.DryRun(s.Config(), project.NewPath("/modules"), nil)
+		assert.IsTrue(t, !report.HasFailures(), "unexpected failures: %s", report.Full())
+		for _, change := range report.Changes {
+			if change.Path == project.NewPath(stack+"/global.txt") {
+				return change.New
+			}
+		}
+		return string(s.DirEntry(stack[1:]).ReadFile("global.txt"))
+	}
+
+	assert.EqualStrings(t, "cached", generatedContent("/stacks/cached"))
+	assert.EqualStrings(t, "data", generatedContent("/stacks/impure"))
+
+	s.DirEntry("stacks/cached").CreateFile("globals.tm", `globals {
+  value = "b"
+}
+`)
+	s.ReloadConfig()
+	assert.EqualStrings(t, "b", generatedContent("/stacks/cached"))
+
+	s.DirEntry("stacks/impure").CreateFile("data.txt", "changed")
+	assert.EqualStrings(t, "changed", generatedContent("/stacks/impure"))
+}
+
+func TestGenerateGlobalsCacheSkipsImpureFunctions(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name       string
+		expr       string
+		wantCached bool
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:       "pure function",
+			expr:       `tm_upper("a")`,
+			wantCached: true,
+		},
+		{
+			name: "tm_abspath",
+			expr: `tm_abspath(".")`,
+		},
+		{
+			name: "tm_pathexpand",
+			expr: `tm_pathexpand("~/file")`,
+		},
+		{
+			// tm_plantimestamp is not available in every Terraform version,
+			// so the call is done inside tm_try to not fail the evaluation.
+			name: "tm_plantimestamp",
+			expr: `tm_try(tm_plantimestamp(), "now")`,
+		},
+		{
+			name: "tm_timestamp",
+			expr: `tm_timestamp()`,
+		},
+		{
+			name: "tm_uuid",
+			expr: `tm_uuid()`,
+		},
+		{
+			name: "tm_bcrypt",
+			expr: `tm_bcrypt("secret")`,
+		},
+		{
+			name: "tm_fileexists",
+			expr: `tm_tostring(tm_fileexists("data.txt"))`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.NoGit(t)
+			s.BuildTree([]string{
+				"s:stack",
+				`f:terramate.tm:terramate {
+  config {
+    generate {
+      globals_cache = true
+    }
+  }
+}
+
+generate_file "global.txt" {
+  content = global.value
+}
+`,
+				"f:stack/globals.tm:globals {\n  value = " + tc.expr + "\n}\n",
+			})
+
+			report := s.Generate()
+			assert.IsTrue(t, !report.HasFailures(), "unexpected failures: %s", report.Full())
+
+			entries, err := os.ReadDir(filepath.Join(s.RootDir(), filepath.FromSlash(generate.GlobalsCacheDir)))
+			if err != nil && !os.IsNotExist(err) {
+				assert.NoError(t, err)
+			}
+			assert.IsTrue(t, (len(entries) == 1) == tc.wantCached,
+				"globals using %s cached: got %d entries", tc.expr, len(entries))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateDryRun(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:stack/gen.tm:generate_file "changed.txt" {
+  content = "a\nB\nc\n"
+}
+
+generate_file "created.txt" {
+  content = "new\n"
+}
+`,
+		"f:stack/changed.txt:a\nb\nc\n",
+	})
+
+	stackEntry := s.DirEntry("stack")
+	s.Generate()
+
+	// makes the generated code outdated. The orphan.txt file has no header
+	// so it's not detected as generated code.
+	stackEntry.CreateFile("changed.txt", "a\nb\nc\n")
+	stackEntry.RemoveFile("created.txt")
+	stackEntry.CreateFile("orphan.txt", "")
+	s.RootEntry().CreateFile("orphan.hcl", "// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n\na = 1\n")
+
+	report := generate.DryRun(s.Config(), project.NewPath("/modules"), nil)
+	assert.IsTrue(t, !report.HasFailures(), "unexpected failures: %s", report.Full())
+
+	type change struct {
+		Path string
+		Kind generate.FileChangeKind
+		Diff string
+	}
+	got := []change{}
+	for _, c := range report.Changes {
+		got = append(got, change{Path: c.Path.String(), Kind: c.Kind, Diff: c.Diff()})
+	}
+
+	want := []change{
+		{
+			Path: "/orphan.hcl",
+			Kind: generate.FileDeleted,
+			Diff: `--- a/orphan.hcl
++++ /dev/null
+@@ -1,3 +0,0 @@
+-// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT
+-
+-a = 1
+`,
+		},
+		{
+			Path: "/stack/changed.txt",
+			Kind: generate.FileChanged,
+			Diff: `--- a/stack/changed.txt
++++ b/stack/changed.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			Path: "/stack/created.txt",
+			Kind: generate.FileCreated,
+			Diff: `--- /dev/null
++++ b/stack/created.txt
+@@ -0,0 +1 @@
++new
+`,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected changes: -(want) +(got)\n%s", diff)
+	}
+
+	// nothing is written on dry runs.
+	assert.EqualStrings(t, "a\nb\nc\n", string(stackEntry.ReadFile("changed.txt")))
+	_, err := os.Stat(filepath.Join(stackEntry.Path(), "created.txt"))
+	assert.IsTrue(t, os.IsNotExist(err), "created.txt must not be generated on dry runs")
+	_, err = os.Stat(filepath.Join(s.RootDir(), "orphan.hcl"))
+	assert.NoError(t, err, "orphan.hcl must not be deleted on dry runs")
+
+	s.Generate()
+	report = generate.DryRun(s.Config(), project.NewPath("/modules"), nil)
+	assert.EqualInts(t, 0, len(report.Changes), "generated code must be up to date")
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateFiltered(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/a",
+		"s:stacks/b",
+		`f:gen.tm:generate_file "stack.txt" {
+  content = terramate.stack.name
+}
+
+generate_file "/root.txt" {
+  context = root
+  content = "root"
+}
+`,
+		"f:orphan.hcl:// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n",
+	})
+
+	exists := func(relpath string) bool {
+		_, err := os.Stat(filepath.Join(s.RootDir(), filepath.FromSlash(relpath)))
+		return err == nil
+	}
+
+	report := generate.DoFiltered(s.Config(), project.NewPath("/modules"), nil, &generate.Filter{
+		Stacks: project.Paths{project.NewPath("/stacks/a")},
+	})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stacks/a"),
+				Created: []string{"stack.txt"},
+			},
+		},
+	})
+	assert.IsTrue(t, !exists("stacks/b/stack.txt"), "stack not selected was generated")
+	assert.IsTrue(t, !exists("root.txt"), "root context generated without being requested")
+	assert.IsTrue(t, exists("orphan.hcl"), "orphaned file outside selected stacks was deleted")
+
+	report = generate.DoFiltered(s.Config(), project.NewPath("/modules"), nil, &generate.Filter{
+		RootContext: true,
+	})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/"),
+				Created: []string{"root.txt"},
+			},
+		},
+	})
+	assert.IsTrue(t, !exists("stacks/b/stack.txt"), "stack not selected was generated")
+	assert.IsTrue(t, exists("orphan.hcl"), "orphaned file outside selected stacks was deleted")
+}
@@ -9,8 +9,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -128,7 +130,7 @@ func Load(root *config.Root, vendorDir project.Path) ([]LoadResult, error) {
 				continue
 			}
 
-			file, err := genfile.Eval(block, evalctx)
+			file, err := genfile.Eval(root, block, evalctx)
 			if err != nil {
 				res.Err = errors.L(res.Err, err).AsError()
 				results = append(results, res)
@@ -145,6 +147,61 @@ func Load(root *config.Root, vendorDir project.Path) ([]LoadResult, error) {
 	return results, nil
 }
 
+// Validate loads the code generation configuration of the given stack, the
+// same way [Do] does, but without writing any files. It returns all the errors
+// found: failures evaluating globals and generate blocks, failed assertions
+// and conflicting or invalid generated file paths. Failed assertions and
+// conflicting files are reported at the range of the originating block.
+func Validate(root *config.Root, st *config.Stack, vendorDir project.Path) error {
+	report := globals.ForStack(root, st)
+	if err := report.AsError(); err != nil {
+		return err
+	}
+
+	asserts, err := loadAsserts(root, st, report.Globals)
+	if err != nil {
+		return err
+	}
+
+	errs := errors.L()
+	var generated []GenFile
+	genfiles, err := genfile.Load(root, st, report.Globals, vendorDir, nil)
+	errs.Append(err)
+	for _, f := range genfiles {
+		generated = append(generated, f)
+	}
+	genhcls, err := genhcl.Load(root, st, report.Globals, vendorDir, nil)
+	errs.Append(err)
+	for _, f := range genhcls {
+		generated = append(generated, f)
+	}
+
+	for _, gen := range generated {
+		asserts = append(asserts, gen.Asserts()...)
+	}
+	for _, assert := range asserts {
+		if !assert.Assertion && !assert.Warning {
+			errs.Append(errors.E(ErrAssertion, assert.Range, assert.Message))
+		}
+	}
+
+	errsmap := checkFileConflict(generated)
+	for _, file := range generated {
+		err, ok := errsmap[file.Label()]
+		if !ok || !file.Condition() {
+			continue
+		}
+		// the conflict is reported at each of the conflicting blocks.
+		var e *errors.Error
+		if errors.As(err, &e) {
+			errs.Append(errors.E(ErrConflictingConfig, file.Range(), e.Description))
+		}
+	}
+
+	errs.Append(validateStackGeneratedFiles(root, st.HostDir(root), generated))
+	return errs.AsError()
+}
+
 // Do will generate code for the entire configuration.
 //
 // There generation mechanism depend on the generate_* block context attribute:
@@ -177,14 +234,103 @@ func Do(
 	vendorDir project.Path,
 	vendorRequests chan<- event.VendorRequest,
 ) Report {
-	stackReport := forEachStack(root, vendorDir,
-		vendorRequests, doStackGeneration)
-	rootReport := doRootGeneration(root)
-	report := mergeReports(stackReport, rootReport)
-	return cleanupOrphaned(root, report)
+	return DoFiltered(root, vendorDir, vendorRequests, nil)
+}
+
+// Filter restricts the code generation to part of the project.
+type Filter struct {
+	// Stacks are the directories of the stacks whose code is generated.
+	Stacks project.Paths
+
+	// RootContext tells if the generate_file blocks with context=root are
+	// generated.
+	RootContext bool
+}
+
+// DoFiltered generates code like [Do] but only for the stacks selected by
+// the filter, and for the root context blocks only if the filter says so.
+// Generated files outside the selected stacks are never deleted, even if they
+// are orphaned. A nil filter selects the whole project.
+func DoFiltered(
+	root *config.Root,
+	vendorDir project.Path,
+	vendorRequests chan<- event.VendorRequest,
+	filter *Filter,
+) Report {
+	return do(root, vendorDir, vendorRequests, filter, &fileWriter{rootdir: root.HostDir()})
+}
+
+// DryRun computes the changes that [DoFiltered] would make to the project
+// without writing or deleting any files. The returned report has the files
+// that would be created, changed and deleted, and its Changes field has the
+// current and the generated content of each of them.
+//
+// The tm_vendor calls are not vendored on dry runs.
+func DryRun(root *config.Root, vendorDir project.Path, filter *Filter) Report {
+	w := &fileWriter{
+		rootdir: root.HostDir(),
+		dryRun:  true,
+	}
+	report := do(root, vendorDir, nil, filter, w)
+	report.Changes = w.sortedChanges()
+	return report
+}
+
+func do(
+	root *config.Root,
+	vendorDir project.Path,
+	vendorRequests chan<- event.VendorRequest,
+	filter *Filter,
+	w *fileWriter,
+) Report {
+	manifest, err := LoadManifest(root)
+	if err != nil {
+		return Report{BootstrapErr: err}
+	}
+	ms := newManifestState(manifest)
+
+	cache := newGlobalsCache(root, w.dryRun)
+	stackReport := forEachStack(root, vendorDir, vendorRequests, cache, filter,
+		func(
+			root *config.Root,
+			stack *config.Stack,
+			globals *eval.Object,
+			vendorDir project.Path,
+			vendorRequests chan<- event.VendorRequest,
+		) dirReport {
+			report := doStackGeneration(w, ms, root, stack, globals, vendorDir, vendorRequests)
+			if report.err == nil {
+				ms.finished(stack.Dir.String())
+			}
+			return report
+		})
+
+	report := stackReport
+	if filter == nil || filter.RootContext {
+		rootReport := doRootGeneration(w, ms, root)
+		if !rootReport.HasFailures() {
+			ms.finished(rootOwner)
+		}
+		report = mergeReports(stackReport, rootReport)
+		if filter == nil {
+			report = cleanupOrphaned(w, ms, root, report)
+		} else {
+			report.sort()
+		}
+	}
+
+	report.ManualEdits = ms.sortedManualEdits()
+	if !w.dryRun {
+		if err := ms.save(root, ms.result(root, filter == nil)); err != nil {
+			report.CleanupErr = errors.L(report.CleanupErr, err).AsError()
+		}
+	}
+	return report
 }
 
 func doStackGeneration(
+	w *fileWriter,
+	ms *manifestState,
 	root *config.Root,
 	stack *config.Stack,
 	globals *eval.Object,
@@ -223,7 +369,7 @@ func doStackGeneration(
 		return report
 	}
 
-	allFiles, err := allStackGeneratedFiles(root, stack.HostDir(root), generated)
+	allFiles, err := allStackGeneratedFiles(ms, root, stack, generated)
 	if err != nil {
 		report.err = errors.E(err, "listing all generated files")
 		return report
@@ -249,12 +395,18 @@ func doStackGeneration(
 		oldFileBody, oldExists := allFiles[filename]
 
 		if !oldExists || oldFileBody != body {
-			err := writeGeneratedCode(path, file)
+			prjpath := stack.Dir.Join(filename)
+			if oldExists && ms.edited(prjpath, oldFileBody) {
+				logger.Warn().Msg("overwriting manual changes to generated file")
+				ms.addManualEdit(prjpath)
+			}
+			err := w.write(path, file, oldFileBody, oldExists)
 			if err != nil {
 				report.err = errors.E(err, "saving file %q", filename)
 				return report
 			}
 		}
+		ms.generated(stack.Dir.Join(filename), stack.Dir, file)
 
 		if !oldExists {
 			log.Info().
@@ -285,7 +437,7 @@ func doStackGeneration(
 		report.addDeletedFile(filename)
 
 		path := filepath.Join(stackpath, filename)
-		err = os.Remove(path)
+		err = w.remove(path, allFiles[filename])
 		if err != nil {
 			report.err = errors.E("removing file %s", filename)
 			return report
@@ -298,7 +450,7 @@ func doStackGeneration(
 	return report
 }
 
-func doRootGeneration(root *config.Root) Report {
+func doRootGeneration(w *fileWriter, ms *manifestState, root *config.Root) Report {
 	logger := log.With().
 		Str("action", "generate.doRootGeneration").
 		Logger()
@@ -345,7 +497,7 @@ func doRootGeneration(root *config.Root) Report {
 
 			logger.Debug().Msg("block validated successfully")
 
-			file, err := genfile.Eval(block, evalctx)
+			file, err := genfile.Eval(root, block, evalctx)
 			if err != nil {
 				report.addFailure(targetDir, err)
 				return report
@@ -372,7 +524,7 @@ func doRootGeneration(root *config.Root) Report {
 
 	logger.Debug().Msg("no conflicts found")
 
-	generateRootFiles(root, files, &report)
+	generateRootFiles(w, ms, root, files, &report)
 	return report
 }
 
@@ -476,7 +628,7 @@ processSubdirs:
 				return nil, errors.E(err, "checking if file is generated %q", file)
 			}
 
-			if hasGenHCLHeader(string(data)) {
+			if hasGenHeader(string(data)) {
 				genfiles = append(genfiles, filepath.ToSlash(
 					filepath.Join(relSubdir, entry.Name())))
 			}
@@ -503,8 +655,25 @@ func DetectOutdated(root *config.Root, vendorDir project.Path) ([]string, error)
 
 	logger.Debug().Msg("checking outdated code inside stacks")
 
-	for _, stack := range stacks {
-		outdated, err := stackOutdated(root, stack.Stack, vendorDir)
+	type stackResult struct {
+		outdated []string
+		err      error
+	}
+
+	manifest, err := LoadManifest(root)
+	if err != nil {
+		return nil, err
+	}
+	ms := newManifestState(manifest)
+
+	cache := newGlobalsCache(root, false)
+	results := make([]stackResult, len(stacks))
+	parallelDo(codeGenParallel(root), len(stacks), func(i int) {
+		results[i].outdated, results[i].err = stackOutdated(root, cache, ms, stacks[i].Stack, vendorDir)
+	})
+
+	for i, stack := range stacks {
+		outdated, err := results[i].outdated, results[i].err
 		if err != nil {
 			errs.Append(err)
 			continue
@@ -539,6 +708,13 @@ func DetectOutdated(root *config.Root, vendorDir project.Path) ([]string, error)
 		return nil, err
 	}
 
+	// the root context is not checked, so only the files of stacks that
+	// don't exist anymore are orphans.
+	orphanedFiles, err = appendManifestOrphans(ms, root, orphanedFiles)
+	if err != nil {
+		return nil, err
+	}
+
 	outdatedFiles = append(outdatedFiles, orphanedFiles...)
 	sort.Strings(outdatedFiles)
 	return outdatedFiles, nil
@@ -549,6 +725,8 @@ func DetectOutdated(root *config.Root, vendorDir project.Path) ([]string, error)
 // If the stack has an invalid configuration it will return an error.
 func stackOutdated(
 	root *config.Root,
+	cache *globalsCache,
+	ms *manifestState,
 	st *config.Stack,
 	vendorDir project.Path,
 ) ([]string, error) {
@@ -557,7 +735,7 @@ func stackOutdated(
 		Stringer("stack", st).
 		Logger()
 
-	report := globals.ForStack(root, st)
+	report := cache.forStack(root, st)
 	if err := report.AsError(); err != nil {
 		return nil, errors.E(err, "checking for outdated code")
 	}
@@ -574,10 +752,14 @@ func stackOutdated(
 		return nil, err
 	}
 
-	genfilesOnFs, err := ListGenFiles(root, stackpath)
+	allFiles, err := allStackGeneratedFiles(ms, root, st, generated)
 	if err != nil {
 		return nil, errors.E(err, "checking for outdated code")
 	}
+	genfilesOnFs := make([]string, 0, len(allFiles))
+	for filename := range allFiles {
+		genfilesOnFs = append(genfilesOnFs, filename)
+	}
 
 	logger.Debug().Msgf("generated files detected on fs: %v", genfilesOnFs)
 
@@ -676,7 +858,7 @@ func writeGeneratedCode(target string, genfile GenFile) error {
 
 	if genfile.Header() != "" {
 		// WHY: some file generation strategies don't provide
-		// headers, like generate_file, so we can't detect
+		// headers, like generate_file without a header, so we can't detect
 		// if we are overwriting a Terramate generated file.
 		logger.Trace().Msg("checking file can be written")
 		if err := checkFileCanBeOverwritten(target); err != nil {
@@ -724,7 +906,7 @@ func readGeneratedFile(path string) (string, bool, error) {
 
 	logger.Trace().Msg("Check if file has terramate header.")
 
-	if hasGenHCLHeader(data) {
+	if hasGenHeader(data) {
 		return data, true, nil
 	}
 
@@ -775,6 +957,8 @@ func forEachStack(
 	root *config.Root,
 	vendorDir project.Path,
 	vendorRequests chan<- event.VendorRequest,
+	cache *globalsCache,
+	filter *Filter,
 	fn forEachStackFunc,
 ) Report {
 	logger := log.With().
@@ -792,33 +976,107 @@ func forEachStack(
 		return report
 	}
 
-	for _, elem := range stacks {
+	if filter != nil {
+		selected := map[project.Path]bool{}
+		for _, dir := range filter.Stacks {
+			selected[dir] = true
+		}
+		filtered := stacks[:0]
+		for _, elem := range stacks {
+			if selected[elem.Dir()] {
+				filtered = append(filtered, elem)
+			}
+		}
+		stacks = filtered
+	}
+
+	type stackResult struct {
+		globalsErr error
+		report     dirReport
+	}
+
+	results := make([]stackResult, len(stacks))
+	parallelDo(codeGenParallel(root), len(stacks), func(i int) {
+		elem := stacks[i]
 		logger := logger.With().
 			Stringer("stack", elem).
 			Logger()
 
 		logger.Trace().Msg("Load stack globals.")
 
-		globalsReport := globals.ForStack(root, elem.Stack)
+		globalsReport := cache.forStack(root, elem.Stack)
 		if err := globalsReport.AsError(); err != nil {
-			report.addFailure(elem.Dir(), errors.E(ErrLoadingGlobals, err))
-			continue
+			results[i].globalsErr = errors.E(ErrLoadingGlobals, err)
+			return
 		}
 
 		logger.Trace().Msg("Calling stack callback.")
 
-		stackReport := fn(root, elem.Stack, globalsReport.Globals, vendorDir, vendorRequests)
-		report.addDirReport(elem.Dir(), stackReport)
+		results[i].report = fn(root, elem.Stack, globalsReport.Globals, vendorDir, vendorRequests)
+	})
+
+	// the reports are added in the stacks order, so the report is the same
+	// no matter the order the stacks were generated.
+	for i, elem := range stacks {
+		if results[i].globalsErr != nil {
+			report.addFailure(elem.Dir(), results[i].globalsErr)
+			continue
+		}
+		report.addDirReport(elem.Dir(), results[i].report)
 	}
 
 	return report
 }
 
+// codeGenParallel returns the maximum number of stacks processed concurrently,
+// as defined by terramate.config.generate.parallel.
+func codeGenParallel(root *config.Root) int {
+	cfg := root.Tree().Node.Terramate
+	if cfg != nil && cfg.Config != nil && cfg.Config.Generate != nil &&
+		cfg.Config.Generate.Parallel > 0 {
+		return cfg.Config.Generate.Parallel
+	}
+	return runtime.NumCPU()
+}
+
+// parallelDo calls fn for each index in [0, n) using at most the given
+// number of goroutines, and waits for all calls to return.
+func parallelDo(workers, n int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
 func allStackGeneratedFiles(
+	ms *manifestState,
 	root *config.Root,
-	dir string,
+	st *config.Stack,
 	genfiles []GenFile,
 ) (map[string]string, error) {
+	dir := st.HostDir(root)
 	allFiles := map[string]string{}
 	files, err := ListGenFiles(root, dir)
 	if err != nil {
@@ -828,7 +1086,9 @@ func allStackGeneratedFiles(
 	// WHY: not all Terramate files have headers and can be detected
 	// so we use the list of files to be generated to check for these
 	// They may or not exist.
+	labels := map[string]bool{}
 	for _, genfile := range genfiles {
+		labels[genfile.Label()] = true
 		// Files that have header or that are inside the stack dir
 		// can be detected by ListGenFiles
 		if genfile.Header() == "" {
@@ -836,23 +1096,64 @@ func allStackGeneratedFiles(
 		}
 	}
 
-	for _, filename := range files {
-		path := filepath.Join(dir, filename)
-		body, err := os.ReadFile(path)
+	// Files without header generated before are only known by the manifest.
+	// They are only considered generated while unmodified, since they can't
+	// be told apart from manual files otherwise.
+	var manifestFiles []string
+	prefix := st.Dir.String()
+	if prefix != "/" {
+		prefix += "/"
+	}
+	for _, p := range ms.ownedBy(st.Dir.String()) {
+		filename := strings.TrimPrefix(p.String(), prefix)
+		if !labels[filename] {
+			manifestFiles = append(manifestFiles, filename)
+		}
+	}
+
+	readFile := func(filename string) (string, bool, error) {
+		body, err := os.ReadFile(filepath.Join(dir, filename))
 		if err != nil {
 			if os.IsNotExist(err) {
-				continue
+				return "", false, nil
 			}
-			return nil, errors.E(err, "reading generated file")
+			return "", false, errors.E(err, "reading generated file")
 		}
+		return string(body), true, nil
+	}
 
-		allFiles[filename] = string(body)
+	for _, filename := range files {
+		body, found, err := readFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			allFiles[filename] = body
+		}
+	}
+
+	for _, filename := range manifestFiles {
+		if _, ok := allFiles[filename]; ok {
+			continue
+		}
+		body, found, err := readFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		if ms.edited(st.Dir.Join(filename), body) {
+			ms.addManualEdit(st.Dir.Join(filename))
+			continue
+		}
+		allFiles[filename] = body
 	}
 
 	return allFiles, nil
 }
 
-func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
+func generateRootFiles(w *fileWriter, ms *manifestState, root *config.Root, genfiles []GenFile, report *Report) {
 	logger := log.With().
 		Str("action", "generate.generateRootFiles()").
 		Logger()
@@ -921,7 +1222,7 @@ func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
 			dirReport := dirReport{}
 			dir := path.Dir(label)
 
-			err := os.Remove(abspath)
+			err := w.remove(abspath, w.readOld(abspath))
 			if err != nil {
 				dirReport.err = errors.E(err, "deleting file")
 			} else {
@@ -952,7 +1253,12 @@ func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
 				Bool("fileChanged", body != diskContent).
 				Msg("writing file")
 
-			err := writeGeneratedCode(abspath, genfile)
+			if existOnDisk && ms.edited(project.NewPath(label), diskContent) {
+				logger.Warn().Msg("overwriting manual changes to generated file")
+				ms.addManualEdit(project.NewPath(label))
+			}
+
+			err := w.write(abspath, genfile, diskContent, existOnDisk)
 			if err != nil {
 				dirReport.err = errors.E(err, "saving file %s", label)
 				report.addDirReport(dir, dirReport)
@@ -961,6 +1267,7 @@ func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
 
 			logger.Debug().Msg("successfully written")
 		}
+		ms.generated(project.NewPath(label), project.NewPath("/"), genfile)
 
 		if !existOnDisk {
 			dirReport.addCreatedFile(filename)
@@ -989,7 +1296,7 @@ func genBlockLogger(logger zerolog.Logger, blockname, label, context string) zer
 		Logger()
 }
 
-func hasGenHCLHeader(code string) bool {
+func hasGenHeader(code string) bool {
 	// When changing headers we need to support old ones (or break).
 	// For now keeping them here, to avoid breaks.
 	for _, header := range []string{genhcl.Header, genhcl.HeaderV0} {
@@ -997,7 +1304,7 @@ func hasGenHCLHeader(code string) bool {
 			return true
 		}
 	}
-	return false
+	return genfile.HasHeader(code)
 }
 
 func validateStackGeneratedFiles(root *config.Root, stackpath string, generated []GenFile) error {
@@ -1276,7 +1583,36 @@ func loadStackCodeCfgs(
 	return genfilesConfigs, nil
 }
 
-func cleanupOrphaned(root *config.Root, report Report) Report {
+// appendManifestOrphans appends to the orphaned files found by their header
+// the unmodified files recorded in the manifest that aren't generated anymore
+// and are not owned by any stack.
+func appendManifestOrphans(ms *manifestState, root *config.Root, files []string) ([]string, error) {
+	known := map[string]bool{}
+	for _, f := range files {
+		known[f] = true
+	}
+	for _, p := range ms.orphaned(root) {
+		relpath := p.String()[1:]
+		if known[relpath] {
+			continue
+		}
+		content, found, err := readFile(filepath.Join(root.HostDir(), filepath.FromSlash(relpath)))
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		if ms.edited(p, content) {
+			ms.addManualEdit(p)
+			continue
+		}
+		files = append(files, relpath)
+	}
+	return files, nil
+}
+
+func cleanupOrphaned(w *fileWriter, ms *manifestState, root *config.Root, report Report) Report {
 	logger := log.With().
 		Str("action", "generate.cleanupOrphaned()").
 		Logger()
@@ -1290,7 +1626,22 @@ func cleanupOrphaned(root *config.Root, report Report) Report {
 
 	logger.Debug().Msg("listing orphaned generated files")
 
-	orphanedGenFiles, err := ListGenFiles(root, root.HostDir())
+	genfiles, err := ListGenFiles(root, root.HostDir())
+	if err != nil {
+		report.CleanupErr = err
+		return report
+	}
+
+	// the root context files generated in this run can have a header, so
+	// they are listed too but they are not orphans.
+	orphanedGenFiles := []string{}
+	for _, genfile := range genfiles {
+		if !ms.isGenerated(project.NewPath("/" + genfile)) {
+			orphanedGenFiles = append(orphanedGenFiles, genfile)
+		}
+	}
+
+	orphanedGenFiles, err = appendManifestOrphans(ms, root, orphanedGenFiles)
 	if err != nil {
 		report.CleanupErr = err
 		return report
@@ -1302,7 +1653,7 @@ func cleanupOrphaned(root *config.Root, report Report) Report {
 	for _, genfile := range orphanedGenFiles {
 		genfileAbspath := filepath.Join(root.HostDir(), genfile)
 		dir := project.NewPath("/" + filepath.ToSlash(filepath.Dir(genfile)))
-		if err := os.Remove(genfileAbspath); err != nil {
+		if err := w.remove(genfileAbspath, w.readOld(genfileAbspath)); err != nil {
 			if deleteFailures[dir] == nil {
 				deleteFailures[dir] = errors.L()
 			}
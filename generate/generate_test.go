@@ -6,6 +6,7 @@ package generate_test
 import (
 	"fmt"
 	"io/fs"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -757,6 +758,34 @@ func testCodeGeneration(t *testing.T, tcases []testcase) {
 				}
 			}
 
+			// the manifest must record exactly the wanted files, with the
+			// hash of their content.
+			assertManifest := func(t *testing.T) {
+				t.Helper()
+
+				manifest, err := generate.LoadManifest(s.Config())
+				assert.NoError(t, err)
+
+				want := 0
+				for _, wantDesc := range tcase.want {
+					stack := s.StackEntry(wantDesc.dir[1:])
+					for name := range wantDesc.files {
+						want++
+						file := project.NewPath(path.Join(wantDesc.dir, name))
+						entry, ok := manifest.Lookup(file)
+						if !ok {
+							t.Errorf("generated file %s not recorded in the manifest", file)
+							continue
+						}
+						if entry.Modified(string(stack.ReadFile(name))) {
+							t.Errorf("manifest hash of %s mismatches its content", file)
+						}
+					}
+				}
+				assert.EqualInts(t, want, len(manifest.Files),
+					"manifest files mismatch: %v", manifest.Files)
+			}
+
 			vendorDir := project.NewPath("/modules")
 			if tcase.vendorDir != "" {
 				vendorDir = project.NewPath(tcase.vendorDir)
@@ -765,6 +794,7 @@ func testCodeGeneration(t *testing.T, tcases []testcase) {
 			assertEqualReports(t, report, tcase.wantReport)
 
 			assertGeneratedFiles(t)
+			assertManifest(t)
 
 			// piggyback on the tests to validate that regeneration doesn't
 			// delete files or fail and has identical results.
@@ -776,6 +806,7 @@ func testCodeGeneration(t *testing.T, tcases []testcase) {
 					Failures: tcase.wantReport.Failures,
 				})
 				assertGeneratedFiles(t)
+				assertManifest(t)
 			})
 
 			// Check we don't have extraneous/unwanted files
@@ -830,6 +861,11 @@ func testCodeGeneration(t *testing.T, tcases []testcase) {
 					return nil
 				}
 
+				// the manifest contents are checked by assertManifest.
+				if path == filepath.Join(s.RootDir(), filepath.FromSlash(generate.ManifestFile)) {
+					return nil
+				}
+
 				// sandbox creates README.md inside test dirs
 				if d.Name() == config.DefaultFilename ||
 					d.Name() == stackpkg.DefaultFilename ||
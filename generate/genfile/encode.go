@@ -0,0 +1,527 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package genfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported formats of the generate_file.format attribute.
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
+
+// keyOrder is the order in which the keys of an object were defined. The
+// order of cty objects is lost after evaluation, so it's recovered from the
+// object constructors of the content expression.
+type keyOrder struct {
+	keys  []string
+	attrs map[string]*keyOrder
+	elems []*keyOrder
+}
+
+// exprKeyOrder returns the key order of the object constructors of expr. It
+// returns nil if the expression is not an object or tuple constructor, in
+// which case the keys are rendered sorted.
+func exprKeyOrder(expr hclsyntax.Expression, evalctx *eval.Context) *keyOrder {
+	switch e := expr.(type) {
+	case *hclsyntax.ParenthesesExpr:
+		return exprKeyOrder(e.Expression, evalctx)
+	case *hclsyntax.TupleConsExpr:
+		order := &keyOrder{}
+		for _, elem := range e.Exprs {
+			order.elems = append(order.elems, exprKeyOrder(elem, evalctx))
+		}
+		return order
+	case *hclsyntax.ObjectConsExpr:
+		order := &keyOrder{attrs: map[string]*keyOrder{}}
+		for _, item := range e.Items {
+			keyval, err := evalctx.Eval(item.KeyExpr)
+			if err != nil || keyval.IsNull() || !keyval.IsKnown() || keyval.Type() != cty.String {
+				return nil
+			}
+			key := keyval.AsString()
+			if _, ok := order.attrs[key]; !ok {
+				order.keys = append(order.keys, key)
+			}
+			order.attrs[key] = exprKeyOrder(item.ValueExpr, evalctx)
+		}
+		return order
+	default:
+		return nil
+	}
+}
+
+func (o *keyOrder) attr(key string) *keyOrder {
+	if o == nil {
+		return nil
+	}
+	return o.attrs[key]
+}
+
+func (o *keyOrder) elem(i int) *keyOrder {
+	if o == nil || i >= len(o.elems) {
+		return nil
+	}
+	return o.elems[i]
+}
+
+// orderedKeys returns the keys of the object or map value in definition
+// order. Keys without a known order are sorted and placed last.
+func (o *keyOrder) orderedKeys(val cty.Value) []string {
+	present := map[string]bool{}
+	var rest []string
+	for it := val.ElementIterator(); it.Next(); {
+		k, _ := it.Element()
+		present[k.AsString()] = true
+		rest = append(rest, k.AsString())
+	}
+
+	var keys []string
+	if o != nil {
+		seen := map[string]bool{}
+		for _, k := range o.keys {
+			if present[k] {
+				keys = append(keys, k)
+				seen[k] = true
+			}
+		}
+		var unordered []string
+		for _, k := range rest {
+			if !seen[k] {
+				unordered = append(unordered, k)
+			}
+		}
+		rest = unordered
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// encode renders the value in the given format.
+func encode(format string, val cty.Value, order *keyOrder, indent int) (string, error) {
+	val, _ = val.UnmarkDeep()
+	if !val.IsWhollyKnown() {
+		return "", errors.E(ErrContentEncode, "content has unknown values")
+	}
+	switch format {
+	case FormatYAML:
+		return encodeYAML(val, order, indent)
+	case FormatTOML:
+		return encodeTOML(val, order, indent)
+	case FormatJSON:
+		return encodeJSON(val, order, indent)
+	default:
+		return "", errors.E(errors.ErrInternal, "unsupported format %q", format)
+	}
+}
+
+func encodeYAML(val cty.Value, order *keyOrder, indent int) (string, error) {
+	if indent < 2 || indent > 9 {
+		return "", errors.E(ErrInvalidIndent, "yaml indent must be between 2 and 9 but given %d", indent)
+	}
+	node, err := yamlNode(val, order)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(node); err != nil {
+		return "", errors.E(ErrContentEncode, err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", errors.E(ErrContentEncode, err)
+	}
+	return buf.String(), nil
+}
+
+func yamlNode(val cty.Value, order *keyOrder) (*yaml.Node, error) {
+	typ := val.Type()
+	switch {
+	case val.IsNull():
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	case typ == cty.String:
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: val.AsString()}
+		if yaml11Bool.MatchString(node.Value) {
+			// YAML 1.1 parsers, still common in the Kubernetes ecosystem,
+			// read these as booleans.
+			node.Style = yaml.DoubleQuotedStyle
+		}
+		return node, nil
+	case typ == cty.Bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(val.True())}, nil
+	case typ == cty.Number:
+		num, tag, err := formatNumber(val.AsBigFloat())
+		if err != nil {
+			return nil, err
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: num}, nil
+	case typ.IsObjectType() || typ.IsMapType():
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range order.orderedKeys(val) {
+			elem, err := yamlNode(keyValue(val, key), order.attr(key))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, elem)
+		}
+		return node, nil
+	case typ.IsTupleType() || typ.IsListType() || typ.IsSetType():
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		i := 0
+		for it := val.ElementIterator(); it.Next(); i++ {
+			_, v := it.Element()
+			elem, err := yamlNode(v, order.elem(i))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, elem)
+		}
+		return node, nil
+	default:
+		return nil, errors.E(ErrContentEncode, "type %s can't be encoded", typ.FriendlyName())
+	}
+}
+
+func encodeJSON(val cty.Value, order *keyOrder, indent int) (string, error) {
+	if indent < 0 {
+		return "", errors.E(ErrInvalidIndent, "json indent must not be negative but given %d", indent)
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, val, order, indent, 0); err != nil {
+		return "", err
+	}
+	buf.WriteString("\n")
+	return buf.String(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, val cty.Value, order *keyOrder, indent, depth int) error {
+	newline := func(depth int) {
+		if indent > 0 {
+			buf.WriteString("\n")
+			buf.WriteString(strings.Repeat(" ", indent*depth))
+		}
+	}
+
+	typ := val.Type()
+	switch {
+	case val.IsNull():
+		buf.WriteString("null")
+	case typ == cty.String:
+		buf.WriteString(jsonString(val.AsString()))
+	case typ == cty.Bool:
+		buf.WriteString(strconv.FormatBool(val.True()))
+	case typ == cty.Number:
+		num, _, err := formatNumber(val.AsBigFloat())
+		if err != nil {
+			return err
+		}
+		buf.WriteString(num)
+	case typ.IsObjectType() || typ.IsMapType():
+		keys := order.orderedKeys(val)
+		if len(keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		sep := ":"
+		if indent > 0 {
+			sep = ": "
+		}
+		buf.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			newline(depth + 1)
+			buf.WriteString(jsonString(key))
+			buf.WriteString(sep)
+			err := writeJSON(buf, keyValue(val, key), order.attr(key), indent, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		buf.WriteString("}")
+	case typ.IsTupleType() || typ.IsListType() || typ.IsSetType():
+		if val.LengthInt() == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[")
+		i := 0
+		for it := val.ElementIterator(); it.Next(); i++ {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			newline(depth + 1)
+			_, v := it.Element()
+			if err := writeJSON(buf, v, order.elem(i), indent, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		buf.WriteString("]")
+	default:
+		return errors.E(ErrContentEncode, "type %s can't be encoded", typ.FriendlyName())
+	}
+	return nil
+}
+
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// encoding a string never fails.
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// yaml11Bool matches the strings that YAML 1.1 resolves as booleans.
+var yaml11Bool = regexp.MustCompile(`^(y|Y|yes|Yes|YES|n|N|no|No|NO|on|On|ON|off|Off|OFF)$`)
+
+// tomlBareKey matches the keys that don't need to be quoted in TOML.
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func encodeTOML(val cty.Value, order *keyOrder, indent int) (string, error) {
+	if indent < 0 {
+		return "", errors.E(ErrInvalidIndent, "toml indent must not be negative but given %d", indent)
+	}
+	if val.IsNull() || !(val.Type().IsObjectType() || val.Type().IsMapType()) {
+		return "", errors.E(ErrContentEncode,
+			"toml content must be an object but has type %s", val.Type().FriendlyName())
+	}
+	enc := tomlEncoder{indent: indent}
+	if err := enc.table(nil, val, order, false); err != nil {
+		return "", err
+	}
+	return enc.buf.String(), nil
+}
+
+type tomlEncoder struct {
+	buf    bytes.Buffer
+	indent int
+}
+
+// table writes the table at path. Key/value pairs are written first, since
+// everything after a table header belongs to that table, then the sub-tables
+// and arrays of tables in definition order.
+func (e *tomlEncoder) table(path []string, val cty.Value, order *keyOrder, arrayElem bool) error {
+	keys := order.orderedKeys(val)
+
+	var values, tables []string
+	for _, key := range keys {
+		v := keyValue(val, key)
+		if v.IsNull() {
+			return errors.E(ErrContentEncode, "%s: toml has no null value",
+				tomlKeyPath(append(path, key)))
+		}
+		if isTOMLTable(v) || isTOMLArrayOfTables(v) {
+			tables = append(tables, key)
+		} else {
+			values = append(values, key)
+		}
+	}
+
+	// headers are indented by their nesting level and the key/value pairs
+	// one level deeper than their header.
+	prefix := strings.Repeat(" ", e.indent*len(path))
+	if len(path) > 0 {
+		if arrayElem || len(values) > 0 || len(tables) == 0 {
+			e.separate()
+			e.buf.WriteString(strings.Repeat(" ", e.indent*(len(path)-1)))
+			if arrayElem {
+				e.buf.WriteString("[[" + tomlKeyPath(path) + "]]\n")
+			} else {
+				e.buf.WriteString("[" + tomlKeyPath(path) + "]\n")
+			}
+		}
+	}
+
+	for _, key := range values {
+		e.buf.WriteString(prefix)
+		e.buf.WriteString(tomlKey(key))
+		e.buf.WriteString(" = ")
+		if err := e.inline(keyValue(val, key), order.attr(key)); err != nil {
+			return err
+		}
+		e.buf.WriteString("\n")
+	}
+
+	for _, key := range tables {
+		v := keyValue(val, key)
+		subpath := append(append([]string{}, path...), key)
+		if isTOMLTable(v) {
+			if err := e.table(subpath, v, order.attr(key), false); err != nil {
+				return err
+			}
+			continue
+		}
+		i := 0
+		for it := v.ElementIterator(); it.Next(); i++ {
+			_, elem := it.Element()
+			if err := e.table(subpath, elem, order.attr(key).elem(i), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// separate adds a blank line between the previous content and a new table.
+func (e *tomlEncoder) separate() {
+	if e.buf.Len() > 0 {
+		e.buf.WriteString("\n")
+	}
+}
+
+// inline writes the value as an inline TOML value.
+func (e *tomlEncoder) inline(val cty.Value, order *keyOrder) error {
+	typ := val.Type()
+	switch {
+	case val.IsNull():
+		return errors.E(ErrContentEncode, "toml has no null value")
+	case typ == cty.String:
+		e.buf.WriteString(tomlString(val.AsString()))
+	case typ == cty.Bool:
+		e.buf.WriteString(strconv.FormatBool(val.True()))
+	case typ == cty.Number:
+		num, _, err := formatNumber(val.AsBigFloat())
+		if err != nil {
+			return err
+		}
+		e.buf.WriteString(num)
+	case typ.IsObjectType() || typ.IsMapType():
+		e.buf.WriteString("{")
+		for i, key := range order.orderedKeys(val) {
+			if i > 0 {
+				e.buf.WriteString(",")
+			}
+			e.buf.WriteString(" " + tomlKey(key) + " = ")
+			if err := e.inline(keyValue(val, key), order.attr(key)); err != nil {
+				return err
+			}
+		}
+		if val.LengthInt() > 0 {
+			e.buf.WriteString(" ")
+		}
+		e.buf.WriteString("}")
+	case typ.IsTupleType() || typ.IsListType() || typ.IsSetType():
+		e.buf.WriteString("[")
+		i := 0
+		for it := val.ElementIterator(); it.Next(); i++ {
+			if i > 0 {
+				e.buf.WriteString(", ")
+			}
+			_, v := it.Element()
+			if err := e.inline(v, order.elem(i)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteString("]")
+	default:
+		return errors.E(ErrContentEncode, "type %s can't be encoded", typ.FriendlyName())
+	}
+	return nil
+}
+
+func isTOMLTable(val cty.Value) bool {
+	return !val.IsNull() && (val.Type().IsObjectType() || val.Type().IsMapType())
+}
+
+func isTOMLArrayOfTables(val cty.Value) bool {
+	typ := val.Type()
+	if val.IsNull() || !(typ.IsTupleType() || typ.IsListType()) || val.LengthInt() == 0 {
+		return false
+	}
+	for it := val.ElementIterator(); it.Next(); {
+		_, elem := it.Element()
+		if !isTOMLTable(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlKeyPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+func tomlKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteString(`"`)
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				b.WriteString(`\u` + strings.ToUpper(strconv.FormatInt(int64(r)+0x10000, 16)[1:]))
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(`"`)
+	return b.String()
+}
+
+// formatNumber formats the number the same way for all formats, returning
+// also its YAML tag.
+func formatNumber(num *big.Float) (string, string, error) {
+	if num.IsInf() {
+		return "", "", errors.E(ErrContentEncode, "infinite numbers can't be encoded")
+	}
+	if num.IsInt() {
+		return num.Text('f', 0), "!!int", nil
+	}
+	f, _ := num.Float64()
+	return strconv.FormatFloat(f, 'f', -1, 64), "!!float", nil
+}
+
+// keyValue returns the value of the key of an object or map value.
+func keyValue(val cty.Value, key string) cty.Value {
+	if val.Type().IsObjectType() {
+		return val.GetAttr(key)
+	}
+	return val.Index(cty.StringVal(key))
+}
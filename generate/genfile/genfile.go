@@ -6,9 +6,14 @@ package genfile
 
 import (
 	"fmt"
+	"os"
 	"path"
+	"path/filepath"
 	"sort"
+	"strings"
 
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/event"
@@ -21,6 +26,7 @@ import (
 	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/stack"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 )
 
 const (
@@ -41,6 +47,28 @@ const (
 	// ErrLabelConflict indicates the two generate_file blocks
 	// have the same label.
 	ErrLabelConflict errors.Kind = "label conflict detected"
+
+	// ErrTemplateEval indicates an error when loading or evaluating the
+	// template file.
+	ErrTemplateEval errors.Kind = "evaluating template"
+
+	// ErrInvalidTemplateType indicates the template attribute or the
+	// template result has an invalid type.
+	ErrInvalidTemplateType errors.Kind = "invalid template type"
+
+	// ErrTemplateOutsideRoot indicates the template path is outside the
+	// project root.
+	ErrTemplateOutsideRoot errors.Kind = "template outside project root"
+
+	// ErrInvalidVarsType indicates the vars attribute has an invalid type.
+	ErrInvalidVarsType errors.Kind = "invalid vars type"
+
+	// ErrInvalidIndent indicates the indent attribute has an invalid value.
+	ErrInvalidIndent errors.Kind = "invalid indent"
+
+	// ErrContentEncode indicates the content can't be encoded in the
+	// configured format.
+	ErrContentEncode errors.Kind = "encoding content"
 )
 
 const (
@@ -56,6 +84,7 @@ type File struct {
 	label     string
 	context   string
 	origin    info.Range
+	header    string
 	body      string
 	condition bool
 	asserts   []config.Assert
@@ -94,10 +123,11 @@ func (f File) Asserts() []config.Assert {
 	return f.asserts
 }
 
-// Header returns the header of this file.
+// Header returns the header of this file, which is empty unless the
+// generate_file block defines the header attribute. If the content starts
+// with a shebang line, it's part of the header, since it must come first.
 func (f File) Header() string {
-	// For now we don't support headers for arbitrary files
-	return ""
+	return f.header
 }
 
 func (f File) String() string {
@@ -144,7 +174,7 @@ func Load(
 
 		evalctx.SetFunction(stdlib.Name("vendor"), stdlib.VendorFunc(vendorTargetDir, vendorDir, vendorRequests))
 
-		file, err := Eval(genFileBlock, evalctx.Context)
+		file, err := Eval(root, genFileBlock, evalctx.Context)
 		if err != nil {
 			return nil, err
 		}
@@ -159,7 +189,7 @@ func Load(
 }
 
 // Eval the generate_file block.
-func Eval(block hcl.GenFileBlock, evalctx *eval.Context) (File, error) {
+func Eval(root *config.Root, block hcl.GenFileBlock, evalctx *eval.Context) (File, error) {
 	name := block.Label
 	err := lets.Load(block.Lets, evalctx)
 	if err != nil {
@@ -221,29 +251,204 @@ func Eval(block hcl.GenFileBlock, evalctx *eval.Context) (File, error) {
 		}, nil
 	}
 
-	value, err := evalctx.Eval(block.Content.Expr)
+	var body string
+	if block.Template != nil {
+		body, err = evalTemplate(root, block, evalctx)
+	} else {
+		body, err = evalContent(block, evalctx)
+	}
 	if err != nil {
-		return File{}, errors.E(ErrContentEval, err)
+		return File{}, err
 	}
 
-	if value.Type() != cty.String {
-		return File{}, errors.E(
-			ErrInvalidContentType,
-			"content has type %s but must be string",
-			value.Type().FriendlyName(),
-		)
+	var header string
+	if block.Header != nil {
+		header, body, err = evalHeader(block, evalctx, body)
+		if err != nil {
+			return File{}, err
+		}
 	}
 
 	return File{
 		label:     name,
 		origin:    block.Range,
-		body:      value.AsString(),
+		header:    header,
+		body:      body,
 		condition: condition,
 		context:   block.Context,
 		asserts:   asserts,
 	}, nil
 }
 
+func evalContent(block hcl.GenFileBlock, evalctx *eval.Context) (string, error) {
+	value, err := evalctx.Eval(block.Content.Expr)
+	if err != nil {
+		return "", errors.E(ErrContentEval, err)
+	}
+
+	if block.Format == "" {
+		if value.Type() != cty.String {
+			return "", errors.E(
+				ErrInvalidContentType,
+				"content has type %s but must be string",
+				value.Type().FriendlyName(),
+			)
+		}
+		return value.AsString(), nil
+	}
+
+	indent := 2
+	if block.Format == FormatTOML {
+		indent = 0
+	}
+	if block.Indent != nil {
+		indentVal, err := evalctx.Eval(block.Indent.Expr)
+		if err != nil {
+			return "", errors.E(ErrInvalidIndent, err)
+		}
+		if indentVal.Type() != cty.Number || indentVal.IsNull() || !indentVal.AsBigFloat().IsInt() {
+			return "", errors.E(ErrInvalidIndent,
+				"indent has type %s but must be an integer",
+				indentVal.Type().FriendlyName(),
+			)
+		}
+		i64, _ := indentVal.AsBigFloat().Int64()
+		indent = int(i64)
+	}
+
+	order := exprKeyOrder(block.Content.Expr, evalctx)
+	body, err := encode(block.Format, value, order, indent)
+	if err != nil {
+		return "", errors.E(block.Content.Expr.Range(), err)
+	}
+	return body, nil
+}
+
+// evalTemplate renders the template file of the block. The template path is
+// relative to the directory of the file defining the block, or to the project
+// root if it's absolute, and it must be inside the project. Besides the
+// namespaces available to the block, the template can reference the keys of
+// the vars object as variables.
+func evalTemplate(root *config.Root, block hcl.GenFileBlock, evalctx *eval.Context) (string, error) {
+	pathVal, err := evalctx.Eval(block.Template.Expr)
+	if err != nil {
+		return "", errors.E(ErrTemplateEval, err)
+	}
+	if pathVal.Type() != cty.String || pathVal.IsNull() {
+		return "", errors.E(
+			ErrInvalidTemplateType,
+			"template has type %s but must be string",
+			pathVal.Type().FriendlyName(),
+		)
+	}
+
+	tmplPath := pathVal.AsString()
+	rootdir := root.HostDir()
+	var abspath string
+	if path.IsAbs(tmplPath) {
+		abspath = filepath.Join(rootdir, filepath.FromSlash(tmplPath))
+	} else {
+		cfgdir := filepath.Dir(block.Range.HostPath())
+		abspath = filepath.Join(cfgdir, filepath.FromSlash(tmplPath))
+	}
+
+	rel, err := filepath.Rel(rootdir, abspath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.E(ErrTemplateOutsideRoot, block.Template.Expr.Range(),
+			"template %s is outside the project root", tmplPath)
+	}
+
+	src, err := os.ReadFile(abspath)
+	if err != nil {
+		return "", errors.E(ErrTemplateEval, block.Template.Expr.Range(), err,
+			"reading template %s", tmplPath)
+	}
+
+	tmplctx := evalctx.Copy()
+	if block.Vars != nil {
+		vars, err := evalctx.Eval(block.Vars.Expr)
+		if err != nil {
+			return "", errors.E(ErrTemplateEval, err)
+		}
+		if vars.IsNull() || !(vars.Type().IsObjectType() || vars.Type().IsMapType()) {
+			return "", errors.E(
+				ErrInvalidVarsType,
+				block.Vars.Expr.Range(),
+				"vars has type %s but must be an object",
+				vars.Type().FriendlyName(),
+			)
+		}
+		variables := tmplctx.Unwrap().Variables
+		for it := vars.ElementIterator(); it.Next(); {
+			k, v := it.Element()
+			name := k.AsString()
+			if _, ok := variables[name]; ok {
+				return "", errors.E(
+					ErrInvalidVarsType,
+					block.Vars.Expr.Range(),
+					"vars.%s conflicts with the %s namespace", name, name,
+				)
+			}
+			variables[name] = v
+		}
+	}
+
+	expr, diags := hclsyntax.ParseTemplate(src, abspath, hhcl.InitialPos)
+	if diags.HasErrors() {
+		return "", errors.E(ErrTemplateEval, diags)
+	}
+	value, err := tmplctx.Eval(expr)
+	if err != nil {
+		return "", errors.E(ErrTemplateEval, err)
+	}
+	value, err = convert.Convert(value, cty.String)
+	if err != nil || value.IsNull() {
+		return "", errors.E(
+			ErrInvalidTemplateType,
+			block.Template.Expr.Range(),
+			"template result has type %s but must be string",
+			value.Type().FriendlyName(),
+		)
+	}
+	return value.AsString(), nil
+}
+
+// evalHeader evaluates the header attribute, which can be a boolean or the
+// custom text written after the header marker. It returns the header and the
+// body without its shebang line, if any.
+func evalHeader(block hcl.GenFileBlock, evalctx *eval.Context, body string) (string, string, error) {
+	value, err := evalctx.Eval(block.Header.Expr)
+	if err != nil {
+		return "", "", errors.E(ErrInvalidHeader, err)
+	}
+
+	var text string
+	switch {
+	case value.IsNull():
+		return "", body, nil
+	case value.Type() == cty.Bool:
+		if value.False() {
+			return "", body, nil
+		}
+	case value.Type() == cty.String:
+		text = value.AsString()
+	default:
+		return "", "", errors.E(
+			ErrInvalidHeader,
+			block.Header.Expr.Range(),
+			"header has type %s but must be boolean or string",
+			value.Type().FriendlyName(),
+		)
+	}
+
+	header, err := renderHeader(block.Label, text)
+	if err != nil {
+		return "", "", errors.E(block.Header.Expr.Range(), err)
+	}
+	shebang, body := splitShebang(body)
+	return shebang + header, body, nil
+}
+
 // loadGenFileBlocks will load all generate_file blocks.
 // The returned map maps the name of the block (its label)
 // to the original block and the path (relative to project root) of the config
@@ -0,0 +1,150 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package genfile
+
+import (
+	"path"
+	"strings"
+
+	"github.com/terramate-io/terramate/errors"
+)
+
+// ErrInvalidHeader indicates the header attribute has an invalid value or
+// the file has no comment style to write it.
+const ErrInvalidHeader errors.Kind = "invalid header"
+
+// HeaderMarker is the text of the first header line of generated files. The
+// header of a file can be written in any of the supported comment styles.
+const HeaderMarker = "TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT"
+
+// commentStyle is how a single line comment is written in a file type.
+type commentStyle struct {
+	start, end string
+}
+
+var (
+	hashComment  = commentStyle{start: "# "}
+	slashComment = commentStyle{start: "// "}
+	xmlComment   = commentStyle{start: "<!-- ", end: " -->"}
+)
+
+var commentStyles = []commentStyle{hashComment, slashComment, xmlComment}
+
+// extCommentStyles maps file extensions to their comment style. Extensions
+// not listed here have no comment syntax, like .json, so no header can be
+// written on them.
+var extCommentStyles = map[string]commentStyle{
+	".yaml":       hashComment,
+	".yml":        hashComment,
+	".toml":       hashComment,
+	".sh":         hashComment,
+	".bash":       hashComment,
+	".zsh":        hashComment,
+	".py":         hashComment,
+	".rb":         hashComment,
+	".pl":         hashComment,
+	".ps1":        hashComment,
+	".conf":       hashComment,
+	".cfg":        hashComment,
+	".ini":        hashComment,
+	".env":        hashComment,
+	".properties": hashComment,
+	".mk":         hashComment,
+	".gitignore":  hashComment,
+	".tf":         slashComment,
+	".tfvars":     slashComment,
+	".hcl":        slashComment,
+	".tm":         slashComment,
+	".go":         slashComment,
+	".js":         slashComment,
+	".jsx":        slashComment,
+	".ts":         slashComment,
+	".tsx":        slashComment,
+	".java":       slashComment,
+	".kt":         slashComment,
+	".scala":      slashComment,
+	".groovy":     slashComment,
+	".c":          slashComment,
+	".h":          slashComment,
+	".cpp":        slashComment,
+	".cs":         slashComment,
+	".rs":         slashComment,
+	".swift":      slashComment,
+	".proto":      slashComment,
+	".md":         xmlComment,
+	".markdown":   xmlComment,
+	".html":       xmlComment,
+	".htm":        xmlComment,
+	".xml":        xmlComment,
+	".svg":        xmlComment,
+}
+
+// nameCommentStyles maps well known file names without extension to their
+// comment style.
+var nameCommentStyles = map[string]commentStyle{
+	"Dockerfile":  hashComment,
+	"Makefile":    hashComment,
+	"Gemfile":     hashComment,
+	"Jenkinsfile": slashComment,
+}
+
+// fileCommentStyle returns the comment style of the file, chosen from its
+// name or extension. It returns false if the file has no comment syntax.
+func fileCommentStyle(filename string) (commentStyle, bool) {
+	base := path.Base(filename)
+	if style, ok := nameCommentStyles[base]; ok {
+		return style, true
+	}
+	style, ok := extCommentStyles[strings.ToLower(path.Ext(base))]
+	return style, ok
+}
+
+// renderHeader renders the header of the file, with the marker line followed
+// by the lines of the custom text, if any.
+func renderHeader(filename string, text string) (string, error) {
+	style, ok := fileCommentStyle(filename)
+	if !ok {
+		return "", errors.E(ErrInvalidHeader,
+			"%s: file type has no known comment style to write the header", filename)
+	}
+	lines := []string{HeaderMarker}
+	if text != "" {
+		lines = append(lines, strings.Split(strings.TrimSuffix(text, "\n"), "\n")...)
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		if style.end != "" && strings.Contains(line, strings.TrimSpace(style.end)) {
+			return "", errors.E(ErrInvalidHeader,
+				"header line %q can't contain the comment terminator %q", line, style.end)
+		}
+		b.WriteString(strings.TrimRight(style.start+line, " ") + style.end + "\n")
+	}
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+// splitShebang splits the shebang line, if any, from the rest of the code.
+// The header is written after the shebang, which must be the first line.
+func splitShebang(code string) (string, string) {
+	if !strings.HasPrefix(code, "#!") {
+		return "", code
+	}
+	i := strings.IndexByte(code, '\n')
+	if i == -1 {
+		return code + "\n", ""
+	}
+	return code[:i+1], code[i+1:]
+}
+
+// HasHeader tells if the code starts with a generated file header in any of
+// the supported comment styles, after the shebang line, if any.
+func HasHeader(code string) bool {
+	_, code = splitShebang(code)
+	for _, style := range commentStyles {
+		if strings.HasPrefix(code, style.start+HeaderMarker+style.end) {
+			return true
+		}
+	}
+	return false
+}
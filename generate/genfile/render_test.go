@@ -0,0 +1,332 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package genfile_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate/genfile"
+	"github.com/terramate-io/terramate/project"
+	errtest "github.com/terramate-io/terramate/test/errors"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateFileRendering(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name    string
+		layout  []string
+		want    string
+		wantErr error
+	}
+
+	const values = `
+  content = {
+    name     = global.name
+    replicas = 3
+    enabled  = true
+    ratio    = 0.5
+    empty    = null
+    image = {
+      tag        = "1.0"
+      repository = "nginx"
+    }
+    ports = [80, 443]
+    env = [
+      { name = "B", value = "yes" },
+      { value = "1", name = "A" },
+    ]
+  }
+`
+
+	for _, tc := range []testcase{
+		{
+			name: "template with vars",
+			layout: []string{
+				"f:templates/values.tmpl:name: ${name}\n%{ for p in ports ~}\n- ${p}\n%{ endfor ~}\nstack: ${terramate.stack.name}\n",
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  template = "../templates/values.tmpl"
+  vars = {
+    name  = global.name
+    ports = [80, 443]
+  }
+}
+`,
+			},
+			want: "name: app\n- 80\n- 443\nstack: stack\n",
+		},
+		{
+			name: "absolute template path is relative to the project root",
+			layout: []string{
+				"f:templates/values.tmpl:${global.name}",
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  template = "/templates/values.tmpl"
+}
+`,
+			},
+			want: "app",
+		},
+		{
+			name: "missing template fails",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  template = "missing.tmpl"
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrTemplateEval),
+		},
+		{
+			name: "template outside the project root fails",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  template = "../../values.tmpl"
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrTemplateOutsideRoot),
+		},
+		{
+			name: "absolute template path outside the project root fails",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  template = "/../values.tmpl"
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrTemplateOutsideRoot),
+		},
+		{
+			name: "vars conflicting with namespaces fails",
+			layout: []string{
+				"f:stack/values.tmpl:${global}",
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  template = "values.tmpl"
+  vars     = { global = 1 }
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrInvalidVarsType),
+		},
+		{
+			name: "yaml keeps the key order",
+			layout: []string{
+				"f:stack/gen.tm:generate_file \"values.yaml\" {\n  format = yaml\n" + values + "}\n",
+			},
+			want: `name: app
+replicas: 3
+enabled: true
+ratio: 0.5
+empty: null
+image:
+  tag: "1.0"
+  repository: nginx
+ports:
+  - 80
+  - 443
+env:
+  - name: B
+    value: "yes"
+  - value: "1"
+    name: A
+`,
+		},
+		{
+			name: "yaml with custom indent",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  format  = yaml
+  indent  = 4
+  content = { a = { b = [1] } }
+}
+`,
+			},
+			want: "a:\n    b:\n        - 1\n",
+		},
+		{
+			name: "yaml with invalid indent fails",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  format  = yaml
+  indent  = 1
+  content = {}
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrInvalidIndent),
+		},
+		{
+			name: "json keeps the key order",
+			layout: []string{
+				"f:stack/gen.tm:generate_file \"values.json\" {\n  format = json\n" + values + "}\n",
+			},
+			want: `{
+  "name": "app",
+  "replicas": 3,
+  "enabled": true,
+  "ratio": 0.5,
+  "empty": null,
+  "image": {
+    "tag": "1.0",
+    "repository": "nginx"
+  },
+  "ports": [
+    80,
+    443
+  ],
+  "env": [
+    {
+      "name": "B",
+      "value": "yes"
+    },
+    {
+      "value": "1",
+      "name": "A"
+    }
+  ]
+}
+`,
+		},
+		{
+			name: "toml keeps the key order",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "config.toml" {
+  format = toml
+  content = {
+    title = global.name
+    owner = {
+      name = "tm"
+      dob  = "1979"
+    }
+    ports = [80, 443]
+    "a key" = { x = 1, y = [{ z = "a\"b" }, 1] }
+    servers = [
+      { name = "b", ip = "10.0.0.2" },
+      { name = "a", ip = "10.0.0.1", tags = { role = "db" } },
+    ]
+  }
+}
+`,
+			},
+			want: `title = "app"
+ports = [80, 443]
+
+[owner]
+name = "tm"
+dob = "1979"
+
+["a key"]
+x = 1
+y = [{ z = "a\"b" }, 1]
+
+[[servers]]
+name = "b"
+ip = "10.0.0.2"
+
+[[servers]]
+name = "a"
+ip = "10.0.0.1"
+
+[servers.tags]
+role = "db"
+`,
+		},
+		{
+			name: "toml with indent",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "config.toml" {
+  format  = toml
+  indent  = 2
+  content = { a = { b = { c = 1 } } }
+}
+`,
+			},
+			want: "  [a.b]\n    c = 1\n",
+		},
+		{
+			name: "toml with null fails",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "config.toml" {
+  format  = toml
+  content = { a = null }
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrContentEncode),
+		},
+		{
+			name: "toml content must be an object",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "config.toml" {
+  format  = toml
+  content = [1]
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrContentEncode),
+		},
+		{
+			name: "header on file type without comments fails",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "values.json" {
+  header  = true
+  content = "{}"
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrInvalidHeader),
+		},
+		{
+			name: "header with invalid type fails",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  header  = 1
+  content = ""
+}
+`,
+			},
+			wantErr: errors.E(genfile.ErrInvalidHeader),
+		},
+		{
+			name: "header false writes no header",
+			layout: []string{
+				`f:stack/gen.tm:generate_file "values.yaml" {
+  header  = false
+  content = "a: 1\n"
+}
+`,
+			},
+			want: "a: 1\n",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree(append([]string{
+				"s:stack",
+				"f:globals.tm:globals {\n  name = \"app\"\n}\n",
+			}, tc.layout...))
+
+			root, err := config.LoadRoot(s.RootDir())
+			assert.NoError(t, err)
+
+			st := s.LoadStacks()[0].Stack
+			globals := s.LoadStackGlobals(root, st)
+			got, err := genfile.Load(root, st, globals, project.NewPath("/modules"), nil)
+			errtest.Assert(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+
+			assert.EqualInts(t, 1, len(got))
+			assert.EqualStrings(t, tc.want, got[0].Body())
+		})
+	}
+}
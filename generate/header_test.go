@@ -0,0 +1,143 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateFileHeader(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:stack/gen.tm:generate_file "values.yaml" {
+  header  = true
+  content = "a: 1\n"
+}
+
+generate_file "README.md" {
+  header  = "Edit the stack config instead."
+  content = "# Stack\n"
+}
+
+generate_file "run.sh" {
+  header  = true
+  content = "#!/bin/sh\necho hi\n"
+}
+
+generate_file "data.txt" {
+  content = "no header\n"
+}
+`,
+	})
+
+	report := s.Generate()
+	assert.IsTrue(t, !report.HasFailures(), "unexpected failures: %s", report.Full())
+
+	stack := s.DirEntry("stack")
+	assert.EqualStrings(t,
+		"# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n\na: 1\n",
+		string(stack.ReadFile("values.yaml")))
+	assert.EqualStrings(t,
+		"<!-- TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT -->\n"+
+			"<!-- Edit the stack config instead. -->\n\n# Stack\n",
+		string(stack.ReadFile("README.md")))
+	assert.EqualStrings(t,
+		"#!/bin/sh\n# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n\necho hi\n",
+		string(stack.ReadFile("run.sh")))
+
+	genfiles, err := generate.ListGenFiles(s.Config(), stack.Path())
+	assert.NoError(t, err)
+	assertEqualStringList(t, genfiles, []string{"README.md", "run.sh", "values.yaml"})
+
+	// orphaned files are removed, the ones without a header because they are
+	// recorded in the generation manifest.
+	stack.CreateFile("gen.tm", `generate_file "values.yaml" {
+  header  = true
+  content = "a: 1\n"
+}
+`)
+	s.ReloadConfig()
+	report = s.Generate()
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Deleted: []string{"README.md", "data.txt", "run.sh"},
+			},
+		},
+	})
+}
+
+func TestGenerateFileHeaderProtectsManualFiles(t *testing.T) {
+	t.Parallel()
+
+	const manual = "manual: true\n"
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"f:stack/values.yaml:" + manual,
+		`f:stack/gen.tm:generate_file "values.yaml" {
+  header  = true
+  content = "a: 1\n"
+}
+`,
+	})
+
+	report := generate.Do(s.Config(), project.NewPath("/modules"), nil)
+	assertReportHasError(t, report, errors.E(generate.ErrManualCodeExists))
+	assert.EqualStrings(t, manual, string(s.DirEntry("stack").ReadFile("values.yaml")))
+}
+
+func TestGenerateFileHeaderRootContext(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:gen.tm:generate_file "/docs/readme.md" {
+  context = root
+  header  = true
+  content = "hello\n"
+}
+`,
+	})
+
+	report := s.Generate()
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/docs"),
+				Created: []string{"readme.md"},
+			},
+		},
+	})
+	assert.EqualStrings(t,
+		"<!-- TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT -->\n\nhello\n",
+		string(s.DirEntry("docs").ReadFile("readme.md")))
+
+	// the generated file is not an orphan, so generating again does nothing.
+	assertEqualReports(t, s.Generate(), generate.Report{})
+
+	// it's deleted once its block is removed.
+	s.RootEntry().CreateFile("gen.tm", "")
+	s.ReloadConfig()
+	assertEqualReports(t, s.Generate(), generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/docs"),
+				Deleted: []string{"readme.md"},
+			},
+		},
+	})
+}
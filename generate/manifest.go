@@ -0,0 +1,299 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate/genfile"
+	"github.com/terramate-io/terramate/generate/genhcl"
+	"github.com/terramate-io/terramate/project"
+)
+
+// ErrManifest indicates a failure reading or writing the generation manifest.
+const ErrManifest errors.Kind = "generation manifest"
+
+// ManifestFile is the file, relative to the project root, recording every
+// file generated by Terramate. It's meant to be committed with the generated
+// files.
+const ManifestFile = ".terramate/generated.json"
+
+const manifestVersion = 1
+
+// Manifest records the files generated by Terramate, with the hash of their
+// content and the block that generated them.
+type Manifest struct {
+	Version int `json:"version"`
+
+	// Files are the generated files, keyed by their absolute path relative
+	// to the project root.
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+// ManifestEntry is a generated file recorded in the manifest.
+type ManifestEntry struct {
+	// SHA256 is the hex encoded hash of the generated content.
+	SHA256 string `json:"sha256"`
+
+	// Stack is the stack that generated the file, empty if the file was
+	// generated by a generate_file block with context=root.
+	Stack string `json:"stack,omitempty"`
+
+	// Block is the type of the generate block, generate_hcl or generate_file.
+	Block string `json:"block"`
+
+	// Label is the label of the generate block.
+	Label string `json:"label"`
+
+	// Origin is the range of the generate block.
+	Origin ManifestRange `json:"origin"`
+}
+
+// ManifestRange is the range of a generate block in its configuration file.
+type ManifestRange struct {
+	File  string      `json:"file"`
+	Start ManifestPos `json:"start"`
+	End   ManifestPos `json:"end"`
+}
+
+// String formats the range the same way as [info.Range].
+func (r ManifestRange) String() string {
+	if r.Start.Line == r.End.Line {
+		return fmt.Sprintf("%s:%d,%d-%d", r.File, r.Start.Line, r.Start.Column, r.End.Column)
+	}
+	return fmt.Sprintf("%s:%d,%d-%d,%d", r.File, r.Start.Line, r.Start.Column, r.End.Line, r.End.Column)
+}
+
+// ManifestPos is a position in a configuration file.
+type ManifestPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// LoadManifest loads the generation manifest of the project. If the project
+// has no manifest, an empty one is returned.
+func LoadManifest(root *config.Root) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath(root))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Manifest{Version: manifestVersion, Files: map[string]ManifestEntry{}}, nil
+		}
+		return nil, errors.E(ErrManifest, err, "reading %s", ManifestFile)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.E(ErrManifest, err, "parsing %s", ManifestFile)
+	}
+	if m.Version != manifestVersion {
+		return nil, errors.E(ErrManifest, "%s has unsupported version %d", ManifestFile, m.Version)
+	}
+	if m.Files == nil {
+		m.Files = map[string]ManifestEntry{}
+	}
+	return &m, nil
+}
+
+// Lookup returns the manifest entry of the generated file.
+func (m *Manifest) Lookup(file project.Path) (ManifestEntry, bool) {
+	e, ok := m.Files[file.String()]
+	return e, ok
+}
+
+// Modified tells if the content differs from the generated content recorded
+// in the entry, which means the file was edited after being generated.
+func (e ManifestEntry) Modified(content string) bool {
+	return e.SHA256 != contentHash(content)
+}
+
+func manifestPath(root *config.Root) string {
+	return filepath.Join(root.HostDir(), filepath.FromSlash(ManifestFile))
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func newManifestEntry(owner project.Path, file GenFile) ManifestEntry {
+	block := "generate_file"
+	if _, ok := file.(genhcl.HCL); ok {
+		block = "generate_hcl"
+	}
+	r := file.Range()
+	var stack string
+	if file.Context() != genfile.RootContext {
+		stack = owner.String()
+	}
+	return ManifestEntry{
+		SHA256: contentHash(file.Header() + file.Body()),
+		Stack:  stack,
+		Block:  block,
+		Label:  file.Label(),
+		Origin: ManifestRange{
+			File:  r.Path().String(),
+			Start: ManifestPos{Line: r.Start().Line(), Column: r.Start().Column()},
+			End:   ManifestPos{Line: r.End().Line(), Column: r.End().Column()},
+		},
+	}
+}
+
+// manifestState tracks the changes to the manifest during code generation,
+// which may generate the stacks concurrently. Entries are owned by their
+// stack, or by the root context, and the entries of an owner are only
+// replaced when all its code is generated successfully.
+type manifestState struct {
+	old *Manifest
+
+	mu          sync.Mutex
+	files       map[string]ManifestEntry
+	done        map[string]bool
+	manualEdits []project.Path
+}
+
+// rootOwner is the owner of the files generated with context=root.
+const rootOwner = ""
+
+func newManifestState(old *Manifest) *manifestState {
+	return &manifestState{
+		old:   old,
+		files: map[string]ManifestEntry{},
+		done:  map[string]bool{},
+	}
+}
+
+// generated records the file generated at the given path.
+func (s *manifestState) generated(path project.Path, owner project.Path, file GenFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path.String()] = newManifestEntry(owner, file)
+}
+
+// isGenerated tells if the file at the given path was generated in this run.
+func (s *manifestState) isGenerated(path project.Path) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.files[path.String()]
+	return ok
+}
+
+// finished marks the owner code generation as successful, so its old entries
+// are replaced.
+func (s *manifestState) finished(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done[owner] = true
+}
+
+// edited tells if the file with the given content was edited manually since
+// it was generated.
+func (s *manifestState) edited(path project.Path, content string) bool {
+	e, ok := s.old.Lookup(path)
+	return ok && e.Modified(content)
+}
+
+func (s *manifestState) addManualEdit(path project.Path) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manualEdits = append(s.manualEdits, path)
+}
+
+// ownedBy returns the old entries of the owner, sorted by path.
+func (s *manifestState) ownedBy(owner string) []project.Path {
+	var paths []project.Path
+	for p, e := range s.old.Files {
+		if e.Stack == owner {
+			paths = append(paths, project.NewPath(p))
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].String() < paths[j].String()
+	})
+	return paths
+}
+
+// orphaned returns the old entries not generated anymore whose owner is not
+// a stack anymore, or the root context if it was generated successfully.
+// The entries of existing stacks are handled by their code generation.
+func (s *manifestState) orphaned(root *config.Root) []project.Path {
+	var paths []project.Path
+	for p, e := range s.old.Files {
+		if _, ok := s.files[p]; ok || !s.ownerOrphaned(root, e.Stack) {
+			continue
+		}
+		paths = append(paths, project.NewPath(p))
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].String() < paths[j].String()
+	})
+	return paths
+}
+
+func (s *manifestState) ownerOrphaned(root *config.Root, owner string) bool {
+	if owner == rootOwner {
+		return s.done[rootOwner]
+	}
+	cfg, ok := root.Lookup(project.NewPath(owner))
+	return !ok || !cfg.IsStack()
+}
+
+// result computes the new manifest. The old entries of owners whose code
+// generation failed or wasn't done are kept, unless the owner is orphaned in
+// a full code generation, in which case its files were cleaned up.
+func (s *manifestState) result(root *config.Root, full bool) *Manifest {
+	m := &Manifest{Version: manifestVersion, Files: map[string]ManifestEntry{}}
+	for p, e := range s.old.Files {
+		if s.done[e.Stack] || (full && s.ownerOrphaned(root, e.Stack)) {
+			continue
+		}
+		m.Files[p] = e
+	}
+	for p, e := range s.files {
+		m.Files[p] = e
+	}
+	return m
+}
+
+// save writes the manifest, unless it's unchanged or both the old and the
+// new manifests are empty.
+func (s *manifestState) save(root *config.Root, m *Manifest) error {
+	path := manifestPath(root)
+	old, err := os.ReadFile(path)
+	exists := err == nil
+	if !exists && len(m.Files) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.E(ErrManifest, err, "encoding %s", ManifestFile)
+	}
+	data = append(data, '\n')
+	if exists && bytes.Equal(old, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.E(ErrManifest, err, "writing %s", ManifestFile)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return errors.E(ErrManifest, err, "writing %s", ManifestFile)
+	}
+	return nil
+}
+
+func (s *manifestState) sortedManualEdits() []project.Path {
+	edits := append([]project.Path{}, s.manualEdits...)
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].String() < edits[j].String()
+	})
+	return edits
+}
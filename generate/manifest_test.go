@@ -0,0 +1,142 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateManifest(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/a",
+		"s:stacks/b",
+		`f:stacks/gen.tm:generate_file "file.txt" {
+  content = terramate.stack.name
+}
+
+generate_hcl "main.tf" {
+  content {
+    a = 1
+  }
+}
+`,
+		`f:root.tm:generate_file "/root.txt" {
+  context = root
+  content = "root"
+}
+`,
+	})
+
+	report := s.Generate()
+	assert.IsTrue(t, !report.HasFailures(), "unexpected failures: %s", report.Full())
+
+	manifest, err := generate.LoadManifest(s.Config())
+	assert.NoError(t, err)
+	assert.EqualInts(t, 5, len(manifest.Files))
+
+	entry, ok := manifest.Lookup(project.NewPath("/stacks/a/file.txt"))
+	assert.IsTrue(t, ok, "generated file not recorded")
+	assert.EqualStrings(t, "/stacks/a", entry.Stack)
+	assert.EqualStrings(t, "generate_file", entry.Block)
+	assert.EqualStrings(t, "file.txt", entry.Label)
+	assert.EqualStrings(t, "/stacks/gen.tm:1,1-3,2", entry.Origin.String())
+	assert.IsTrue(t, !entry.Modified("a"), "unmodified content detected as modified")
+	assert.IsTrue(t, entry.Modified("edited"), "modified content not detected")
+
+	entry, ok = manifest.Lookup(project.NewPath("/stacks/b/main.tf"))
+	assert.IsTrue(t, ok, "generated file not recorded")
+	assert.EqualStrings(t, "generate_hcl", entry.Block)
+
+	entry, ok = manifest.Lookup(project.NewPath("/root.txt"))
+	assert.IsTrue(t, ok, "root context file not recorded")
+	assert.EqualStrings(t, "", entry.Stack)
+
+	// manual changes are overwritten, but reported.
+	s.DirEntry("stacks/a").CreateFile("file.txt", "edited")
+	report = s.Generate()
+	assertEqualPaths(t, report.ManualEdits, project.Paths{project.NewPath("/stacks/a/file.txt")})
+	assert.EqualStrings(t, "a", string(s.DirEntry("stacks/a").ReadFile("file.txt")))
+
+	// orphaned files without header are deleted unless edited manually.
+	s.DirEntry("stacks/b").CreateFile("file.txt", "edited")
+	s.RootEntry().CreateFile("root.tm", "")
+	s.DirEntry("stacks").CreateFile("gen.tm", "")
+	s.ReloadConfig()
+	report = s.Generate()
+	assertEqualPaths(t, report.ManualEdits, project.Paths{project.NewPath("/stacks/b/file.txt")})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/"),
+				Deleted: []string{"root.txt"},
+			},
+			{
+				Dir:     project.NewPath("/stacks/a"),
+				Deleted: []string{"file.txt", "main.tf"},
+			},
+			{
+				Dir:     project.NewPath("/stacks/b"),
+				Deleted: []string{"main.tf"},
+			},
+		},
+	})
+	assert.EqualStrings(t, "edited", string(s.DirEntry("stacks/b").ReadFile("file.txt")))
+
+	manifest, err = generate.LoadManifest(s.Config())
+	assert.NoError(t, err)
+	assert.EqualInts(t, 0, len(manifest.Files))
+}
+
+func TestGenerateManifestFiltered(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/a",
+		"s:stacks/b",
+		`f:stacks/gen.tm:generate_file "file.txt" {
+  content = terramate.stack.name
+}
+`,
+	})
+
+	report := s.Generate()
+	assert.IsTrue(t, !report.HasFailures(), "unexpected failures: %s", report.Full())
+
+	s.DirEntry("stacks").CreateFile("gen.tm", `generate_file "other.txt" {
+  content = terramate.stack.name
+}
+`)
+	s.ReloadConfig()
+	report = generate.DoFiltered(s.Config(), project.NewPath("/modules"), nil, &generate.Filter{
+		Stacks: project.Paths{project.NewPath("/stacks/a")},
+	})
+	assert.IsTrue(t, !report.HasFailures(), "unexpected failures: %s", report.Full())
+
+	manifest, err := generate.LoadManifest(s.Config())
+	assert.NoError(t, err)
+	for _, path := range []string{"/stacks/a/other.txt", "/stacks/b/file.txt"} {
+		_, ok := manifest.Lookup(project.NewPath(path))
+		assert.IsTrue(t, ok, "%s not recorded", path)
+	}
+	_, ok := manifest.Lookup(project.NewPath("/stacks/a/file.txt"))
+	assert.IsTrue(t, !ok, "deleted file still recorded")
+}
+
+func assertEqualPaths(t *testing.T, got, want project.Paths) {
+	t.Helper()
+
+	assert.EqualInts(t, len(want), len(got), "paths %v != %v", got, want)
+	for i := range want {
+		assert.EqualStrings(t, want[i].String(), got[i].String())
+	}
+}
@@ -437,6 +437,7 @@ func TestOutdatedDetection(t *testing.T) {
 					},
 					want: []string{
 						"stack-2/test.hcl",
+						"stack-2/test.txt",
 					},
 				},
 			},
@@ -685,7 +686,7 @@ func TestOutdatedDetection(t *testing.T) {
 			},
 		},
 		{
-			name: "generate_file is not detected when deleted",
+			name: "generate_file is detected when deleted using the manifest",
 			steps: []step{
 				{
 					layout: []string{
@@ -715,7 +716,10 @@ func TestOutdatedDetection(t *testing.T) {
 							body: Doc(),
 						},
 					},
-					want: []string{},
+					want: []string{
+						"stack-1/test.txt",
+						"stack-2/test.txt",
+					},
 				},
 			},
 		},
@@ -24,6 +24,41 @@ type Result struct {
 	Deleted []string
 }
 
+// FileChangeKind is the kind of change made to a generated file.
+type FileChangeKind string
+
+// The kinds of changes made to generated files.
+const (
+	FileCreated FileChangeKind = "created"
+	FileChanged FileChangeKind = "changed"
+	FileDeleted FileChangeKind = "deleted"
+)
+
+// FileChange is a change that the code generation makes to a file.
+type FileChange struct {
+	// Path is the absolute path of the file relative to the project root.
+	Path project.Path
+	// Kind is the kind of the change.
+	Kind FileChangeKind
+	// Old is the content of the file before the change, empty if the file
+	// is created.
+	Old string
+	// New is the generated content of the file, empty if the file is deleted.
+	New string
+}
+
+// Diff returns the change as an unified diff.
+func (c FileChange) Diff() string {
+	oldName, newName := "a"+c.Path.String(), "b"+c.Path.String()
+	switch c.Kind {
+	case FileCreated:
+		oldName = "/dev/null"
+	case FileDeleted:
+		newName = "/dev/null"
+	}
+	return unifiedDiff(oldName, newName, c.Old, c.New)
+}
+
 // FailureResult represents a failure on code generation.
 type FailureResult struct {
 	Result
@@ -45,6 +80,16 @@ type Report struct {
 	// CleanupErr is an error that happened after code generation
 	// was done while trying to cleanup files outside stacks.
 	CleanupErr error
+
+	// Changes are the changes to the generated files, ordered by path.
+	// They are only computed by [DryRun].
+	Changes []FileChange
+
+	// ManualEdits are the generated files that were edited manually since
+	// they were generated, according to the [Manifest], ordered by path.
+	// Changed files had the manual changes overwritten, while orphaned files
+	// without a header are kept.
+	ManualEdits []project.Path
 }
 
 // HasFailures returns true if this report includes any failures.
@@ -123,6 +168,15 @@ func (r Report) Full() string {
 		addLine("\terror: %s\n", r.CleanupErr)
 	}
 
+	if len(r.ManualEdits) > 0 {
+		addLine("Generated files edited manually:")
+		newline()
+		for _, file := range r.ManualEdits {
+			addLine("- %s", file)
+		}
+		newline()
+	}
+
 	if needsHint {
 		addLine("Hint: '+', '~' and '-' means the file was created, changed and deleted, respectively.")
 	}
@@ -178,13 +232,19 @@ func (r Report) Minimal() string {
 		addLine("\terror: %s", r.CleanupErr)
 	}
 
+	for _, file := range r.ManualEdits {
+		addLine("Generated file %s was edited manually", file)
+	}
+
 	return strings.Join(report, "\n")
 }
 
 func (r Report) empty() bool {
 	return r.BootstrapErr == nil &&
+		r.CleanupErr == nil &&
 		len(r.Failures) == 0 &&
-		len(r.Successes) == 0
+		len(r.Successes) == 0 &&
+		len(r.ManualEdits) == 0
 }
 
 func (r *Report) sort() {
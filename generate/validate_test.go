@@ -0,0 +1,94 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestValidateStack(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/ok",
+		"s:stacks/asserts",
+		"s:stacks/eval",
+		`f:stacks/asserts/gen.tm:generate_hcl "a.tf" {
+  assert {
+    assertion = false
+    message   = "always fails"
+  }
+  assert {
+    assertion = false
+    message   = "only warns"
+    warning   = true
+  }
+  content {
+    a = 1
+  }
+}
+
+generate_file "a.tf" {
+  content = "a"
+}
+`,
+		`f:stacks/eval/gen.tm:generate_hcl "b.tf" {
+  content {
+    b = global.undefined
+  }
+}
+`,
+	})
+
+	root := s.Config()
+	validate := func(dir string) map[errors.Kind]int {
+		st, err := config.LoadStack(root, project.NewPath(dir))
+		assert.NoError(t, err)
+
+		kinds := map[errors.Kind]int{}
+		err = generate.Validate(root, st, project.NewPath("/modules"))
+		for _, err := range errors.L(err).Errors() {
+			e, ok := err.(*errors.Error)
+			if !ok {
+				t.Fatalf("unexpected error type %T: %v", err, err)
+			}
+			if e.FileRange.Empty() {
+				t.Errorf("error without range: %v", e)
+			}
+			switch {
+			case errors.IsKind(e, generate.ErrAssertion):
+				kinds[generate.ErrAssertion]++
+			case errors.IsKind(e, generate.ErrConflictingConfig):
+				kinds[generate.ErrConflictingConfig]++
+			default:
+				kinds["other"]++
+			}
+		}
+		return kinds
+	}
+
+	if diff := cmp.Diff(map[errors.Kind]int{}, validate("/stacks/ok")); diff != "" {
+		t.Errorf("unexpected errors for /stacks/ok: %s", diff)
+	}
+	if diff := cmp.Diff(map[errors.Kind]int{
+		generate.ErrAssertion:         1,
+		generate.ErrConflictingConfig: 2,
+	}, validate("/stacks/asserts")); diff != "" {
+		t.Errorf("unexpected errors for /stacks/asserts: %s", diff)
+	}
+	if diff := cmp.Diff(map[errors.Kind]int{
+		"other": 1,
+	}, validate("/stacks/eval")); diff != "" {
+		t.Errorf("unexpected errors for /stacks/eval: %s", diff)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/terramate-io/terramate/project"
+)
+
+// fileWriter writes and removes the generated files. On dry runs the files
+// are left untouched and the changes are only recorded. It's safe for
+// concurrent use.
+type fileWriter struct {
+	rootdir string
+	dryRun  bool
+
+	mu      sync.Mutex
+	changes []FileChange
+}
+
+// write writes the generated file at the given path, which had the old
+// content if it existed.
+func (w *fileWriter) write(path string, genfile GenFile, old string, exists bool) error {
+	if !w.dryRun {
+		return writeGeneratedCode(path, genfile)
+	}
+
+	if genfile.Header() != "" {
+		if err := checkFileCanBeOverwritten(path); err != nil {
+			return err
+		}
+	}
+
+	kind := FileChanged
+	if !exists {
+		kind = FileCreated
+	}
+	w.addChange(FileChange{
+		Path: project.PrjAbsPath(w.rootdir, path),
+		Kind: kind,
+		Old:  old,
+		New:  genfile.Header() + genfile.Body(),
+	})
+	return nil
+}
+
+// remove removes the generated file at the given path, which has the old
+// content.
+func (w *fileWriter) remove(path string, old string) error {
+	if !w.dryRun {
+		return os.Remove(path)
+	}
+	w.addChange(FileChange{
+		Path: project.PrjAbsPath(w.rootdir, path),
+		Kind: FileDeleted,
+		Old:  old,
+	})
+	return nil
+}
+
+// readOld returns the content of the file to be removed. The content is only
+// needed, and read, on dry runs.
+func (w *fileWriter) readOld(path string) string {
+	if !w.dryRun {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (w *fileWriter) addChange(change FileChange) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.changes = append(w.changes, change)
+}
+
+func (w *fileWriter) sortedChanges() []FileChange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sort.Slice(w.changes, func(i, j int) bool {
+		return w.changes[i].Path.String() < w.changes[j].Path.String()
+	})
+	return w.changes
+}
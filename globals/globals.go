@@ -233,6 +233,67 @@ func (dirExprs HierarchicalExprs) SetOverride(
 	}
 }
 
+// Definitions returns the expressions which set the global at the given
+// accessor path (without the "global" namespace), from the most specific
+// configuration dir (the one that takes precedence) to the least specific one.
+//
+// If no expression sets the path or any of its parent objects, then the
+// expressions defining its attributes through labeled globals blocks are
+// returned, eg.: the block `globals a { b = 1 }` defines `global.a`.
+func (dirExprs HierarchicalExprs) Definitions(path []string) []Expr {
+	isPrefix := func(prefix, path []string) bool {
+		if len(prefix) > len(path) {
+			return false
+		}
+		for i := range prefix {
+			if prefix[i] != path[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	sorted := dirExprs.sort()
+
+	var defs, extensions []Expr
+	for i := len(sorted) - 1; i >= 0; i-- {
+		exprset := sorted[i]
+		accessors := exprset.sort()
+		for j := len(accessors) - 1; j >= 0; j-- {
+			accessor := accessors[j]
+			expr := exprset.expressions[accessor]
+			switch {
+			case isPrefix(accessor.Path(), path):
+				defs = append(defs, expr)
+			case isPrefix(path, accessor.Path()):
+				extensions = append(extensions, expr)
+			}
+		}
+	}
+	if len(defs) > 0 {
+		return defs
+	}
+	return extensions
+}
+
+// Files returns the host paths of the files where the expressions are
+// defined, including the imported ones, sorted and without duplicates.
+func (dirExprs HierarchicalExprs) Files() []string {
+	seen := map[string]bool{}
+	files := []string{}
+	for _, exprset := range dirExprs {
+		for _, expr := range exprset.expressions {
+			file := expr.Origin.HostPath()
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
 // Returns a sorted loaded exprs, sorting it by config dir path.
 // The loaded expressions are sorted by the config dir path
 // from smaller (root) to more specific (stack). Eg:
@@ -14,6 +14,7 @@ import (
 	"github.com/terramate-io/terramate/globals"
 	"github.com/terramate-io/terramate/hcl"
 	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/stack"
 
 	"github.com/terramate-io/terramate/test"
@@ -40,6 +41,73 @@ type (
 	}
 )
 
+func TestGlobalsDefinitions(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		`f:globals.tm:globals {
+  a = 1
+  obj = {
+    b = 1
+  }
+}
+
+globals "labeled" {
+  c = 1
+}
+`,
+		"s:stack",
+		`f:stack/globals.tm:globals {
+  a = 2
+}
+`,
+	})
+
+	tree, ok := s.Config().Lookup(project.NewPath("/stack"))
+	assert.IsTrue(t, ok)
+
+	exprs, err := globals.LoadExprs(tree)
+	assert.NoError(t, err)
+
+	type want struct {
+		file string
+		line int
+	}
+
+	for _, tc := range []struct {
+		path []string
+		want []want
+	}{
+		{
+			path: []string{"a"},
+			want: []want{{"/stack/globals.tm", 2}, {"/globals.tm", 2}},
+		},
+		{
+			path: []string{"obj", "b"},
+			want: []want{{"/globals.tm", 3}},
+		},
+		{
+			path: []string{"labeled"},
+			want: []want{{"/globals.tm", 9}},
+		},
+		{
+			path: []string{"labeled", "c"},
+			want: []want{{"/globals.tm", 9}},
+		},
+		{
+			path: []string{"undefined"},
+		},
+	} {
+		got := exprs.Definitions(tc.path)
+		assert.EqualInts(t, len(tc.want), len(got), "definitions of %v: %v", tc.path, got)
+		for i, want := range tc.want {
+			assert.EqualStrings(t, want.file, got[i].Origin.Path().String())
+			assert.EqualInts(t, want.line, got[i].Origin.Start().Line())
+		}
+	}
+}
+
 // TODO(katcipis): add tests related to tf functions that depend on filesystem
 // (BaseDir parameter passed on Scope when creating eval context).
 func TestLoadGlobals(t *testing.T) {
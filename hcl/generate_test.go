@@ -6,6 +6,7 @@ package hcl_test
 import (
 	"testing"
 
+	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/hcl"
 
 	. "github.com/terramate-io/terramate/test/hclutils"
@@ -110,3 +111,173 @@ func TestHCLParserGenerateBlocksRange(t *testing.T) {
 		testParser(t, tcase)
 	}
 }
+
+func TestHCLParserGenerateFileTemplateAndFormat(t *testing.T) {
+	for _, tc := range []testcase{
+		{
+			name: "generate_file with template and vars",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `generate_file "values.yaml" {
+  template = "values.yaml.tmpl"
+  vars     = { name = "test" }
+}
+`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Generate: hcl.GenerateConfig{
+						Files: []hcl.GenFileBlock{
+							{
+								Label: "values.yaml",
+								Range: Range("gen.tm", Start(1, 1, 0), End(4, 2, 94)),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "generate_file with format and indent",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `generate_file "values.yaml" {
+  format  = yaml
+  indent  = 4
+  content = { name = "test" }
+}
+`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Generate: hcl.GenerateConfig{
+						Files: []hcl.GenFileBlock{
+							{
+								Label: "values.yaml",
+								Range: Range("gen.tm", Start(1, 1, 0), End(5, 2, 92)),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "generate_file without content and template fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.txt" {
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with content and template fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.txt" {
+						content = "test"
+						template = "test.tmpl"
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with vars and no template fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.txt" {
+						content = "test"
+						vars = {}
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with format and template fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.yaml" {
+						template = "test.tmpl"
+						format = yaml
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with unsupported format fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.xml" {
+						content = {}
+						format = xml
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with indent and no format fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.txt" {
+						content = "test"
+						indent = 2
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+	} {
+		testParser(t, tc)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
@@ -42,6 +43,7 @@ type Config struct {
 	Terramate *Terramate
 	Stack     *Stack
 	Globals   ast.MergedLabelBlocks
+	Env       *RunEnv
 	Vendor    *VendorConfig
 	Asserts   []AssertConfig
 	Generate  GenerateConfig
@@ -74,9 +76,30 @@ type RunConfig struct {
 
 	// Env contains environment definitions for run.
 	Env *RunEnv
+
+	// Timeout is the maximum duration of the command executed in each stack.
+	// Zero means no timeout.
+	Timeout time.Duration
+
+	// Retry contains the retry configuration of failed commands.
+	Retry *RunRetryConfig
+}
+
+// RunRetryConfig represents the retry configuration of failed commands.
+type RunRetryConfig struct {
+	// Attempts is the maximum number of times a command is retried.
+	Attempts int
+
+	// ExitCodes is the list of exit codes that cause the command to be retried.
+	ExitCodes []int
+
+	// Backoff is the time waited before the first retry. It doubles on each
+	// subsequent attempt.
+	Backoff time.Duration
 }
 
-// RunEnv represents Terramate run environment.
+// RunEnv represents Terramate run environment, defined by the
+// terramate.config.run.env block or by the top-level env blocks.
 type RunEnv struct {
 	// Attributes is the collection of attribute definitions within the env block.
 	Attributes ast.Attributes
@@ -105,8 +128,19 @@ type GitConfig struct {
 
 // RootConfig represents the root config block of a Terramate configuration.
 type RootConfig struct {
-	Git *GitConfig
-	Run *RunConfig
+	Git      *GitConfig
+	Run      *RunConfig
+	Generate *CodeGenConfig
+}
+
+// CodeGenConfig represents Terramate code generation configuration.
+type CodeGenConfig struct {
+	// Parallel is the maximum number of stacks generated concurrently.
+	// Zero means the number of CPUs.
+	Parallel int
+
+	// GlobalsCache enables the cache of evaluated globals.
+	GlobalsCache bool
 }
 
 // ManifestDesc represents a parsed manifest description.
@@ -204,8 +238,20 @@ type GenFileBlock struct {
 	Lets *ast.MergedBlock
 	// Condition attribute of the block, if any.
 	Condition *hclsyntax.Attribute
-	// Content attribute of the block
+	// Content attribute of the block, if any.
 	Content *hclsyntax.Attribute
+	// Template attribute of the block, if any. It's the path of a template
+	// file, relative to the directory of the file defining the block.
+	Template *hclsyntax.Attribute
+	// Vars attribute of the block, if any. It's only used with Template.
+	Vars *hclsyntax.Attribute
+	// Format in which the Content value is rendered. It's empty when the
+	// content is a string, otherwise one of "yaml", "toml" or "json".
+	Format string
+	// Indent attribute of the block, if any. It's only used with Format.
+	Indent *hclsyntax.Attribute
+	// Header attribute of the block, if any.
+	Header *hclsyntax.Attribute
 	// Context of the generation (stack by default).
 	Context string
 	// Asserts represents all assert blocks
@@ -242,6 +288,10 @@ type TerramateParser struct {
 	dir       string
 	files     map[string][]byte // path=content
 	hclparser *hclparse.Parser
+
+	// preparsed are the files added already parsed, which are not parsed
+	// again.
+	preparsed map[string]*hcl.File
 	evalctx   *eval.Context
 
 	// parsedFiles stores a map of all parsed files
@@ -300,6 +350,7 @@ func NewTerramateParser(rootdir string, dir string) (*TerramateParser, error) {
 		dir:         dir,
 		files:       map[string][]byte{},
 		hclparser:   hclparse.NewParser(),
+		preparsed:   map[string]*hcl.File{},
 		Config:      NewTopLevelRawConfig(),
 		Imported:    NewTopLevelRawConfig(),
 		parsedFiles: make(map[string]parsedFile),
@@ -386,6 +437,16 @@ func (p *TerramateParser) AddFileContent(name string, data []byte) error {
 	return nil
 }
 
+// AddParsedFile adds a file already parsed with [hclsyntax.ParseConfig], so
+// its syntax is not parsed again. The file must have no syntax errors.
+func (p *TerramateParser) AddParsedFile(name string, file *hcl.File) error {
+	if err := p.AddFileContent(name, file.Bytes); err != nil {
+		return err
+	}
+	p.preparsed[name] = file
+	return nil
+}
+
 // ParseConfig parses and checks the schema of previously added files and
 // return either a Config or an error.
 func (p *TerramateParser) ParseConfig() (Config, error) {
@@ -427,7 +488,10 @@ func (p *TerramateParser) ParsedBodies() map[string]*hclsyntax.Body {
 	parsed := make(map[string]*hclsyntax.Body)
 	bodyMap := p.hclparser.Files()
 	for _, filename := range p.internalParsedFiles() {
-		hclfile := bodyMap[filename]
+		hclfile, ok := p.preparsed[filename]
+		if !ok {
+			hclfile = bodyMap[filename]
+		}
 		// A cast error here would be a severe programming error on Terramate
 		// side, so we are by design allowing the cast to panic
 		parsed[filename] = hclfile.Body.(*hclsyntax.Body)
@@ -477,6 +541,10 @@ func (p *TerramateParser) mergeConfig() error {
 func (p *TerramateParser) parseSyntax() error {
 	errs := errors.L()
 	for _, name := range p.sortedFilenames() {
+		if _, ok := p.preparsed[name]; ok {
+			p.addParsedFile(p.dir, internal, name)
+			continue
+		}
 		data := p.files[name]
 		_, diags := p.hclparser.ParseHCL(data, name)
 		if diags.HasErrors() {
@@ -759,12 +827,18 @@ func (c Config) HasRunEnv() bool {
 		c.Terramate.Config.Run.Env != nil
 }
 
+// HasEnv returns true if the config has a top-level env block defined.
+func (c Config) HasEnv() bool {
+	return c.Env != nil
+}
+
 // AbsDir returns the absolute path of the configuration directory.
 func (c Config) AbsDir() string { return c.absdir }
 
 // IsEmpty returns true if the config is empty, false otherwise.
 func (c Config) IsEmpty() bool {
 	return c.Stack == nil && c.Terramate == nil &&
+		c.Env == nil &&
 		c.Vendor == nil && len(c.Asserts) == 0 &&
 		len(c.Globals) == 0 &&
 		len(c.Generate.Files) == 0 && len(c.Generate.HCLs) == 0
@@ -937,6 +1011,11 @@ func parseGenerateFileBlock(block *ast.Block) (GenFileBlock, error) {
 		}
 	}
 
+	var format string
+	if formatAttr, ok := block.Body.Attributes["format"]; ok {
+		format = hcl.ExprAsKeyword(formatAttr.Expr)
+	}
+
 	mergedLets := ast.MergedLabelBlocks{}
 	for labelType, mergedBlock := range letsConfig.MergedLabelBlocks {
 		if labelType.Type == "lets" {
@@ -961,6 +1040,11 @@ func parseGenerateFileBlock(block *ast.Block) (GenFileBlock, error) {
 		Lets:      lets,
 		Asserts:   asserts,
 		Content:   block.Body.Attributes["content"],
+		Template:  block.Body.Attributes["template"],
+		Vars:      block.Body.Attributes["vars"],
+		Format:    format,
+		Indent:    block.Body.Attributes["indent"],
+		Header:    block.Body.Attributes["header"],
 		Condition: block.Body.Attributes["condition"],
 		Context:   context,
 	}, nil
@@ -1074,7 +1158,27 @@ func validateGenerateFileBlock(block *ast.Block) error {
 		Attributes: []hcl.AttributeSchema{
 			{
 				Name:     "content",
-				Required: true,
+				Required: false,
+			},
+			{
+				Name:     "template",
+				Required: false,
+			},
+			{
+				Name:     "vars",
+				Required: false,
+			},
+			{
+				Name:     "format",
+				Required: false,
+			},
+			{
+				Name:     "indent",
+				Required: false,
+			},
+			{
+				Name:     "header",
+				Required: false,
 			},
 			{
 				Name:     "condition",
@@ -1101,6 +1205,39 @@ func validateGenerateFileBlock(block *ast.Block) error {
 	if diags.HasErrors() {
 		errs.Append(errors.E(ErrTerramateSchema, diags))
 	}
+
+	attrs := block.Body.Attributes
+	_, hasContent := attrs["content"]
+	template, hasTemplate := attrs["template"]
+	switch {
+	case hasContent && hasTemplate:
+		errs.Append(errors.E(ErrTerramateSchema, template.NameRange,
+			"generate_file must define either content or template, not both"))
+	case !hasContent && !hasTemplate:
+		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"generate_file must define either content or template"))
+	}
+	if vars, ok := attrs["vars"]; ok && !hasTemplate {
+		errs.Append(errors.E(ErrTerramateSchema, vars.NameRange,
+			"generate_file.vars requires the template attribute"))
+	}
+	format, hasFormat := attrs["format"]
+	if hasFormat {
+		if !hasContent {
+			errs.Append(errors.E(ErrTerramateSchema, format.NameRange,
+				"generate_file.format requires the content attribute"))
+		}
+		f := hcl.ExprAsKeyword(format.Expr)
+		if f != "yaml" && f != "toml" && f != "json" {
+			errs.Append(errors.E(ErrTerramateSchema, format.Expr.Range(),
+				"generate_file.format supported values are \"yaml\", \"toml\" and \"json\""+
+					" but given %q", f))
+		}
+	}
+	if indent, ok := attrs["indent"]; ok && !hasFormat {
+		errs.Append(errors.E(ErrTerramateSchema, indent.NameRange,
+			"generate_file.indent requires the format attribute"))
+	}
 	err := errs.AsError()
 	if err != nil {
 		return err
@@ -1416,7 +1553,7 @@ func parseRootConfig(cfg *RootConfig, block *ast.MergedBlock) error {
 		))
 	}
 
-	errs.AppendWrap(ErrTerramateSchema, block.ValidateSubBlocks("git", "run"))
+	errs.AppendWrap(ErrTerramateSchema, block.ValidateSubBlocks("git", "run", "generate"))
 
 	gitBlock, ok := block.Blocks[ast.NewEmptyLabelBlockType("git")]
 	if ok {
@@ -1442,6 +1579,65 @@ func parseRootConfig(cfg *RootConfig, block *ast.MergedBlock) error {
 		errs.Append(parseRunConfig(cfg.Run, runBlock))
 	}
 
+	generateBlock, ok := block.Blocks[ast.NewEmptyLabelBlockType("generate")]
+	if ok {
+		logger.Trace().Msg("Type is 'generate'")
+
+		cfg.Generate = &CodeGenConfig{}
+
+		logger.Trace().Msg("Parse generate config.")
+
+		errs.Append(parseCodeGenConfig(cfg.Generate, generateBlock))
+	}
+
+	return errs.AsError()
+}
+
+func parseCodeGenConfig(cfg *CodeGenConfig, generateBlock *ast.MergedBlock) error {
+	errs := errors.L()
+	errs.AppendWrap(ErrTerramateSchema, generateBlock.ValidateSubBlocks())
+
+	for _, attr := range generateBlock.Attributes.SortedList() {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			errs.Append(errors.E(diags,
+				"failed to evaluate terramate.config.generate.%s attribute", attr.Name,
+			))
+			continue
+		}
+
+		switch attr.Name {
+		case "parallel":
+			parallel, err := parseIntAttr(attr, value, "terramate.config.generate.parallel")
+			if err != nil {
+				errs.Append(err)
+				continue
+			}
+			if parallel < 0 {
+				errs.Append(attrErr(attr,
+					"terramate.config.generate.parallel must not be negative"))
+				continue
+			}
+			cfg.Parallel = parallel
+		case "globals_cache":
+			if value.Type() != cty.Bool {
+				errs.Append(attrErr(attr,
+					"terramate.config.generate.globals_cache is not a bool but %q",
+					value.Type().FriendlyName(),
+				))
+				continue
+			}
+			cfg.GlobalsCache = value.True()
+		default:
+			errs.Append(errors.E(
+				ErrTerramateSchema,
+				attr.NameRange,
+				"unrecognized attribute terramate.config.generate.%s",
+				attr.Name,
+			))
+		}
+	}
+
 	return errs.AsError()
 }
 
@@ -1474,13 +1670,20 @@ func parseRunConfig(runCfg *RunConfig, runBlock *ast.MergedBlock) error {
 				continue
 			}
 			runCfg.CheckGenCode = value.True()
+		case "timeout":
+			timeout, err := parseDurationAttr(attr, value, "terramate.config.run.timeout")
+			if err != nil {
+				errs.Append(err)
+				continue
+			}
+			runCfg.Timeout = timeout
 		default:
 			errs.Append(errors.E("unrecognized attribute terramate.config.run.env.%s",
 				attr.Name))
 		}
 	}
 
-	errs.AppendWrap(ErrTerramateSchema, runBlock.ValidateSubBlocks("env"))
+	errs.AppendWrap(ErrTerramateSchema, runBlock.ValidateSubBlocks("env", "retry"))
 
 	block, ok := runBlock.Blocks[ast.NewEmptyLabelBlockType("env")]
 	if ok {
@@ -1488,9 +1691,107 @@ func parseRunConfig(runCfg *RunConfig, runBlock *ast.MergedBlock) error {
 		errs.Append(parseRunEnv(runCfg.Env, block))
 	}
 
+	block, ok = runBlock.Blocks[ast.NewEmptyLabelBlockType("retry")]
+	if ok {
+		runCfg.Retry = &RunRetryConfig{}
+		errs.Append(parseRunRetry(runCfg.Retry, block))
+	}
+
 	return errs.AsError()
 }
 
+func parseRunRetry(retry *RunRetryConfig, retryBlock *ast.MergedBlock) error {
+	errs := errors.L()
+	errs.AppendWrap(ErrTerramateSchema, retryBlock.ValidateSubBlocks())
+
+	for _, attr := range retryBlock.Attributes.SortedList() {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			errs.Append(errors.E(diags,
+				"failed to evaluate terramate.config.run.retry.%s attribute", attr.Name,
+			))
+			continue
+		}
+
+		switch attr.Name {
+		case "attempts":
+			attempts, err := parseIntAttr(attr, value, "terramate.config.run.retry.attempts")
+			if err != nil {
+				errs.Append(err)
+				continue
+			}
+			if attempts < 0 {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.retry.attempts must not be negative"))
+				continue
+			}
+			retry.Attempts = attempts
+		case "exit_codes":
+			if !value.Type().IsListType() && !value.Type().IsTupleType() {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.retry.exit_codes is not a list of numbers but %q",
+					value.Type().FriendlyName(),
+				))
+				continue
+			}
+			var codes []int
+			it := value.ElementIterator()
+			for it.Next() {
+				_, elem := it.Element()
+				code, err := parseIntAttr(attr, elem, "terramate.config.run.retry.exit_codes element")
+				if err != nil {
+					errs.Append(err)
+					continue
+				}
+				codes = append(codes, code)
+			}
+			retry.ExitCodes = codes
+		case "backoff":
+			backoff, err := parseDurationAttr(attr, value, "terramate.config.run.retry.backoff")
+			if err != nil {
+				errs.Append(err)
+				continue
+			}
+			retry.Backoff = backoff
+		default:
+			errs.Append(errors.E(
+				ErrTerramateSchema,
+				attr.NameRange,
+				"unrecognized attribute terramate.config.run.retry.%s",
+				attr.Name,
+			))
+		}
+	}
+
+	return errs.AsError()
+}
+
+func parseDurationAttr(attr ast.Attribute, value cty.Value, name string) (time.Duration, error) {
+	if value.Type() != cty.String {
+		return 0, attrErr(attr, "%s is not a string but %q", name, value.Type().FriendlyName())
+	}
+	d, err := time.ParseDuration(value.AsString())
+	if err != nil {
+		return 0, attrErr(attr, "%s is not a valid duration: %v", name, err)
+	}
+	if d < 0 {
+		return 0, attrErr(attr, "%s must not be negative", name)
+	}
+	return d, nil
+}
+
+func parseIntAttr(attr ast.Attribute, value cty.Value, name string) (int, error) {
+	if value.Type() != cty.Number {
+		return 0, attrErr(attr, "%s is not a number but %q", name, value.Type().FriendlyName())
+	}
+	bf := value.AsBigFloat()
+	if !bf.IsInt() {
+		return 0, attrErr(attr, "%s is not an integer", name)
+	}
+	i, _ := bf.Int64()
+	return int(i), nil
+}
+
 func parseRunEnv(runEnv *RunEnv, envBlock *ast.MergedBlock) error {
 	if len(envBlock.Attributes) > 0 {
 		runEnv.Attributes = envBlock.Attributes
@@ -1703,6 +2004,14 @@ func (p *TerramateParser) parseTerramateSchema() (Config, error) {
 		}
 	}
 
+	envBlock, ok := rawconfig.MergedBlocks["env"]
+	if ok {
+		logger.Trace().Msg("Found \"env\" block")
+
+		config.Env = &RunEnv{}
+		errs.Append(parseRunEnv(config.Env, envBlock))
+	}
+
 	if foundVendor {
 		logger.Debug().Msg("parsing manifest")
 
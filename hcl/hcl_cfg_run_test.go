@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -289,6 +290,117 @@ func TestHCLParserConfigRun(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "run.timeout and run.retry defined",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      timeout = "10m"
+						      retry {
+						        attempts   = 3
+						        exit_codes = [1, 42]
+						        backoff    = "5s"
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Run: &hcl.RunConfig{
+								CheckGenCode: true,
+								Timeout:      10 * time.Minute,
+								Retry: &hcl.RunRetryConfig{
+									Attempts:  3,
+									ExitCodes: []int{1, 42},
+									Backoff:   5 * time.Second,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "run.timeout must be a valid duration",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      timeout = "ten minutes"
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("cfg.tm", Start(5, 23, 74), End(5, 36, 87)),
+					),
+				},
+			},
+		},
+		{
+			name: "run.retry.exit_codes must be a list of integers",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      retry {
+						        exit_codes = "1"
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "unrecognized attribute on run.retry fails",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      retry {
+						        unknown = 1
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
 		{
 			name: "attrs on run.env in single block/file",
 			input: []cfgfile{
@@ -660,3 +772,104 @@ func TestHCLParserConfigRun(t *testing.T) {
 		testParser(t, tc)
 	}
 }
+
+func TestHCLParserEnv(t *testing.T) {
+	envCfg := func(rawattributes string) hcl.Config {
+		rootdir := t.TempDir()
+		filepath := filepath.Join(rootdir, "test_file.hcl")
+		assert.NoError(t, os.WriteFile(filepath, []byte(rawattributes), 0700))
+
+		parser := hclparse.NewParser()
+		res, diags := parser.ParseHCLFile(filepath)
+		if diags.HasErrors() {
+			t.Fatalf("test case provided invalid hcl, error: %v hcl:\n%s", diags, rawattributes)
+		}
+
+		body := res.Body.(*hclsyntax.Body)
+		attrs := make(ast.Attributes)
+
+		for name, attr := range body.Attributes {
+			attrs[name] = ast.NewAttribute(rootdir, attr.AsHCLAttribute())
+		}
+
+		return hcl.Config{
+			Env: &hcl.RunEnv{
+				Attributes: attrs,
+			},
+		}
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "empty env",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body:     `env {}`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Env: &hcl.RunEnv{},
+				},
+			},
+		},
+		{
+			name: "env attributes merged across files",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `env {
+					  A = "a"
+					}`,
+				},
+				{
+					filename: "cfg2.tm",
+					body: `env {
+					  B = global.b
+					}`,
+				},
+			},
+			want: want{
+				config: envCfg(`
+					A = "a"
+					B = global.b
+				`),
+			},
+		},
+		{
+			name: "env with labels fails",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `env "label" {
+					  A = "a"
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "env with blocks fails",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `env {
+					  sub {}
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+	} {
+		testParser(t, tc)
+	}
+}
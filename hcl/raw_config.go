@@ -40,6 +40,7 @@ func NewTopLevelRawConfig() RawConfig {
 	return NewCustomRawConfig(map[string]mergeHandler{
 		"terramate":     (*RawConfig).mergeBlock,
 		"globals":       (*RawConfig).mergeLabeledBlock,
+		"env":           (*RawConfig).mergeBlock,
 		"stack":         (*RawConfig).addBlock,
 		"vendor":        (*RawConfig).addBlock,
 		"generate_file": (*RawConfig).addBlock,
@@ -103,7 +104,8 @@ func (cfg *RawConfig) addBlock(block *ast.Block) error {
 
 func (cfg *RawConfig) mergeBlock(block *ast.Block) error {
 	if len(block.Labels) > 0 {
-		return errors.E("block type %q does not support labels", block.Type)
+		return errors.E(ErrTerramateSchema, block.LabelRanges(),
+			"block type %q does not support labels", block.Type)
 	}
 
 	if other, ok := cfg.MergedBlocks[block.Type]; ok {
@@ -0,0 +1,79 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+	"github.com/terramate-io/terramate/stack"
+	"go.lsp.dev/jsonrpc2"
+	lsp "go.lsp.dev/protocol"
+)
+
+func (s *Server) handleCodeAction(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.CodeActionParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
+
+	fname := params.TextDocument.URI.Filename()
+	content, err := s.documentContent(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("reading document")
+		return reply(ctx, nil, nil)
+	}
+
+	actions := codeActions(params.TextDocument.URI, fname, content, log)
+	return reply(ctx, actions, nil)
+}
+
+// codeActions returns the quick fixes available for the document:
+//   - Adding a stack.id to a stack block without one.
+//   - Formatting the document.
+func codeActions(docURI lsp.DocumentURI, fname, content string, log zerolog.Logger) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	replaceAll := func(title, newText string) lsp.CodeAction {
+		return lsp.CodeAction{
+			Title: title,
+			Kind:  lsp.QuickFix,
+			Edit: &lsp.WorkspaceEdit{
+				Changes: map[lsp.DocumentURI][]lsp.TextEdit{
+					docURI: {
+						{
+							Range:   documentRange(content),
+							NewText: newText,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	if block, ok := stackBlock(fname, []byte(content)); ok {
+		if _, hasID := block.Body.Attributes["id"]; !hasID {
+			_, updated, err := stack.SetStackID(fname, []byte(content))
+			if err != nil {
+				log.Debug().Err(err).Msg("setting stack.id")
+			} else {
+				actions = append(actions, replaceAll("Add missing stack.id", string(updated)))
+			}
+		}
+	}
+
+	edits, err := formatEdits(fname, content)
+	if err != nil {
+		log.Debug().Err(err).Msg("formatting document")
+	} else if len(edits) > 0 {
+		actions = append(actions, replaceAll("Format file", edits[0].NewText))
+	}
+	return actions
+}
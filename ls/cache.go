@@ -0,0 +1,135 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog/log"
+)
+
+// dirCache is the cached model of a checked directory: its Terramate files
+// and their parsed syntax. It's kept until the directory is invalidated.
+type dirCache struct {
+	// files are the Terramate files of the directory on disk.
+	files []string
+
+	// parsed are the parsed files, keyed by filename.
+	parsed map[string]parsedDoc
+}
+
+// parsedDoc is the parse of a document with the given content.
+type parsedDoc struct {
+	content string
+	file    *hhcl.File
+	diags   hhcl.Diagnostics
+}
+
+// dirCache returns the cached model of the directory, listing its files if
+// it's not cached.
+func (s *Server) dirCache(dir string) (*dirCache, error) {
+	if cache, ok := s.dirs[dir]; ok {
+		return cache, nil
+	}
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	cache := &dirCache{
+		files:  files,
+		parsed: map[string]parsedDoc{},
+	}
+	s.dirs[dir] = cache
+	return cache, nil
+}
+
+// dropDirCache drops the cached model of the directory and of its
+// subdirectories.
+func (s *Server) dropDirCache(dir string) {
+	for cached := range s.dirs {
+		if isInsideDir(dir, cached) {
+			delete(s.dirs, cached)
+		}
+	}
+}
+
+// forgetDocument drops the cached parse of the document, which may have
+// unsaved content.
+func (s *Server) forgetDocument(fname string) {
+	if cache, ok := s.dirs[filepath.Dir(fname)]; ok {
+		delete(cache.parsed, fname)
+	}
+}
+
+// content returns the content of the file: the given content of the current
+// file, the unsaved content of the opened documents or else the content last
+// parsed, which is only read from disk if the file wasn't parsed yet.
+func (c *dirCache) content(s *Server, fname, currentFile, currentContent string) (string, error) {
+	if fname == currentFile {
+		return currentContent, nil
+	}
+	if content, ok := s.documents[fname]; ok {
+		return content, nil
+	}
+	if parsed, ok := c.parsed[fname]; ok {
+		return parsed.content, nil
+	}
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// parse returns the parse of the file content, parsing it only if it
+// changed since it was last parsed.
+func (c *dirCache) parse(fname, content string) parsedDoc {
+	if parsed, ok := c.parsed[fname]; ok && parsed.content == content {
+		return parsed
+	}
+
+	log.Trace().Str("file", fname).Msg("parsing changed file")
+
+	file, diags := hclsyntax.ParseConfig([]byte(content), fname, hhcl.InitialPos)
+	parsed := parsedDoc{
+		content: content,
+		file:    file,
+		diags:   diags,
+	}
+	c.parsed[fname] = parsed
+	return parsed
+}
+
+// listFiles returns the Terramate files of the directory.
+func listFiles(dir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Trace().Msg("looking for Terramate files")
+
+	files := []string{}
+	for _, dirEntry := range dirEntries {
+		logger := log.With().
+			Str("entryName", dirEntry.Name()).
+			Logger()
+
+		if dirEntry.IsDir() {
+			logger.Trace().Msg("ignoring dir")
+			continue
+		}
+
+		filename := dirEntry.Name()
+		if strings.HasSuffix(filename, ".tm") || strings.HasSuffix(filename, ".tm.hcl") {
+			files = append(files, filepath.Join(dir, filename))
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog"
+	"github.com/terramate-io/terramate/stdlib"
+	"github.com/zclconf/go-cty/cty"
+	"go.lsp.dev/jsonrpc2"
+	lsp "go.lsp.dev/protocol"
+)
+
+// namespaces are the variable namespaces completed at the start of a
+// traversal.
+var namespaces = []string{"global", "let", "terramate"}
+
+func (s *Server) handleCompletion(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.CompletionParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
+
+	fname := params.TextDocument.URI.Filename()
+	content, err := s.documentContent(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("reading document")
+		return reply(ctx, nil, nil)
+	}
+
+	items := s.completions(fname, content, params.Position, log)
+	return reply(ctx, &lsp.CompletionList{Items: items}, nil)
+}
+
+// completions returns the completion items for the traversal being typed at
+// the given position, eg.: `global.a.`, `terramate.stack.na` or `tm_up`.
+func (s *Server) completions(fname, content string, pos lsp.Position, log zerolog.Logger) []lsp.CompletionItem {
+	offset := offsetAt(content, pos)
+	start := offset
+	for start > 0 && isTraversalChar(content[start-1]) {
+		start--
+	}
+
+	parts := strings.Split(content[start:offset], ".")
+	partial := parts[len(parts)-1]
+	if len(parts) == 1 {
+		return filterCompletions(rootCompletions(fname, s.workspace), partial)
+	}
+
+	ns, path := parts[0], parts[1:len(parts)-1]
+	switch ns {
+	case "let":
+		if len(path) > 0 {
+			return nil
+		}
+		// the incomplete traversal is replaced so the enclosing block can be
+		// parsed.
+		patched := content[:start] + "null" + content[offset:]
+		return filterCompletions(letCompletions(fname, patched, start), partial)
+	case "global", "terramate":
+		sc, err := s.loadScope(fname)
+		if err != nil {
+			log.Debug().Err(err).Msg("loading scope of document")
+			return nil
+		}
+		val, _ := sc.namespace(ns)
+		val, ok := lookupValue(val, path)
+		if !ok {
+			return nil
+		}
+		return filterCompletions(memberCompletions(val), partial)
+	}
+	return nil
+}
+
+func isTraversalChar(c byte) bool {
+	return c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '.'
+}
+
+func filterCompletions(items []lsp.CompletionItem, prefix string) []lsp.CompletionItem {
+	filtered := []lsp.CompletionItem{}
+	for _, item := range items {
+		if strings.HasPrefix(item.Label, prefix) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// rootCompletions returns the variable namespaces and the Terramate
+// functions.
+func rootCompletions(fname, workspace string) []lsp.CompletionItem {
+	items := []lsp.CompletionItem{}
+	for _, ns := range namespaces {
+		items = append(items, lsp.CompletionItem{
+			Label:  ns,
+			Kind:   lsp.CompletionItemKindModule,
+			Detail: "namespace",
+		})
+	}
+
+	// the functions are bound to a base directory that must exist.
+	basedir := filepath.Dir(fname)
+	if st, err := os.Stat(basedir); err != nil || !st.IsDir() {
+		basedir = workspace
+		if st, err := os.Stat(basedir); err != nil || !st.IsDir() {
+			return items
+		}
+	}
+
+	funcs := stdlib.Functions(basedir)
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fn := funcs[name]
+		params := []string{}
+		for _, param := range fn.Params() {
+			params = append(params, param.Name)
+		}
+		if varParam := fn.VarParam(); varParam != nil {
+			params = append(params, "..."+varParam.Name)
+		}
+		items = append(items, lsp.CompletionItem{
+			Label:  name,
+			Kind:   lsp.CompletionItemKindFunction,
+			Detail: name + "(" + strings.Join(params, ", ") + ")",
+		})
+	}
+	return items
+}
+
+// memberCompletions returns the attributes of the object or map value.
+func memberCompletions(val cty.Value) []lsp.CompletionItem {
+	if val.IsNull() || !val.IsKnown() {
+		return nil
+	}
+
+	members := map[string]cty.Value{}
+	typ := val.Type()
+	switch {
+	case typ.IsObjectType():
+		for name := range typ.AttributeTypes() {
+			members[name] = val.GetAttr(name)
+		}
+	case typ.IsMapType():
+		for it := val.ElementIterator(); it.Next(); {
+			k, v := it.Element()
+			members[k.AsString()] = v
+		}
+	default:
+		return nil
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := []lsp.CompletionItem{}
+	for _, name := range names {
+		items = append(items, lsp.CompletionItem{
+			Label:  name,
+			Kind:   lsp.CompletionItemKindField,
+			Detail: members[name].Type().FriendlyName(),
+		})
+	}
+	return items
+}
+
+// letCompletions returns the names defined in the lets blocks of the
+// generate_hcl or generate_file block enclosing the offset.
+func letCompletions(fname, content string, offset int) []lsp.CompletionItem {
+	file, _ := hclsyntax.ParseConfig([]byte(content), fname, hhcl.InitialPos)
+	if file == nil {
+		return nil
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+
+	names := map[string]bool{}
+	for _, block := range body.Blocks {
+		if block.Type != "generate_hcl" && block.Type != "generate_file" {
+			continue
+		}
+		rng := block.Range()
+		if offset < rng.Start.Byte || offset > rng.End.Byte {
+			continue
+		}
+		for _, lets := range block.Body.Blocks {
+			if lets.Type != "lets" {
+				continue
+			}
+			for name := range lets.Body.Attributes {
+				names[name] = true
+			}
+			for _, mapBlock := range lets.Body.Blocks {
+				if mapBlock.Type == "map" && len(mapBlock.Labels) == 1 {
+					names[mapBlock.Labels[0]] = true
+				}
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	items := []lsp.CompletionItem{}
+	for _, name := range sorted {
+		items = append(items, lsp.CompletionItem{
+			Label:  name,
+			Kind:   lsp.CompletionItemKindVariable,
+			Detail: "let",
+		})
+	}
+	return items
+}
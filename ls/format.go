@@ -0,0 +1,173 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/fmt"
+	"go.lsp.dev/jsonrpc2"
+	lsp "go.lsp.dev/protocol"
+)
+
+func (s *Server) handleFormatting(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.DocumentFormattingParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
+
+	fname := params.TextDocument.URI.Filename()
+	content, err := s.documentContent(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("reading document")
+		return reply(ctx, nil, nil)
+	}
+
+	edits, err := formatEdits(fname, content)
+	if err != nil {
+		// syntax errors are already reported as diagnostics.
+		log.Debug().Err(err).Msg("formatting document")
+		return reply(ctx, nil, nil)
+	}
+	return reply(ctx, edits, nil)
+}
+
+func (s *Server) handleRangeFormatting(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.DocumentRangeFormattingParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
+
+	fname := params.TextDocument.URI.Filename()
+	content, err := s.documentContent(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("reading document")
+		return reply(ctx, nil, nil)
+	}
+
+	edits, err := formatRangeEdits(fname, content, params.Range)
+	if err != nil {
+		log.Debug().Err(err).Msg("formatting document range")
+		return reply(ctx, nil, nil)
+	}
+	return reply(ctx, edits, nil)
+}
+
+// formatEdits returns the edits needed to format the whole document. There
+// are no edits if the document is already formatted.
+func formatEdits(fname, content string) ([]lsp.TextEdit, error) {
+	formatted, err := fmt.Format(content, fname)
+	if err != nil {
+		return nil, err
+	}
+	if formatted == content {
+		return []lsp.TextEdit{}, nil
+	}
+	return []lsp.TextEdit{
+		{
+			Range:   documentRange(content),
+			NewText: formatted,
+		},
+	}, nil
+}
+
+// formatRangeEdits returns the edits needed to format the top-level
+// attributes and blocks of the document overlapping the given range. Only
+// whole items are formatted, as a partial block is not valid HCL.
+func formatRangeEdits(fname, content string, rng lsp.Range) ([]lsp.TextEdit, error) {
+	file, diags := hclsyntax.ParseConfig([]byte(content), fname, hhcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, errors.E(fmt.ErrHCLSyntax, diags)
+	}
+	body := file.Body.(*hclsyntax.Body)
+
+	start, end := offsetAt(content, rng.Start), offsetAt(content, rng.End)
+	spanStart, spanEnd := -1, -1
+	extend := func(itemRng hhcl.Range) {
+		if itemRng.End.Byte < start || itemRng.Start.Byte > end {
+			return
+		}
+		if spanStart == -1 || itemRng.Start.Byte < spanStart {
+			spanStart = itemRng.Start.Byte
+		}
+		if itemRng.End.Byte > spanEnd {
+			spanEnd = itemRng.End.Byte
+		}
+	}
+	for _, attr := range body.Attributes {
+		extend(attr.Range())
+	}
+	for _, block := range body.Blocks {
+		extend(block.Range())
+	}
+	if spanStart == -1 {
+		return []lsp.TextEdit{}, nil
+	}
+
+	// the span is extended to whole lines.
+	spanStart = strings.LastIndexByte(content[:spanStart], '\n') + 1
+	if i := strings.IndexByte(content[spanEnd:], '\n'); i == -1 {
+		spanEnd = len(content)
+	} else {
+		spanEnd += i + 1
+	}
+
+	original := content[spanStart:spanEnd]
+	formatted, err := fmt.Format(original, fname)
+	if err != nil {
+		return nil, err
+	}
+	if formatted == original {
+		return []lsp.TextEdit{}, nil
+	}
+	return []lsp.TextEdit{
+		{
+			Range: lsp.Range{
+				Start: positionAt(content, spanStart),
+				End:   positionAt(content, spanEnd),
+			},
+			NewText: formatted,
+		},
+	}, nil
+}
+
+// documentRange returns the range covering the whole content.
+func documentRange(content string) lsp.Range {
+	return lsp.Range{
+		End: positionAt(content, len(content)),
+	}
+}
+
+// positionAt returns the LSP position of the byte offset in content. It is
+// the inverse of offsetAt.
+func positionAt(content string, offset int) lsp.Position {
+	pos := lsp.Position{}
+	lineStart := strings.LastIndexByte(content[:offset], '\n') + 1
+	pos.Line = uint32(strings.Count(content[:lineStart], "\n"))
+	for _, r := range content[lineStart:offset] {
+		pos.Character++
+		if r >= 0x10000 {
+			pos.Character++
+		}
+	}
+	return pos
+}
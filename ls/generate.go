@@ -0,0 +1,75 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+)
+
+// defaultVendorDir is the vendor directory used by tm_vendor when the project
+// doesn't configure one.
+const defaultVendorDir = "/modules"
+
+// validateGenerate validates the code generation of the stacks affected by
+// the configuration of dir, which are the stacks at dir or below it. The
+// errors of each stack are kept until the stack is validated again.
+func (s *Server) validateGenerate(dir string) {
+	root, err := s.loadRoot(dir)
+	if err != nil || !isInsideDir(root.HostDir(), dir) {
+		return
+	}
+
+	cfgdir := project.PrjAbsPath(root.HostDir(), dir)
+	for stackdir := range s.genErrors {
+		if isInsideDir(filepath.FromSlash(cfgdir.String()), filepath.FromSlash(stackdir.String())) {
+			delete(s.genErrors, stackdir)
+		}
+	}
+
+	tree, ok := root.Lookup(cfgdir)
+	if !ok {
+		return
+	}
+
+	vendorDir := project.NewPath(defaultVendorDir)
+	if vendor := root.Tree().Node.Vendor; vendor != nil && vendor.Dir != "" {
+		vendorDir = project.NewPath(vendor.Dir)
+	}
+
+	for _, stackTree := range tree.Stacks() {
+		st, err := config.NewStackFromHCL(root.HostDir(), stackTree.Node)
+		if err != nil {
+			s.genErrors[stackTree.Dir()] = err
+			continue
+		}
+
+		log.Debug().Stringer("stack", st.Dir).Msg("validating code generation")
+
+		if err := generate.Validate(root, st, vendorDir); err != nil {
+			s.genErrors[st.Dir] = err
+		}
+	}
+}
+
+// generateErrors returns the code generation errors of all validated stacks.
+func (s *Server) generateErrors() error {
+	stackdirs := make([]string, 0, len(s.genErrors))
+	for stackdir := range s.genErrors {
+		stackdirs = append(stackdirs, stackdir.String())
+	}
+	sort.Strings(stackdirs)
+
+	errs := errors.L()
+	for _, stackdir := range stackdirs {
+		errs.Append(s.genErrors[project.NewPath(stackdir)])
+	}
+	return errs.AsError()
+}
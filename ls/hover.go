@@ -0,0 +1,266 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rs/zerolog"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/zclconf/go-cty/cty"
+	"go.lsp.dev/jsonrpc2"
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func (s *Server) handleHover(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.HoverParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
+
+	fname := params.TextDocument.URI.Filename()
+	content, err := s.documentContent(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("reading document")
+		return reply(ctx, nil, nil)
+	}
+
+	traversal, ok := traversalAt(fname, content, params.Position)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	sc, err := s.loadScope(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("loading scope of document")
+		return reply(ctx, nil, nil)
+	}
+
+	var value string
+	switch traversal.RootName() {
+	case "global":
+		value = hoverGlobal(sc, traversal)
+	case "terramate":
+		value = hoverMetadata(sc, traversal)
+	default:
+		return reply(ctx, nil, nil)
+	}
+
+	rng := toLSPRange(traversal.SourceRange())
+	return reply(ctx, &lsp.Hover{
+		Contents: lsp.MarkupContent{
+			Kind:  lsp.Markdown,
+			Value: value,
+		},
+		Range: &rng,
+	}, nil)
+}
+
+func (s *Server) handleDefinition(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.DefinitionParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
+
+	fname := params.TextDocument.URI.Filename()
+	content, err := s.documentContent(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("reading document")
+		return reply(ctx, nil, nil)
+	}
+
+	traversal, ok := traversalAt(fname, content, params.Position)
+	if !ok || traversal.RootName() != "global" {
+		return reply(ctx, nil, nil)
+	}
+
+	sc, err := s.loadScope(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("loading scope of document")
+		return reply(ctx, nil, nil)
+	}
+
+	defs, err := sc.globalDefinitions(traversalPath(traversal))
+	if err != nil || len(defs) == 0 {
+		log.Debug().Err(err).Msg("no definition found")
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, []lsp.Location{toLSPLocation(defs[0].Origin)}, nil)
+}
+
+// hoverGlobal returns the markdown describing the global accessed by the
+// traversal: its evaluated value, where it is defined and which definitions
+// it overrides.
+func hoverGlobal(sc scope, traversal hhcl.Traversal) string {
+	path := traversalPath(traversal)
+	name := strings.Join(append([]string{"global"}, path...), ".")
+
+	var b strings.Builder
+	report := sc.evalGlobals()
+	globals := cty.ObjectVal(report.Globals.AsValueMap())
+	if val, ok := lookupValue(globals, path); ok {
+		fmt.Fprintf(&b, "```hcl\n%s = %s\n```\n", name, formatValue(val))
+	} else if err := report.AsError(); err != nil {
+		fmt.Fprintf(&b, "`%s` failed to evaluate: %s\n", name, err)
+	} else {
+		fmt.Fprintf(&b, "`%s` is not defined\n", name)
+	}
+
+	defs, err := sc.globalDefinitions(path)
+	if err == nil && len(defs) > 0 {
+		fmt.Fprintf(&b, "\nDefined at `%s`\n", defs[0].Origin)
+		for _, def := range defs[1:] {
+			fmt.Fprintf(&b, "\nOverrides `%s`\n", def.Origin)
+		}
+	}
+
+	if sc.stack != nil {
+		fmt.Fprintf(&b, "\nEvaluated for stack `%s`\n", sc.stack.Dir)
+	}
+	return b.String()
+}
+
+// hoverMetadata returns the markdown describing the Terramate metadata
+// accessed by the traversal.
+func hoverMetadata(sc scope, traversal hhcl.Traversal) string {
+	path := traversalPath(traversal)
+	name := strings.Join(append([]string{"terramate"}, path...), ".")
+
+	val, ok := lookupValue(cty.ObjectVal(sc.runtime()), path)
+	if !ok {
+		return fmt.Sprintf("`%s` is not defined\n", name)
+	}
+	return fmt.Sprintf("```hcl\n%s = %s\n```\n", name, formatValue(val))
+}
+
+func formatValue(val cty.Value) string {
+	return strings.TrimSpace(string(hclwrite.Format(ast.TokensForValue(val).Bytes())))
+}
+
+// traversalAt returns the variable traversal at the given position of the
+// file content, truncated up to the element under the position. Eg.: for
+// `global.a.b` and the position over `a`, it returns `global.a`.
+func traversalAt(fname, content string, pos lsp.Position) (hhcl.Traversal, bool) {
+	file, _ := hclsyntax.ParseConfig([]byte(content), fname, hhcl.InitialPos)
+	if file == nil {
+		return nil, false
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, false
+	}
+
+	offset := offsetAt(content, pos)
+	contains := func(rng hhcl.Range) bool {
+		return rng.Start.Byte <= offset && offset <= rng.End.Byte
+	}
+
+	var found hhcl.Traversal
+	_ = hclsyntax.VisitAll(body, func(node hclsyntax.Node) hhcl.Diagnostics {
+		if expr, ok := node.(*hclsyntax.ScopeTraversalExpr); ok && contains(expr.SrcRange) {
+			found = expr.Traversal
+		}
+		return nil
+	})
+	if found == nil {
+		return nil, false
+	}
+
+	for i := 1; i < len(found); i++ {
+		if found[i].SourceRange().Start.Byte > offset {
+			return found[:i], true
+		}
+	}
+	return found, true
+}
+
+// traversalPath returns the attribute names accessed by the traversal,
+// excluding the root name. The path stops at the first element that is not
+// a literal attribute access.
+func traversalPath(traversal hhcl.Traversal) []string {
+	path := []string{}
+	for _, step := range traversal[1:] {
+		switch t := step.(type) {
+		case hhcl.TraverseAttr:
+			path = append(path, t.Name)
+		case hhcl.TraverseIndex:
+			if !t.Key.Type().Equals(cty.String) || !t.Key.IsKnown() || t.Key.IsNull() {
+				return path
+			}
+			path = append(path, t.Key.AsString())
+		default:
+			return path
+		}
+	}
+	return path
+}
+
+// offsetAt returns the byte offset of the LSP position in content. The LSP
+// position character is measured in UTF-16 code units.
+func offsetAt(content string, pos lsp.Position) int {
+	offset := 0
+	for line := uint32(0); line < pos.Line; line++ {
+		i := strings.IndexByte(content[offset:], '\n')
+		if i == -1 {
+			return len(content)
+		}
+		offset += i + 1
+	}
+
+	units := uint32(0)
+	for offset < len(content) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(content[offset:])
+		if r == '\n' {
+			break
+		}
+		units++
+		if r >= 0x10000 {
+			units++
+		}
+		offset += size
+	}
+	return offset
+}
+
+func toLSPRange(rng hhcl.Range) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{
+			Line:      uint32(rng.Start.Line) - 1,
+			Character: uint32(rng.Start.Column) - 1,
+		},
+		End: lsp.Position{
+			Line:      uint32(rng.End.Line) - 1,
+			Character: uint32(rng.End.Column) - 1,
+		},
+	}
+}
+
+func toLSPLocation(rng info.Range) lsp.Location {
+	return lsp.Location{
+		URI:   uri.File(rng.HostPath()),
+		Range: toLSPRange(rng.ToHCLRange()),
+	}
+}
@@ -7,17 +7,17 @@ package tmls
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 
+	hhcl "github.com/hashicorp/hcl/v2"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/project"
 	"go.lsp.dev/jsonrpc2"
 	lsp "go.lsp.dev/protocol"
 	"go.lsp.dev/uri"
@@ -29,6 +29,25 @@ type Server struct {
 	workspace string
 	handlers  handlers
 
+	// documents is the content of the documents opened on the editor,
+	// which may not be saved yet.
+	documents map[string]string
+
+	// root is the cached project configuration and invalidated is the set
+	// of directories which must be reloaded before its next use.
+	root        *config.Root
+	invalidated map[string]bool
+
+	// dirs is the cached model of the checked directories, dropped when the
+	// directory is invalidated.
+	dirs map[string]*dirCache
+
+	// genErrors are the code generation errors of each validated stack and
+	// published are the files outside of the checked directory which have
+	// diagnostics published.
+	genErrors map[project.Path]error
+	published map[string]bool
+
 	log zerolog.Logger
 }
 
@@ -50,8 +69,13 @@ func NewServer(conn jsonrpc2.Conn) *Server {
 // ServerWithLogger creates a new language server with a custom logger.
 func ServerWithLogger(conn jsonrpc2.Conn, l zerolog.Logger) *Server {
 	s := &Server{
-		conn: conn,
-		log:  l,
+		conn:        conn,
+		documents:   map[string]string{},
+		invalidated: map[string]bool{},
+		dirs:        map[string]*dirCache{},
+		genErrors:   map[project.Path]error{},
+		published:   map[string]bool{},
+		log:         l,
 	}
 	s.buildHandlers()
 	return s
@@ -59,12 +83,20 @@ func ServerWithLogger(conn jsonrpc2.Conn, l zerolog.Logger) *Server {
 
 func (s *Server) buildHandlers() {
 	s.handlers = map[string]handler{
-		lsp.MethodInitialize:             s.handleInitialize,
-		lsp.MethodInitialized:            s.handleInitialized,
-		lsp.MethodTextDocumentDidOpen:    s.handleDocumentOpen,
-		lsp.MethodTextDocumentDidChange:  s.handleDocumentChange,
-		lsp.MethodTextDocumentDidSave:    s.handleDocumentSaved,
-		lsp.MethodTextDocumentCompletion: s.handleCompletion,
+		lsp.MethodInitialize:                     s.handleInitialize,
+		lsp.MethodInitialized:                    s.handleInitialized,
+		lsp.MethodTextDocumentDidOpen:            s.handleDocumentOpen,
+		lsp.MethodTextDocumentDidChange:          s.handleDocumentChange,
+		lsp.MethodTextDocumentDidSave:            s.handleDocumentSaved,
+		lsp.MethodTextDocumentDidClose:           s.handleDocumentClose,
+		lsp.MethodWorkspaceDidChangeWatchedFiles: s.handleWatchedFilesChange,
+		lsp.MethodTextDocumentCompletion:         s.handleCompletion,
+		lsp.MethodTextDocumentHover:              s.handleHover,
+		lsp.MethodTextDocumentDefinition:         s.handleDefinition,
+		lsp.MethodTextDocumentFormatting:         s.handleFormatting,
+		lsp.MethodTextDocumentRangeFormatting:    s.handleRangeFormatting,
+		lsp.MethodTextDocumentDocumentSymbol:     s.handleDocumentSymbol,
+		lsp.MethodTextDocumentCodeAction:         s.handleCodeAction,
 	}
 }
 
@@ -111,17 +143,26 @@ func (s *Server) handleInitialize(
 	s.workspace = string(uri.New(params.RootURI).Filename())
 	err := reply(ctx, lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
-			CompletionProvider: &lsp.CompletionOptions{},
+			CompletionProvider: &lsp.CompletionOptions{
+				TriggerCharacters: []string{"."},
+			},
 
 			// if we support `goto` definition.
-			DefinitionProvider: false,
+			DefinitionProvider: true,
 
 			// If we support `hover` info.
-			HoverProvider: false,
+			HoverProvider: true,
+
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			DocumentSymbolProvider:          true,
+			CodeActionProvider: &lsp.CodeActionOptions{
+				CodeActionKinds: []lsp.CodeActionKind{lsp.QuickFix},
+			},
 
 			TextDocumentSync: lsp.TextDocumentSyncOptions{
-				// Send all file content on every change (can be optimized later).
-				Change: lsp.TextDocumentSyncKindFull,
+				// Send only the changed ranges of the document.
+				Change: lsp.TextDocumentSyncKindIncremental,
 
 				// if we want to be notified about open/close of Terramate files.
 				OpenClose: true,
@@ -175,8 +216,9 @@ func (s *Server) handleDocumentOpen(
 
 	fname := params.TextDocument.URI.Filename()
 	content := params.TextDocument.Text
+	s.documents[fname] = content
 
-	return s.checkAndReply(ctx, reply, fname, content)
+	return s.checkAndReply(ctx, reply, fname, content, true)
 }
 
 func (s *Server) handleDocumentChange(
@@ -185,22 +227,51 @@ func (s *Server) handleDocumentChange(
 	r jsonrpc2.Request,
 	log zerolog.Logger,
 ) error {
-	var params lsp.DidChangeTextDocumentParams
+	var params didChangeParams
 	if err := json.Unmarshal(r.Params(), &params); err != nil {
 		log.Error().Err(err).Msg("failed to unmarshal params")
 		return err
 	}
 
-	if len(params.ContentChanges) != 1 {
-		err := fmt.Errorf("expected content changes = 1, got = %d", len(params.ContentChanges))
-		log.Error().Err(err).Send()
-		return err
+	fname := params.TextDocument.URI.Filename()
+	content, err := s.documentContent(fname)
+	if err != nil {
+		// the document is new and must be fully sent.
+		log.Debug().Err(err).Msg("reading changed document")
 	}
 
-	content := params.ContentChanges[0].Text
-	fname := params.TextDocument.URI.Filename()
+	for _, change := range params.ContentChanges {
+		content = applyChange(content, change)
+	}
+	s.documents[fname] = content
+
+	// the code generation is validated on save, as it uses the saved files.
+	return s.checkAndReply(ctx, reply, fname, content, false)
+}
+
+// didChangeParams are the lsp.DidChangeTextDocumentParams but keeping track of
+// the content changes without a range, which replace the whole document.
+type didChangeParams struct {
+	TextDocument   lsp.VersionedTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []contentChange                     `json:"contentChanges"`
+}
 
-	return s.checkAndReply(ctx, reply, fname, content)
+type contentChange struct {
+	Range *lsp.Range `json:"range,omitempty"`
+	Text  string     `json:"text"`
+}
+
+// applyChange applies the content change to the document.
+func applyChange(content string, change contentChange) string {
+	if change.Range == nil {
+		return change.Text
+	}
+	start := offsetAt(content, change.Range.Start)
+	end := offsetAt(content, change.Range.End)
+	if end < start {
+		start, end = end, start
+	}
+	return content[:start] + change.Text + content[end:]
 }
 
 func (s *Server) handleDocumentSaved(
@@ -221,13 +292,58 @@ func (s *Server) handleDocumentSaved(
 		log.Error().Err(err).Msg("reading saved file.")
 		return nil
 	}
+	s.documents[fname] = string(content)
+	s.invalidate(filepath.Dir(fname))
+
+	return s.checkAndReply(ctx, reply, fname, string(content), true)
+}
+
+func (s *Server) handleDocumentClose(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.DidCloseTextDocumentParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
+
+	// unsaved changes are discarded by the editor.
+	fname := params.TextDocument.URI.Filename()
+	delete(s.documents, fname)
+	s.forgetDocument(fname)
+	return reply(ctx, nil, nil)
+}
+
+func (s *Server) handleWatchedFilesChange(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.DidChangeWatchedFilesParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
 
-	return s.checkAndReply(ctx, reply, fname, string(content))
+	for _, change := range params.Changes {
+		fname := change.URI.Filename()
+		if change.Type == lsp.FileChangeTypeDeleted {
+			// the deleted file may be a directory.
+			s.invalidate(fname)
+		}
+		s.invalidate(filepath.Dir(fname))
+	}
+	return reply(ctx, nil, nil)
 }
 
 // sendErrorDiagnostics sends diagnostics for each provided file, the ones with
 // no reported error gets an empty list of diagnostics, so the editor can clean
-// up its problems panel for it.
+// up its problems panel for it. The diagnostics of errors in other files are
+// also sent and cleaned up once they are fixed.
 func (s *Server) sendErrorDiagnostics(ctx context.Context, files []string, err error) error {
 	errs := errors.L()
 	switch e := err.(type) {
@@ -246,6 +362,15 @@ func (s *Server) sendErrorDiagnostics(ctx context.Context, files []string, err e
 	for _, filename := range files {
 		diagsMap[filename] = []lsp.Diagnostic{}
 	}
+	for filename := range s.published {
+		diagsMap[filename] = []lsp.Diagnostic{}
+	}
+	type diagKey struct {
+		filename string
+		rng      lsp.Range
+		message  string
+	}
+	seen := map[diagKey]bool{}
 
 	for _, err := range errs.Errors() {
 		e, ok := err.(*errors.Error)
@@ -263,15 +388,47 @@ func (s *Server) sendErrorDiagnostics(ctx context.Context, files []string, err e
 		fileRange.End.Line = uint32(e.FileRange.End.Line) - 1
 		fileRange.End.Character = uint32(e.FileRange.End.Column) - 1
 
-		diagsMap[filename] = append(diagsMap[filename], lsp.Diagnostic{
+		severity := lsp.DiagnosticSeverityError
+		if errors.IsKind(e, ErrStackMissingPath) {
+			// the run order ignores missing paths.
+			severity = lsp.DiagnosticSeverityWarning
+		}
+
+		diag := lsp.Diagnostic{
 			Message:  e.Message(),
 			Range:    fileRange,
-			Severity: lsp.DiagnosticSeverityError,
+			Severity: severity,
 			Source:   "terramate",
-		})
+		}
+		// the same code generation error is found in multiple stacks.
+		key := diagKey{filename, fileRange, diag.Message}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		diagsMap[filename] = append(diagsMap[filename], diag)
 	}
 
+	checked := map[string]bool{}
 	for _, filename := range files {
+		checked[filename] = true
+	}
+	others := []string{}
+	for filename := range diagsMap {
+		if !checked[filename] {
+			others = append(others, filename)
+		}
+	}
+	sort.Strings(others)
+
+	s.published = map[string]bool{}
+	for _, filename := range others {
+		if len(diagsMap[filename]) > 0 {
+			s.published[filename] = true
+		}
+	}
+
+	for _, filename := range append(files, others...) {
 		diags := diagsMap[filename]
 		filePath := lsp.URI(uri.File(filepath.ToSlash(filename)))
 		s.sendDiagnostics(ctx, filePath, diags)
@@ -280,19 +437,17 @@ func (s *Server) sendErrorDiagnostics(ctx context.Context, files []string, err e
 	return nil
 }
 
-func (s *Server) handleCompletion(
-	ctx context.Context,
-	reply jsonrpc2.Replier,
-	r jsonrpc2.Request,
-	log zerolog.Logger,
-) error {
-	var params lsp.CompletionParams
-	if err := json.Unmarshal(r.Params(), &params); err != nil {
-		log.Error().Err(err).Msg("failed to unmarshal params")
-		return jsonrpc2.ErrParse
+// documentContent returns the content of the document opened on the editor
+// or, if the document is not opened, the content of the file.
+func (s *Server) documentContent(fname string) (string, error) {
+	if content, ok := s.documents[fname]; ok {
+		return content, nil
 	}
-	log.Debug().Str("params", string(r.Params()))
-	return reply(ctx, nil, nil)
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
 }
 
 func (s *Server) sendDiagnostics(ctx context.Context, uri lsp.URI, diags []lsp.Diagnostic) {
@@ -306,93 +461,72 @@ func (s *Server) sendDiagnostics(ctx context.Context, uri lsp.URI, diags []lsp.D
 	}
 }
 
+// checkAndReply checks the files of the directory of fname and, if validateGen
+// is true and they have no errors, validates the code generation of the stacks
+// affected by them. The diagnostics of the checked files and of the code
+// generation of all validated stacks are sent to the editor.
 func (s *Server) checkAndReply(
 	ctx context.Context,
 	reply jsonrpc2.Replier,
 	fname string,
 	content string,
+	validateGen bool,
 ) error {
-	files, err := listFiles(fname)
-	files = append(files, fname)
-	sort.Strings(files)
+	files := []string{fname}
+	cache, err := s.dirCache(filepath.Dir(fname))
 	if err == nil {
-		err = s.checkFiles(files, fname, content)
-	}
-
-	return reply(ctx, nil,
-		s.sendErrorDiagnostics(ctx, files, err),
-	)
-}
-
-func listFiles(fromFile string) ([]string, error) {
-	dir := filepath.Dir(fromFile)
-	dirEntries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Trace().Msg("looking for Terramate files")
-
-	files := []string{}
-	for _, dirEntry := range dirEntries {
-		logger := log.With().
-			Str("entryName", dirEntry.Name()).
-			Logger()
-
-		if dirEntry.IsDir() {
-			logger.Trace().Msg("ignoring dir")
-			continue
-		}
-
-		filename := dirEntry.Name()
-		if strings.HasSuffix(filename, ".tm") || strings.HasSuffix(filename, ".tm.hcl") {
-			path := filepath.Join(dir, filename)
-
-			if path == fromFile {
-				// ignore source file
-				continue
+		for _, file := range cache.files {
+			if file != fname {
+				files = append(files, file)
 			}
+		}
+		sort.Strings(files)
 
-			files = append(files, path)
+		err = s.checkFiles(cache, files, fname, content)
+		if err == nil && validateGen {
+			s.validateGenerate(filepath.Dir(fname))
 		}
 	}
 
-	return files, nil
+	return reply(ctx, nil,
+		s.sendErrorDiagnostics(ctx, files, errors.L(err, s.generateErrors())),
+	)
 }
 
 // checkFiles checks if the given provided files have errors but the currentFile
-// is handled separately because it can be unsaved.
-func (s *Server) checkFiles(files []string, currentFile string, currentContent string) error {
+// is handled separately because it can be unsaved. The other documents opened
+// on the editor are also checked using their unsaved content. Only the files
+// which changed since they were last checked are parsed again.
+func (s *Server) checkFiles(cache *dirCache, files []string, currentFile string, currentContent string) error {
 	dir := filepath.Dir(currentFile)
-	_, rootdir, found, _ := config.TryLoadConfig(dir)
-	if !found {
-		rootdir = s.workspace
+	rootdir := s.workspace
+	root, err := s.loadRoot(dir)
+	if err == nil {
+		rootdir = root.HostDir()
 	}
 
-	log.Trace().Msgf("using project root: %s (found: %t)", rootdir, found)
+	log.Trace().Msgf("using project root: %s (found: %t)", rootdir, err == nil)
 
 	parser, err := hcl.NewTerramateParser(rootdir, dir)
 	if err != nil {
 		return errors.E(err, "failed to create terramate parser")
 	}
 
+	parsedFiles := map[string]*hhcl.File{}
 	for _, fname := range files {
-		var (
-			contents []byte
-			err      error
-		)
-
-		if currentFile == fname {
-			contents = []byte(currentContent)
-		} else {
-			contents, err = os.ReadFile(fname)
-		}
-
+		content, err := cache.content(s, fname, currentFile, currentContent)
 		if err != nil {
 			return err
 		}
 
-		err = parser.AddFileContent(fname, contents)
+		parsed := cache.parse(fname, content)
+		if parsed.diags.HasErrors() {
+			// the parser reports the syntax errors.
+			err = parser.AddFileContent(fname, []byte(content))
+		} else {
+			err = parser.AddParsedFile(fname, parsed.file)
+			parsedFiles[fname] = parsed.file
+		}
 		if err != nil {
 			return err
 		}
@@ -400,5 +534,8 @@ func (s *Server) checkFiles(files []string, currentFile string, currentContent s
 
 	log.Debug().Msg("about to parse all the files")
 	_, err = parser.ParseConfig()
-	return err
+	if err != nil || root == nil {
+		return err
+	}
+	return checkProject(root, dir, parsedFiles)
 }
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -462,6 +463,591 @@ stack {
 	}
 }
 
+func TestHoverAndDefinitionOfGlobals(t *testing.T) {
+	f := lstest.Setup(t, globalsLayout()...)
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+
+	const genfile = "stack/gen.tm"
+
+	// line 2: `    name = global.env`
+	hover := f.Editor.Hover(genfile, 2, 19)
+	if hover == nil {
+		t.Fatal("expected hover for global.env")
+	}
+	assert.EqualStrings(t, string(lsp.Markdown), string(hover.Contents.Kind))
+	for _, want := range []string{
+		"global.env = \"dev\"",
+		"Defined at `/stack/globals.tm:2,3-14`",
+		"Overrides `/globals.tm:2,3-15`",
+		"Evaluated for stack `/stack`",
+	} {
+		assertContains(t, hover.Contents.Value, want)
+	}
+	if diff := cmp.Diff(&lsp.Range{
+		Start: lsp.Position{Line: 2, Character: 11},
+		End:   lsp.Position{Line: 2, Character: 21},
+	}, hover.Range); diff != "" {
+		t.Fatalf("unexpected hover range: %s", diff)
+	}
+
+	// line 5: `    value = global.obj.a`, hovering `obj` shows the whole object.
+	hover = f.Editor.Hover(genfile, 5, 20)
+	if hover == nil {
+		t.Fatal("expected hover for global.obj")
+	}
+	assertContains(t, hover.Contents.Value, "global.obj = {\n  a = 1\n}")
+
+	// hovering anything else returns nothing.
+	if hover := f.Editor.Hover(genfile, 0, 2); hover != nil {
+		t.Fatalf("unexpected hover: %v", hover)
+	}
+
+	locations := f.Editor.Definition(genfile, 2, 19)
+	if diff := cmp.Diff([]lsp.Location{
+		{
+			URI: uri.File(filepath.Join(f.Sandbox.RootDir(), "stack", "globals.tm")),
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 2},
+				End:   lsp.Position{Line: 1, Character: 13},
+			},
+		},
+	}, locations); diff != "" {
+		t.Fatalf("unexpected definition: %s", diff)
+	}
+
+	locations = f.Editor.Definition(genfile, 5, 24)
+	if diff := cmp.Diff([]lsp.Location{
+		{
+			URI: uri.File(filepath.Join(f.Sandbox.RootDir(), "globals.tm")),
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 2, Character: 2},
+				End:   lsp.Position{Line: 4, Character: 3},
+			},
+		},
+	}, locations); diff != "" {
+		t.Fatalf("unexpected definition: %s", diff)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	f := lstest.Setup(t, globalsLayout()...)
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+
+	const editfile = "stack/edit.tm"
+
+	f.Editor.Change(editfile, `generate_hcl "file.tf" {
+  lets {
+    name = "a"
+    map items {
+      for_each = []
+      key      = element.new
+      value    = element.new
+    }
+  }
+  content {
+    a = global.
+    b = global.obj.
+    c = terramate.stack.path.
+    d = tm_up
+    e = let.
+    f = gl
+  }
+}
+`)
+	drainRequests(t, f)
+
+	type testcase struct {
+		line uint32
+		char uint32
+		want []string
+	}
+
+	for _, tc := range []testcase{
+		{line: 10, char: 15, want: []string{"env", "obj"}},
+		{line: 11, char: 19, want: []string{"a"}},
+		{line: 12, char: 29, want: []string{"absolute", "basename", "relative", "to_root"}},
+		{line: 13, char: 13, want: []string{"tm_upper"}},
+		{line: 14, char: 12, want: []string{"items", "name"}},
+		{line: 15, char: 10, want: []string{"global"}},
+	} {
+		got := f.Editor.Completion(editfile, tc.line, tc.char)
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("completion at %d:%d: got(-) want(+):\n%s", tc.line, tc.char, diff)
+		}
+	}
+}
+
+func globalsLayout() []string {
+	return []string{
+		`f:globals.tm:globals {
+  env = "prod"
+  obj = {
+    a = 1
+  }
+}
+`,
+		"s:stack",
+		`f:stack/globals.tm:globals {
+  env = "dev"
+}
+`,
+		`f:stack/gen.tm:generate_hcl "file.tf" {
+  lets {
+    name = global.env
+  }
+  content {
+    value = global.obj.a
+    other = let.name
+  }
+}
+`,
+	}
+}
+
+// drainRequests consumes all the requests (usually diagnostics) sent by the
+// server to the editor.
+func drainRequests(t *testing.T, f lstest.Fixture) {
+	t.Helper()
+	for {
+		select {
+		case <-f.Editor.Requests:
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func assertContains(t *testing.T, s, substr string) {
+	t.Helper()
+	if !strings.Contains(s, substr) {
+		t.Fatalf("%q not found in:\n%s", substr, s)
+	}
+}
+
 func init() {
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 }
+
+func TestIncrementalDocumentChange(t *testing.T) {
+	const stackfile = "stack/stack.tm"
+
+	f := lstest.Setup(t, `f:stack/stack.tm:stack {
+  name = "stack"
+}
+`)
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+	f.Editor.Open(stackfile)
+	assertDiagnostics(t, f, stackfile, nil)
+
+	// insert at the beginning of the file.
+	f.Editor.ChangeRange(stackfile, lsp.Range{}, "# comment\n")
+	assertDiagnostics(t, f, stackfile, nil)
+
+	// line 2: `  name = "stack"`
+	f.Editor.ChangeRange(stackfile, lsp.Range{
+		Start: lsp.Position{Line: 2, Character: 2},
+		End:   lsp.Position{Line: 2, Character: 2},
+	}, "bug ")
+	diags := assertDiagnostics(t, f, stackfile, []lsp.DiagnosticSeverity{
+		lsp.DiagnosticSeverityError,
+	})
+	assert.EqualInts(t, 2, int(diags[0].Range.Start.Line))
+
+	f.Editor.ChangeRange(stackfile, lsp.Range{
+		Start: lsp.Position{Line: 2, Character: 2},
+		End:   lsp.Position{Line: 2, Character: 6},
+	}, "")
+	assertDiagnostics(t, f, stackfile, nil)
+}
+
+func TestDocumentChangeUsesCachedFiles(t *testing.T) {
+	const (
+		stackfile   = "stack/stack.tm"
+		globalsfile = "stack/globals.tm"
+	)
+
+	f := lstest.Setup(t,
+		`f:stack/stack.tm:stack {
+  name = "stack"
+}
+`,
+		`f:stack/globals.tm:globals {
+  a = 1
+}
+`,
+	)
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+	f.Editor.Open(stackfile)
+	assertDiagnostics(t, f, stackfile, nil)
+
+	// the other files of the directory are not read again until the
+	// directory is invalidated.
+	f.Sandbox.RootEntry().CreateFile(globalsfile, "globals {")
+	f.Editor.Change(stackfile, `stack {
+  name = "changed"
+}
+`)
+	assertDiagnostics(t, f, stackfile, nil)
+
+	f.Editor.FileChanged(globalsfile)
+	f.Editor.Change(stackfile, `stack {
+  name = "stack"
+}
+`)
+	assertDiagnostics(t, f, globalsfile, []lsp.DiagnosticSeverity{
+		lsp.DiagnosticSeverityError,
+	})
+	assertDiagnostics(t, f, stackfile, nil)
+}
+
+func TestStackProjectDiagnostics(t *testing.T) {
+	const (
+		stackfile = "stacks/b/stack.tm"
+		otherfile = "stacks/c/stack.tm"
+	)
+
+	f := lstest.Setup(t,
+		"s:stacks/a:id=dup",
+		`f:stacks/b/stack.tm:stack {
+  id    = "DUP"
+  after = ["../a", "/missing", "tag:a"]
+}
+`,
+		`f:stacks/c/stack.tm:stack {
+  id = "c"
+}
+`,
+	)
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+	f.Editor.Open(stackfile)
+
+	diags := assertDiagnostics(t, f, stackfile, []lsp.DiagnosticSeverity{
+		lsp.DiagnosticSeverityWarning,
+		lsp.DiagnosticSeverityError,
+	})
+	assertContains(t, diags[0].Message, `stack.after entry "/missing"`)
+	assertContains(t, diags[1].Message, `stack "/stacks/a" has the same ID "DUP"`)
+	if diff := cmp.Diff(lsp.Range{
+		Start: lsp.Position{Line: 2, Character: 19},
+		End:   lsp.Position{Line: 2, Character: 29},
+	}, diags[0].Range); diff != "" {
+		t.Fatalf("unexpected diagnostic range: %s", diff)
+	}
+
+	f.Editor.Change(stackfile, `stack {
+  id    = "b"
+  after = ["../a"]
+}
+`)
+	assertDiagnostics(t, f, stackfile, nil)
+
+	// the saved stack directory is reloaded into the project.
+	f.Sandbox.RootEntry().CreateFile(otherfile, `stack {
+  id = "new"
+}
+`)
+	f.Editor.Open(otherfile)
+	drainRequests(t, f)
+	f.Editor.Save(otherfile)
+	drainRequests(t, f)
+
+	f.Editor.Change(stackfile, `stack {
+  id = "new"
+}
+`)
+	diags = assertDiagnostics(t, f, stackfile, []lsp.DiagnosticSeverity{
+		lsp.DiagnosticSeverityError,
+	})
+	assertContains(t, diags[0].Message, `stack "/stacks/c" has the same ID "new"`)
+}
+
+// assertDiagnostics reads the diagnostics published for the file and checks
+// their severities, returning them.
+func assertDiagnostics(
+	t *testing.T,
+	f lstest.Fixture,
+	file string,
+	want []lsp.DiagnosticSeverity,
+) []lsp.Diagnostic {
+	t.Helper()
+	wantURI := uri.File(filepath.Join(f.Sandbox.RootDir(), file))
+	for {
+		select {
+		case r := <-f.Editor.Requests:
+			assert.EqualStrings(t, lsp.MethodTextDocumentPublishDiagnostics, r.Method())
+
+			var params lsp.PublishDiagnosticsParams
+			assert.NoError(t, json.Unmarshal(r.Params(), &params))
+			if params.URI != wantURI {
+				continue
+			}
+
+			got := []lsp.DiagnosticSeverity{}
+			for _, diag := range params.Diagnostics {
+				got = append(got, diag.Severity)
+			}
+			if len(want) == 0 {
+				want = []lsp.DiagnosticSeverity{}
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("diagnostics severities mismatch: got(-) want(+):\n%s\n%s",
+					diff, string(r.Params()))
+			}
+			return params.Diagnostics
+		case <-time.After(time.Second):
+			t.Fatalf("no diagnostics published for %s", file)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	const file = "stack/stack.tm"
+
+	f := lstest.Setup(t, "s:stack")
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+
+	f.Editor.Change(file, `globals {
+  a    = 1
+}
+
+globals {
+  b =    2
+}
+`)
+	drainRequests(t, f)
+
+	edits := f.Editor.Formatting(file)
+	if diff := cmp.Diff([]lsp.TextEdit{
+		{
+			Range: lsp.Range{
+				End: lsp.Position{Line: 7},
+			},
+			NewText: "globals {\n  a = 1\n}\n\nglobals {\n  b = 2\n}\n",
+		},
+	}, edits); diff != "" {
+		t.Fatalf("unexpected formatting edits: %s", diff)
+	}
+
+	// only the second block is formatted.
+	edits = f.Editor.RangeFormatting(file, lsp.Range{
+		Start: lsp.Position{Line: 5, Character: 2},
+		End:   lsp.Position{Line: 5, Character: 4},
+	})
+	if diff := cmp.Diff([]lsp.TextEdit{
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 4},
+				End:   lsp.Position{Line: 7},
+			},
+			NewText: "globals {\n  b = 2\n}\n",
+		},
+	}, edits); diff != "" {
+		t.Fatalf("unexpected range formatting edits: %s", diff)
+	}
+
+	f.Editor.Change(file, "globals {\n  a = 1\n}\n")
+	drainRequests(t, f)
+	assert.EqualInts(t, 0, len(f.Editor.Formatting(file)))
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	const file = "stack/stack.tm"
+
+	f := lstest.Setup(t, `f:stack/stack.tm:stack {
+  name = "stack"
+}
+
+globals "obj" {
+  a = 1
+  b = 2
+}
+
+import {
+  source = "/other.tm"
+}
+
+generate_hcl "main.tf" {
+  assert {
+    assertion = true
+    message   = "ok"
+  }
+  content {}
+}
+`)
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+
+	type symbol struct {
+		Name     string
+		Kind     lsp.SymbolKind
+		Detail   string
+		Children []symbol
+	}
+	var toSymbols func([]lsp.DocumentSymbol) []symbol
+	toSymbols = func(docSymbols []lsp.DocumentSymbol) []symbol {
+		var symbols []symbol
+		for _, s := range docSymbols {
+			symbols = append(symbols, symbol{
+				Name:     s.Name,
+				Kind:     s.Kind,
+				Detail:   s.Detail,
+				Children: toSymbols(s.Children),
+			})
+		}
+		return symbols
+	}
+
+	got := f.Editor.DocumentSymbols(file)
+	if diff := cmp.Diff([]symbol{
+		{Name: "stack", Kind: lsp.SymbolKindModule},
+		{
+			Name: `globals "obj"`,
+			Kind: lsp.SymbolKindNamespace,
+			Children: []symbol{
+				{Name: "a", Kind: lsp.SymbolKindVariable},
+				{Name: "b", Kind: lsp.SymbolKindVariable},
+			},
+		},
+		{Name: "import", Kind: lsp.SymbolKindPackage, Detail: "/other.tm"},
+		{
+			Name: `generate_hcl "main.tf"`,
+			Kind: lsp.SymbolKindFile,
+			Children: []symbol{
+				{Name: "assert", Kind: lsp.SymbolKindBoolean},
+			},
+		},
+	}, toSymbols(got)); diff != "" {
+		t.Fatalf("unexpected symbols: got(-) want(+):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(lsp.Range{
+		Start: lsp.Position{Line: 4},
+		End:   lsp.Position{Line: 7, Character: 1},
+	}, got[1].Range); diff != "" {
+		t.Fatalf("unexpected symbol range: %s", diff)
+	}
+}
+
+func TestCodeActions(t *testing.T) {
+	const file = "stack/stack.tm"
+
+	f := lstest.Setup(t, `f:stack/stack.tm:stack {
+  name =  "stack"
+}
+`)
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+
+	actions := f.Editor.CodeActions(file, lsp.Range{})
+	titles := []string{}
+	for _, action := range actions {
+		titles = append(titles, action.Title)
+		assert.EqualStrings(t, string(lsp.QuickFix), string(action.Kind))
+	}
+	if diff := cmp.Diff([]string{"Add missing stack.id", "Format file"}, titles); diff != "" {
+		t.Fatalf("unexpected code actions: %s", diff)
+	}
+
+	docURI := uri.File(filepath.Join(f.Sandbox.RootDir(), file))
+	edits := actions[0].Edit.Changes[docURI]
+	assert.EqualInts(t, 1, len(edits))
+	assertContains(t, edits[0].NewText, "  id   = \"")
+
+	edits = actions[1].Edit.Changes[docURI]
+	assert.EqualInts(t, 1, len(edits))
+	assert.EqualStrings(t, "stack {\n  name = \"stack\"\n}\n", edits[0].NewText)
+
+	f.Editor.Change(file, `stack {
+  name = "stack"
+  id   = "stack"
+}
+`)
+	drainRequests(t, f)
+	assert.EqualInts(t, 0, len(f.Editor.CodeActions(file, lsp.Range{})))
+}
+
+func TestGenerateDiagnostics(t *testing.T) {
+	const (
+		parentfile = "stacks/gen.tm"
+		stackfile  = "stacks/a/gen.tm"
+	)
+
+	f := lstest.Setup(t,
+		"s:stacks/a",
+		"s:stacks/b",
+		`f:stacks/gen.tm:generate_hcl "file.tf" {
+  assert {
+    assertion = terramate.stack.name == "a"
+    message   = "not stack a"
+  }
+  content {
+    name = terramate.stack.name
+  }
+}
+`,
+		`f:stacks/a/gen.tm:generate_file "file.tf" {
+  content = "conflict"
+}
+`,
+	)
+	f.Editor.CheckInitialize(f.Sandbox.RootDir())
+	f.Editor.Open(parentfile)
+
+	got := collectDiagnostics(t, f)
+
+	// the assertion fails only for stack b and the conflict in stack a is
+	// reported at both blocks.
+	messages := func(diags []lsp.Diagnostic) []string {
+		msgs := []string{}
+		for _, diag := range diags {
+			msgs = append(msgs, diag.Message)
+		}
+		return msgs
+	}
+	conflict := `conflicting config detected: configs from "/stacks/gen.tm" and ` +
+		`"/stacks/a/gen.tm" generate a file with same name "file.tf" have ` +
+		"`condition = true`"
+	if diff := cmp.Diff([]string{
+		conflict,
+		"assertion failed: not stack a",
+	}, messages(got[parentfile])); diff != "" {
+		t.Fatalf("unexpected diagnostics for %s: %s", parentfile, diff)
+	}
+	assert.EqualInts(t, 2, int(got[parentfile][1].Range.Start.Line))
+	if diff := cmp.Diff([]string{conflict}, messages(got[stackfile])); diff != "" {
+		t.Fatalf("unexpected diagnostics for %s: %s", stackfile, diff)
+	}
+
+	f.Sandbox.RootEntry().CreateFile(parentfile, `generate_hcl "other.tf" {
+  content {
+    name = terramate.stack.name
+  }
+}
+`)
+	f.Editor.Save(parentfile)
+
+	got = collectDiagnostics(t, f)
+	assert.EqualInts(t, 0, len(got[parentfile]), "diagnostics: %v", got)
+	if diags, ok := got[stackfile]; !ok || len(diags) != 0 {
+		t.Fatalf("expected diagnostics of %s to be cleared: %v", stackfile, got)
+	}
+}
+
+// collectDiagnostics consumes all the diagnostics sent by the server to the
+// editor, returning the last ones of each file relative to the sandbox.
+func collectDiagnostics(t *testing.T, f lstest.Fixture) map[string][]lsp.Diagnostic {
+	t.Helper()
+	got := map[string][]lsp.Diagnostic{}
+	for {
+		select {
+		case r := <-f.Editor.Requests:
+			assert.EqualStrings(t, lsp.MethodTextDocumentPublishDiagnostics, r.Method())
+
+			var params lsp.PublishDiagnosticsParams
+			assert.NoError(t, json.Unmarshal(r.Params(), &params))
+			rel, err := filepath.Rel(f.Sandbox.RootDir(), params.URI.Filename())
+			assert.NoError(t, err)
+			got[filepath.ToSlash(rel)] = params.Diagnostics
+		case <-time.After(100 * time.Millisecond):
+			return got
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ErrStackMissingPath indicates that a stack ordering or selection field
+// refers to a path which doesn't exist.
+const ErrStackMissingPath errors.Kind = "stack refers to a non-existent path"
+
+// stackPathFields are the stack attributes which hold a list of paths.
+var stackPathFields = []string{"after", "before", "wants", "wanted_by"}
+
+// loadRoot returns the configuration of the project containing dir. The
+// configuration is cached and only the directories invalidated since the last
+// call are reloaded.
+func (s *Server) loadRoot(dir string) (*config.Root, error) {
+	if s.root != nil && isInsideDir(s.root.HostDir(), dir) {
+		s.reloadInvalidated()
+		return s.root, nil
+	}
+
+	root, _, found, err := config.TryLoadConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		root, err = config.LoadRoot(s.workspace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.root = root
+	s.invalidated = map[string]bool{}
+	return root, nil
+}
+
+// invalidate marks the configuration of the given directory as stale and
+// drops its cached files.
+func (s *Server) invalidate(dir string) {
+	s.invalidated[dir] = true
+	s.dropDirCache(dir)
+}
+
+// reloadInvalidated reloads the invalidated directories of the cached
+// project. A directory that fails to load is kept invalidated, so its last
+// good configuration is used until it's fixed.
+func (s *Server) reloadInvalidated() {
+	dirs := make([]string, 0, len(s.invalidated))
+	for dir := range s.invalidated {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	rootdir := s.root.HostDir()
+	reloaded := []string{}
+	for _, dir := range dirs {
+		if !isInsideDir(rootdir, dir) {
+			delete(s.invalidated, dir)
+			continue
+		}
+
+		done := false
+		for _, parent := range reloaded {
+			if isInsideDir(parent, dir) {
+				done = true
+				break
+			}
+		}
+		if done {
+			delete(s.invalidated, dir)
+			continue
+		}
+
+		// a removed directory is dropped by reloading its parent.
+		loaddir := dir
+		for loaddir != rootdir {
+			if st, err := os.Stat(loaddir); err == nil && st.IsDir() {
+				break
+			}
+			loaddir = filepath.Dir(loaddir)
+		}
+
+		err := s.root.LoadSubTree(project.PrjAbsPath(rootdir, loaddir))
+		if err != nil {
+			log.Debug().Err(err).Str("dir", loaddir).Msg("keeping stale configuration")
+			continue
+		}
+		reloaded = append(reloaded, loaddir)
+		delete(s.invalidated, dir)
+	}
+}
+
+// checkProject checks the stack defined in the given directory files against
+// the rest of the project: the paths referenced by its ordering and selection
+// fields must exist and its ID must be unique.
+func checkProject(root *config.Root, dir string, files map[string]*hhcl.File) error {
+	rootdir := root.HostDir()
+	if !isInsideDir(rootdir, dir) {
+		return nil
+	}
+	stackdir := project.PrjAbsPath(rootdir, dir)
+
+	errs := errors.L()
+	for _, file := range files {
+		block, ok := fileStackBlock(file)
+		if !ok {
+			continue
+		}
+		for _, field := range stackPathFields {
+			if attr, ok := block.Body.Attributes[field]; ok {
+				errs.Append(checkStackPaths(rootdir, stackdir, field, attr))
+			}
+		}
+		if attr, ok := block.Body.Attributes["id"]; ok {
+			errs.Append(checkStackID(root, stackdir, attr))
+		}
+	}
+	return errs.AsError()
+}
+
+func checkStackPaths(rootdir string, stackdir project.Path, field string, attr *hclsyntax.Attribute) error {
+	tuple, ok := attr.Expr.(*hclsyntax.TupleConsExpr)
+	if !ok {
+		return nil
+	}
+
+	errs := errors.L()
+	for _, elem := range tuple.Exprs {
+		val, diags := elem.Value(nil)
+		if diags.HasErrors() || !val.IsKnown() || val.IsNull() || !val.Type().Equals(cty.String) {
+			continue
+		}
+		pathstr := val.AsString()
+		if strings.HasPrefix(pathstr, "tag:") {
+			continue
+		}
+
+		target := pathstr
+		if !path.IsAbs(target) {
+			target = path.Join(stackdir.String(), target)
+		}
+		abspath := filepath.Join(rootdir, filepath.FromSlash(target))
+		if st, err := os.Stat(abspath); err == nil && st.IsDir() {
+			continue
+		}
+		errs.Append(errors.E(ErrStackMissingPath, elem.Range(),
+			"stack.%s entry %q: directory %s not found", field, pathstr, target))
+	}
+	return errs.AsError()
+}
+
+func checkStackID(root *config.Root, stackdir project.Path, attr *hclsyntax.Attribute) error {
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !val.IsKnown() || val.IsNull() || !val.Type().Equals(cty.String) {
+		return nil
+	}
+	id := strings.ToLower(val.AsString())
+	if id == "" {
+		return nil
+	}
+
+	for _, other := range root.Tree().Stacks() {
+		// the cached configuration of the checked stack may be outdated.
+		if other.Dir() == stackdir || other.Node.Stack == nil {
+			continue
+		}
+		if strings.ToLower(other.Node.Stack.ID) == id {
+			return errors.E(config.ErrStackDuplicatedID, attr.Expr.Range(),
+				"stack %q has the same ID %q", other.Dir(), val.AsString())
+		}
+	}
+	return nil
+}
+
+// stackBlock returns the stack block of the file content, if any.
+func stackBlock(fname string, content []byte) (*hclsyntax.Block, bool) {
+	file, _ := hclsyntax.ParseConfig(content, fname, hhcl.InitialPos)
+	return fileStackBlock(file)
+}
+
+// fileStackBlock returns the stack block of the parsed file, if any.
+func fileStackBlock(file *hhcl.File) (*hclsyntax.Block, bool) {
+	if file == nil {
+		return nil, false
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, false
+	}
+	for _, block := range body.Blocks {
+		if block.Type == "stack" {
+			return block, true
+		}
+	}
+	return nil, false
+}
+
+// isInsideDir tells if dir is the base directory or one of its descendants.
+func isInsideDir(base, dir string) bool {
+	rel, err := filepath.Rel(base, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
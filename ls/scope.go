@@ -0,0 +1,128 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"path/filepath"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stdlib"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// scope is the evaluation scope of a file: the project configuration, the
+// directory of the file and the stack enclosing it, if any.
+type scope struct {
+	root  *config.Root
+	tree  *config.Tree
+	stack *config.Stack
+}
+
+// loadScope loads the scope of the given file. The project is the one
+// containing the file or the workspace if the file is not inside any project.
+// The returned scope shares the cached project configuration.
+func (s *Server) loadScope(fname string) (scope, error) {
+	hostdir := filepath.Dir(fname)
+	root, err := s.loadRoot(hostdir)
+	if err != nil {
+		return scope{}, err
+	}
+
+	if !isInsideDir(root.HostDir(), hostdir) {
+		return scope{}, errors.E("file %s is outside the project %s", fname, root.HostDir())
+	}
+
+	tree, ok := root.Lookup(project.PrjAbsPath(root.HostDir(), hostdir))
+	if !ok {
+		return scope{}, errors.E("directory %s is not part of the project", hostdir)
+	}
+
+	sc := scope{
+		root: root,
+		tree: tree,
+	}
+	for node := tree; node != nil; node = node.Parent {
+		if node.IsStack() {
+			sc.stack, err = config.NewStackFromHCL(root.HostDir(), node.Node)
+			if err != nil {
+				return scope{}, err
+			}
+			break
+		}
+	}
+	return sc, nil
+}
+
+// runtime returns the values of the terramate namespace for the scope. The
+// stack metadata is the one of the enclosing stack or, if the file is not
+// inside a stack, an empty stack at the file directory.
+func (sc scope) runtime() project.Runtime {
+	st := sc.stack
+	if st == nil {
+		st = &config.Stack{Dir: sc.tree.Dir()}
+	}
+	runtime := sc.root.Runtime()
+	runtime.Merge(st.RuntimeValues(sc.root))
+	return runtime
+}
+
+// evalGlobals evaluates the globals of the file directory using the metadata
+// of the enclosing stack.
+func (sc scope) evalGlobals() globals.EvalReport {
+	ctx := eval.NewContext(stdlib.Functions(sc.tree.HostDir()))
+	ctx.SetNamespace("terramate", sc.runtime())
+	return globals.ForDir(sc.root, sc.tree.Dir(), ctx)
+}
+
+// globalDefinitions returns the expressions which set the global at the
+// given path, from the one that takes precedence to the ones overridden by it.
+func (sc scope) globalDefinitions(path []string) ([]globals.Expr, error) {
+	exprs, err := globals.LoadExprs(sc.tree)
+	if err != nil {
+		return nil, err
+	}
+	return exprs.Definitions(path), nil
+}
+
+// namespace returns the value of the given namespace for the scope.
+func (sc scope) namespace(name string) (cty.Value, bool) {
+	switch name {
+	case "global":
+		report := sc.evalGlobals()
+		return cty.ObjectVal(report.Globals.AsValueMap()), true
+	case "terramate":
+		return cty.ObjectVal(sc.runtime()), true
+	}
+	return cty.NilVal, false
+}
+
+// lookupValue returns the value at the given path inside val.
+func lookupValue(val cty.Value, path []string) (cty.Value, bool) {
+	for _, key := range path {
+		if val.IsNull() || !val.IsKnown() {
+			return cty.NilVal, false
+		}
+		typ := val.Type()
+		switch {
+		case typ.IsObjectType():
+			if !typ.HasAttribute(key) {
+				return cty.NilVal, false
+			}
+			val = val.GetAttr(key)
+		case typ.IsMapType():
+			k := cty.StringVal(key)
+			if !val.HasIndex(k).True() {
+				return cty.NilVal, false
+			}
+			val = val.Index(k)
+		default:
+			return cty.NilVal, false
+		}
+	}
+	return val, true
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tmls
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog"
+	"github.com/zclconf/go-cty/cty"
+	"go.lsp.dev/jsonrpc2"
+	lsp "go.lsp.dev/protocol"
+)
+
+// symbolKinds are the symbol kinds of the blocks listed as document symbols.
+var symbolKinds = map[string]lsp.SymbolKind{
+	"stack":         lsp.SymbolKindModule,
+	"globals":       lsp.SymbolKindNamespace,
+	"generate_hcl":  lsp.SymbolKindFile,
+	"generate_file": lsp.SymbolKindFile,
+	"assert":        lsp.SymbolKindBoolean,
+	"import":        lsp.SymbolKindPackage,
+}
+
+func (s *Server) handleDocumentSymbol(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+	log zerolog.Logger,
+) error {
+	var params lsp.DocumentSymbolParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		log.Error().Err(err).Msg("failed to unmarshal params")
+		return jsonrpc2.ErrParse
+	}
+
+	fname := params.TextDocument.URI.Filename()
+	content, err := s.documentContent(fname)
+	if err != nil {
+		log.Debug().Err(err).Msg("reading document")
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, documentSymbols(fname, content), nil)
+}
+
+// documentSymbols returns the symbols of the Terramate blocks of the
+// document. The symbols are returned even if the document has syntax errors,
+// as long as the blocks could be recovered by the parser.
+func documentSymbols(fname, content string) []lsp.DocumentSymbol {
+	file, _ := hclsyntax.ParseConfig([]byte(content), fname, hhcl.InitialPos)
+	if file == nil {
+		return []lsp.DocumentSymbol{}
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return []lsp.DocumentSymbol{}
+	}
+	return blockSymbols(body)
+}
+
+func blockSymbols(body *hclsyntax.Body) []lsp.DocumentSymbol {
+	symbols := []lsp.DocumentSymbol{}
+	for _, block := range body.Blocks {
+		kind, ok := symbolKinds[block.Type]
+		if !ok {
+			continue
+		}
+
+		name := block.Type
+		for _, label := range block.Labels {
+			name += " " + strconv.Quote(label)
+		}
+
+		symbol := lsp.DocumentSymbol{
+			Name:           name,
+			Kind:           kind,
+			Range:          toLSPRange(block.Range()),
+			SelectionRange: toLSPRange(hhcl.RangeBetween(block.TypeRange, block.OpenBraceRange)),
+		}
+
+		switch block.Type {
+		case "globals":
+			symbol.Children = globalsSymbols(block)
+		case "generate_hcl", "generate_file":
+			// only the nested assert blocks are listed.
+			symbol.Children = blockSymbols(block.Body)
+		case "import":
+			if attr, ok := block.Body.Attributes["source"]; ok {
+				val, diags := attr.Expr.Value(nil)
+				if !diags.HasErrors() && val.IsKnown() && val.Type() == cty.String {
+					symbol.Detail = val.AsString()
+				}
+			}
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// globalsSymbols returns the symbols of the globals defined by the block.
+func globalsSymbols(block *hclsyntax.Block) []lsp.DocumentSymbol {
+	symbols := []lsp.DocumentSymbol{}
+	for name, attr := range block.Body.Attributes {
+		symbols = append(symbols, lsp.DocumentSymbol{
+			Name:           name,
+			Kind:           lsp.SymbolKindVariable,
+			Range:          toLSPRange(attr.Range()),
+			SelectionRange: toLSPRange(attr.NameRange),
+		})
+	}
+	for _, mapBlock := range block.Body.Blocks {
+		if mapBlock.Type != "map" || len(mapBlock.Labels) != 1 {
+			continue
+		}
+		symbols = append(symbols, lsp.DocumentSymbol{
+			Name:           mapBlock.Labels[0],
+			Kind:           lsp.SymbolKindVariable,
+			Range:          toLSPRange(mapBlock.Range()),
+			SelectionRange: toLSPRange(mapBlock.LabelRanges[0]),
+		})
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Range.Start.Line < symbols[j].Range.Start.Line
+	})
+	return symbols
+}
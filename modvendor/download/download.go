@@ -5,9 +5,12 @@
 package download
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	iofs "io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -20,11 +23,13 @@ import (
 	"github.com/terramate-io/terramate/git"
 	"github.com/terramate-io/terramate/modvendor"
 	"github.com/terramate-io/terramate/modvendor/manifest"
+	"github.com/terramate-io/terramate/modvendor/registry"
 	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/tf"
 	"github.com/zclconf/go-cty/cty"
 
 	hhcl "github.com/hashicorp/hcl/v2"
+	hcljson "github.com/hashicorp/hcl/v2/json"
 )
 
 const (
@@ -43,6 +48,7 @@ const (
 
 type modinfo struct {
 	source     string
+	version    string
 	vendoredAt project.Path
 	origin     string
 	subdir     string
@@ -61,7 +67,11 @@ type modinfo struct {
 // The whole path inside the vendor dir will be created if it not exists.
 // Vendoring will not download any git submodules.
 //
-// The remote git module dependencies will also be vendored and each
+// For Terraform Registry sources the Source.Ref is a version constraint,
+// resolved to the newest matching module version using the registry client.
+// Passing a nil client uses a default [registry.Client].
+//
+// The remote git and registry module dependencies will also be vendored and each
 // module.source declaration for those dependencies will be rewritten to
 // reference them inside the vendor directory.
 //
@@ -76,9 +86,17 @@ func Vendor(
 	rootdir string,
 	vendorDir project.Path,
 	modsrc tf.Source,
+	client *registry.Client,
 	events ProgressEventStream,
 ) Report {
-	return vendor(rootdir, vendorDir, modsrc, NewReport(vendorDir), nil, events)
+	client = registryClient(client)
+	report := NewReport(vendorDir)
+	resolved, err := resolveRegistryVersion(client, modsrc, modsrc.Ref)
+	if err != nil {
+		report.addIgnored(modsrc.Raw, errors.E(ErrDownloadMod, err))
+		return report
+	}
+	return vendor(rootdir, vendorDir, resolved, report, nil, client, events)
 }
 
 // HandleVendorRequests starts a goroutine that will handle all vendor requests
@@ -87,12 +105,14 @@ func Vendor(
 //
 // It will read events from vendorRequests until it is closed.
 // If progressEvents is nil it will not send any progress events, like [Vendor].
+// The registry client is also handled like in [Vendor].
 //
 // When vendorRequests is closed it will close the returned [Report] channel,
 // indicating that no more processing will be done.
 func HandleVendorRequests(
 	rootdir string,
 	vendorRequests <-chan event.VendorRequest,
+	client *registry.Client,
 	progressEvents ProgressEventStream,
 ) <-chan Report {
 	reportsStream := make(chan Report)
@@ -112,7 +132,7 @@ func HandleVendorRequests(
 
 			logger.Debug().Msgf("handling vendor request")
 
-			report := Vendor(rootdir, vendorRequest.VendorDir, vendorRequest.Source, progressEvents)
+			report := Vendor(rootdir, vendorRequest.VendorDir, vendorRequest.Source, client, progressEvents)
 
 			logger.Debug().Msgf("handled vendor request, sending report")
 
@@ -174,9 +194,10 @@ func VendorAll(
 	rootdir string,
 	vendorDir project.Path,
 	tfdir string,
+	client *registry.Client,
 	events ProgressEventStream,
 ) Report {
-	return vendorAll(rootdir, vendorDir, tfdir, NewReport(vendorDir), events)
+	return vendorAll(rootdir, vendorDir, tfdir, NewReport(vendorDir), registryClient(client), events)
 }
 
 func vendor(
@@ -185,6 +206,7 @@ func vendor(
 	modsrc tf.Source,
 	report Report,
 	info *modinfo,
+	client *registry.Client,
 	events ProgressEventStream,
 ) Report {
 	logger := log.With().
@@ -192,7 +214,7 @@ func vendor(
 		Str("module.source", modsrc.Raw).
 		Logger()
 
-	moddir, err := downloadVendor(rootdir, vendorDir, modsrc, events)
+	moddir, err := downloadVendor(rootdir, vendorDir, modsrc, client, events)
 	if err != nil {
 		if errors.IsKind(err, ErrAlreadyVendored) {
 			report.addIgnored(modsrc.Raw, err)
@@ -213,7 +235,7 @@ func vendor(
 	logger.Trace().Msg("successfully downloaded")
 
 	report.addVendored(modsrc)
-	return vendorAll(rootdir, vendorDir, moddir, report, events)
+	return vendorAll(rootdir, vendorDir, moddir, report, client, events)
 }
 
 // sourcesInfo represents information about module sources. It retains
@@ -223,7 +245,7 @@ func vendor(
 // specific sources.
 type sourcesInfo struct {
 	list []*modinfo          // ordered list of sources
-	set  map[string]*modinfo // set of sources
+	set  map[string]*modinfo // set of sources, see sourceKey()
 }
 
 func newSourcesInfo() *sourcesInfo {
@@ -232,33 +254,45 @@ func newSourcesInfo() *sourcesInfo {
 	}
 }
 
-func (s *sourcesInfo) append(source, path string) {
-	if _, ok := s.set[source]; ok {
+func (s *sourcesInfo) append(source, version, path string) {
+	key := sourceKey(source, version)
+	if _, ok := s.set[key]; ok {
 		return
 	}
 	info := &modinfo{
-		source: source,
-		origin: path,
+		source:  source,
+		version: version,
+		origin:  path,
 	}
-	s.set[source] = info
+	s.set[key] = info
 	s.list = append(s.list, info)
 }
 
-func (s *sourcesInfo) delete(source string) {
-	for i, info := range s.list {
-		if info.source == source {
+func (s *sourcesInfo) delete(info *modinfo) {
+	for i, other := range s.list {
+		if other == info {
 			s.list = append(s.list[:i], s.list[i+1:]...)
-			delete(s.set, source)
+			delete(s.set, sourceKey(info.source, info.version))
 			return
 		}
 	}
 }
 
+// sourceKey returns the key of a module source and its version constraint.
+// The same registry module may be used with different versions.
+func sourceKey(source, version string) string {
+	if version == "" {
+		return source
+	}
+	return source + "@" + version
+}
+
 func vendorAll(
 	rootdir string,
 	vendorDir project.Path,
 	tfdir string,
 	report Report,
+	client *registry.Client,
 	events ProgressEventStream,
 ) Report {
 	logger := log.With().
@@ -266,7 +300,7 @@ func vendorAll(
 		Str("dir", tfdir).
 		Logger()
 
-	logger.Trace().Msg("scanning .tf files for additional dependencies")
+	logger.Trace().Msg("scanning Terraform files for additional dependencies")
 
 	sources := newSourcesInfo()
 	originMap := map[string]struct{}{}
@@ -281,7 +315,7 @@ func vendorAll(
 			return filepath.SkipDir
 		}
 
-		if !d.Type().IsRegular() || !strings.HasSuffix(path, ".tf") {
+		if !d.Type().IsRegular() || !tf.IsTerraformFile(path) {
 			return nil
 		}
 
@@ -307,7 +341,7 @@ func vendorAll(
 
 			originMap[path] = struct{}{}
 
-			sources.append(mod.Source, path)
+			sources.append(mod.Source, mod.Version, path)
 		}
 		return nil
 	})
@@ -324,7 +358,14 @@ func vendorAll(
 		modsrc, err := tf.ParseSource(source)
 		if err != nil {
 			report.addIgnored(source, err)
-			sources.delete(source)
+			sources.delete(info)
+			continue
+		}
+
+		modsrc, err = resolveRegistryVersion(client, modsrc, info.version)
+		if err != nil {
+			report.addIgnored(source, errors.E(ErrDownloadMod, err, "found in %s", info.origin))
+			sources.delete(info)
 			continue
 		}
 
@@ -338,7 +379,7 @@ func vendorAll(
 			continue
 		}
 
-		report = vendor(rootdir, vendorDir, modsrc, report, info, events)
+		report = vendor(rootdir, vendorDir, modsrc, report, info, client, events)
 		if v, ok := report.Vendored[modvendor.TargetDir(vendorDir, modsrc)]; ok {
 			info.vendoredAt = modvendor.TargetDir(vendorDir, modsrc)
 			info.subdir = v.Source.Subdir
@@ -366,6 +407,7 @@ func downloadVendor(
 	rootdir string,
 	vendorDir project.Path,
 	modsrc tf.Source,
+	client *registry.Client,
 	events ProgressEventStream,
 ) (string, error) {
 	logger := log.With().
@@ -424,21 +466,6 @@ func downloadVendor(
 		Str("tmTempDir", tmTempDir).
 		Logger()
 
-	logger.Trace().Msg("setting up git wrapper")
-
-	// Same strategy used on the Go toolchain:
-	// - https://github.com/golang/go/blob/2ebe77a2fda1ee9ff6fd9a3e08933ad1ebaea039/src/cmd/go/internal/get/get.go#L129
-
-	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
-	g, err := git.WithConfig(git.Config{
-		WorkingDir:     clonedRepoDir,
-		AllowPorcelain: true,
-		Env:            env,
-	})
-	if err != nil {
-		return "", err
-	}
-
 	event := event.VendorProgress{
 		Message:   "downloading",
 		TargetDir: modvendor.TargetDir(vendorDir, modsrc),
@@ -452,25 +479,27 @@ func downloadVendor(
 			Msg("dropped progress event, event handler is not fast enough or absent")
 	}
 
-	logger.Trace().Msg("cloning to workdir")
+	// moduleDir is the module package dir inside the clone dir.
+	moduleDir := clonedRepoDir
 
-	if err := g.Clone(modsrc.URL, clonedRepoDir); err != nil {
-		return "", err
-	}
-
-	const create = false
+	if modsrc.IsRegistry() {
+		logger.Trace().Msg("downloading from registry to workdir")
 
-	if err := g.Checkout(modsrc.Ref, create); err != nil {
-		return "", errors.E(err, "checking ref %s", modsrc.Ref)
-	}
+		moduleDir, err = downloadRegistryModule(client, modsrc, clonedRepoDir)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		logger.Trace().Msg("cloning to workdir")
 
-	if err := os.RemoveAll(filepath.Join(clonedRepoDir, ".git")); err != nil {
-		return "", errors.E(err, "removing .git dir from cloned repo")
+		if err := gitClone(modsrc.URL, modsrc.Ref, clonedRepoDir); err != nil {
+			return "", err
+		}
 	}
 
 	logger.Trace().Msg("checking for manifest")
 
-	matcher, err := manifest.LoadFileMatcher(clonedRepoDir)
+	matcher, err := manifest.LoadFileMatcher(moduleDir)
 	if err != nil {
 		return "", err
 	}
@@ -482,12 +511,12 @@ func downloadVendor(
 			return true
 		}
 		abspath := filepath.Join(path, entry.Name())
-		relpath := strings.TrimPrefix(abspath, clonedRepoDir+pathSeparator)
+		relpath := strings.TrimPrefix(abspath, moduleDir+pathSeparator)
 		return matcher.Match(strings.Split(relpath, pathSeparator), entry.IsDir())
 	}
 
 	logger.Trace().Msg("copying cloned mod to terramate temp vendor dir")
-	if err := fs.CopyDir(tmTempDir, clonedRepoDir, fileFilter); err != nil {
+	if err := fs.CopyDir(tmTempDir, moduleDir, fileFilter); err != nil {
 		return "", errors.E(err, "copying cloned module")
 	}
 
@@ -504,6 +533,124 @@ func downloadVendor(
 	return modVendorDir, nil
 }
 
+// gitClone clones the git repository url into dir, checking out the given
+// ref if it is not empty. The .git dir of the cloned repository is removed.
+func gitClone(url, ref, dir string) error {
+	// Same strategy used on the Go toolchain:
+	// - https://github.com/golang/go/blob/2ebe77a2fda1ee9ff6fd9a3e08933ad1ebaea039/src/cmd/go/internal/get/get.go#L129
+
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	g, err := git.WithConfig(git.Config{
+		WorkingDir:     dir,
+		AllowPorcelain: true,
+		Env:            env,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := g.Clone(url, dir); err != nil {
+		return err
+	}
+
+	if ref != "" {
+		const create = false
+
+		if err := g.Checkout(ref, create); err != nil {
+			return errors.E(err, "checking ref %s", ref)
+		}
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return errors.E(err, "removing .git dir from cloned repo")
+	}
+	return nil
+}
+
+// registryClient returns the client, or a default one if it's nil.
+func registryClient(client *registry.Client) *registry.Client {
+	if client == nil {
+		return &registry.Client{}
+	}
+	return client
+}
+
+// downloadRegistryModule downloads the registry module version into dir,
+// following the location returned by the registry, which can be a git
+// repository or an HTTP archive. It returns the directory of the module
+// package inside dir, which is different from dir if the location has a
+// //subdir part.
+func downloadRegistryModule(client *registry.Client, modsrc tf.Source, dir string) (string, error) {
+	ctx := context.Background()
+
+	location, err := client.DownloadSource(ctx, modsrc.Registry, modsrc.Ref)
+	if err != nil {
+		return "", err
+	}
+
+	logger := log.With().
+		Str("action", "download.downloadRegistryModule()").
+		Stringer("module", modsrc.Registry).
+		Str("version", modsrc.Ref).
+		Str("location", location).
+		Logger()
+
+	var subdir string
+	if archiveURL, archiveSubdir := registry.SplitSubdir(location); registry.IsArchive(archiveURL) {
+		logger.Trace().Msg("downloading module archive")
+
+		if err := client.DownloadArchive(ctx, archiveURL, dir); err != nil {
+			return "", err
+		}
+		subdir = archiveSubdir
+	} else {
+		gitsrc, err := tf.ParseSource(location)
+		if err != nil || gitsrc.IsRegistry() {
+			return "", errors.E(ErrUnsupportedModSrc,
+				"module %s version %s is downloaded from unsupported location %q",
+				modsrc.Registry, modsrc.Ref, location)
+		}
+
+		logger.Trace().Msg("cloning module repository")
+
+		if err := gitClone(gitsrc.URL, gitsrc.Ref, dir); err != nil {
+			return "", err
+		}
+		subdir = gitsrc.Subdir
+	}
+
+	if subdir == "" {
+		return dir, nil
+	}
+
+	// the subdir may use glob patterns, like "*" to select the single top
+	// level directory of an archive.
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(path.Clean(subdir))))
+	if err != nil {
+		return "", errors.E(err, "resolving subdir %q of %q", subdir, location)
+	}
+	if len(matches) != 1 {
+		return "", errors.E(ErrDownloadMod, "subdir %q of %q matches %d paths, expected one",
+			subdir, location, len(matches))
+	}
+	return matches[0], nil
+}
+
+// resolveRegistryVersion returns the registry module source with the Ref set
+// to the newest module version satisfying the version constraints. Other
+// sources are returned unchanged.
+func resolveRegistryVersion(client *registry.Client, modsrc tf.Source, constraints string) (tf.Source, error) {
+	if !modsrc.IsRegistry() {
+		return modsrc, nil
+	}
+	version, err := client.ResolveVersion(context.Background(), modsrc.Registry, constraints)
+	if err != nil {
+		return tf.Source{}, err
+	}
+	modsrc.Ref = version
+	return modsrc, nil
+}
+
 func patchFiles(rootdir string, files []string, sources *sourcesInfo) error {
 	logger := log.With().
 		Str("action", "download.patchFiles").
@@ -522,55 +669,256 @@ func patchFiles(rootdir string, files []string, sources *sourcesInfo) error {
 			errs.Append(err)
 			continue
 		}
-		parsedFile, diags := hclwrite.ParseConfig(bytes, fname, hhcl.Pos{})
+
+		var patched []byte
+		if tf.IsJSONFile(fname) {
+			patched, err = patchJSONFile(rootdir, fname, bytes, sources)
+		} else {
+			patched, err = patchHCLFile(rootdir, fname, bytes, sources)
+		}
+		if err != nil {
+			errs.Append(err)
+			continue
+		}
+
+		logger.Trace().Msg("successfully patched")
+
+		st, err := os.Stat(fname)
+		errs.Append(err)
+		if err == nil {
+			errs.Append(os.WriteFile(fname, patched, st.Mode()))
+		}
+	}
+	return errs.AsError()
+}
+
+// patchHCLFile returns the file in native syntax with the sources of the
+// vendored modules rewritten to their vendor directories.
+func patchHCLFile(rootdir, fname string, bytes []byte, sources *sourcesInfo) ([]byte, error) {
+	logger := log.With().
+		Str("action", "download.patchHCLFile").
+		Str("filename", fname).
+		Logger()
+
+	parsedFile, diags := hclwrite.ParseConfig(bytes, fname, hhcl.Pos{})
+	if diags.HasErrors() {
+		return nil, errors.E(diags)
+	}
+
+	errs := errors.L()
+	for _, block := range parsedFile.Body().Blocks() {
+		if block.Type() != "module" {
+			continue
+		}
+		if len(block.Labels()) != 1 {
+			continue
+		}
+		source := block.Body().GetAttribute("source")
+		if source == nil {
+			continue
+		}
+
+		sourceString := attrString(source)
+
+		var versionString string
+		if version := block.Body().GetAttribute("version"); version != nil {
+			versionString = attrString(version)
+		}
+
+		info, ok := sources.set[sourceKey(sourceString, versionString)]
+		if !ok || info.vendoredAt.String() == "" {
+			continue
+		}
+
+		logger.Trace().
+			Str("module.source", sourceString).
+			Msg("found relevant module")
+
+		relPath, err := vendoredSource(rootdir, fname, info)
+		if err != nil {
+			errs.Append(err)
+			continue
+		}
+
+		block.Body().SetAttributeValue("source", cty.StringVal(relPath))
+
+		// local modules cannot have a version.
+		block.Body().RemoveAttribute("version")
+	}
+	if err := errs.AsError(); err != nil {
+		return nil, err
+	}
+	return parsedFile.Bytes(), nil
+}
+
+// patchJSONFile is the JSON syntax version of patchHCLFile. There's no JSON
+// writer preserving the file layout, so the source and version values are
+// edited in place using the ranges of the parsed file.
+func patchJSONFile(rootdir, fname string, bytes []byte, sources *sourcesInfo) ([]byte, error) {
+	logger := log.With().
+		Str("action", "download.patchJSONFile").
+		Str("filename", fname).
+		Logger()
+
+	file, diags := hcljson.Parse(bytes, fname)
+	if diags.HasErrors() {
+		return nil, errors.E(diags)
+	}
+
+	content, _, diags := file.Body.PartialContent(&hhcl.BodySchema{
+		Blocks: []hhcl.BlockHeaderSchema{
+			{Type: "module", LabelNames: []string{"name"}},
+		},
+	})
+	if diags.HasErrors() {
+		return nil, errors.E(diags)
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+
+	var edits []edit
+	errs := errors.L()
+	for _, block := range content.Blocks {
+		attrs, _, diags := block.Body.PartialContent(&hhcl.BodySchema{
+			Attributes: []hhcl.AttributeSchema{
+				{Name: "source"},
+				{Name: "version"},
+			},
+		})
 		if diags.HasErrors() {
 			errs.Append(errors.E(diags))
 			continue
 		}
 
-		logger.Trace().Msg("successfully parsed for patching")
+		source, ok := attrs.Attributes["source"]
+		if !ok {
+			continue
+		}
+		sourceString, ok := jsonAttrString(source)
+		if !ok {
+			continue
+		}
 
-		blocks := parsedFile.Body().Blocks()
-		for _, block := range blocks {
-			if block.Type() != "module" {
-				continue
-			}
-			if len(block.Labels()) != 1 {
-				continue
-			}
-			source := block.Body().GetAttribute("source")
-			if source == nil {
-				continue
-			}
+		version, hasVersion := attrs.Attributes["version"]
+		var versionString string
+		if hasVersion {
+			versionString, _ = jsonAttrString(version)
+		}
 
-			sourceString := string(source.Expr().BuildTokens(nil).Bytes())
-			sourceString = strings.TrimSpace(sourceString)
-			sourceString = sourceString[1 : len(sourceString)-1] // unquote
-			// TODO(i4k): improve to support parenthesis.
-
-			if info, ok := sources.set[sourceString]; ok && info.vendoredAt.String() != "" {
-				logger.Trace().
-					Str("module.source", sourceString).
-					Msg("found relevant module")
-
-				relPath, err := filepath.Rel(
-					filepath.Dir(fname), filepath.Join(rootdir, filepath.FromSlash(info.vendoredAt.String())),
-				)
-				if err != nil {
-					errs.Append(err)
-					continue
-				}
-
-				relPath = filepath.Join(relPath, info.subdir)
-				block.Body().SetAttributeValue("source", cty.StringVal(filepath.ToSlash(relPath)))
-			}
+		info, ok := sources.set[sourceKey(sourceString, versionString)]
+		if !ok || info.vendoredAt.String() == "" {
+			continue
 		}
 
-		st, err := os.Stat(fname)
-		errs.Append(err)
-		if err == nil {
-			errs.Append(os.WriteFile(fname, parsedFile.Bytes(), st.Mode()))
+		logger.Trace().
+			Str("module.source", sourceString).
+			Msg("found relevant module")
+
+		relPath, err := vendoredSource(rootdir, fname, info)
+		if err != nil {
+			errs.Append(err)
+			continue
+		}
+
+		quoted, err := json.Marshal(relPath)
+		if err != nil {
+			errs.Append(err)
+			continue
+		}
+
+		rng := source.Expr.Range()
+		edits = append(edits, edit{
+			start: rng.Start.Byte,
+			end:   rng.End.Byte,
+			text:  string(quoted),
+		})
+
+		// local modules cannot have a version.
+		if hasVersion {
+			start, end := jsonMemberRange(bytes, version.NameRange.Start.Byte, version.Expr.Range().End.Byte)
+			edits = append(edits, edit{start: start, end: end})
 		}
 	}
-	return errs.AsError()
+	if err := errs.AsError(); err != nil {
+		return nil, err
+	}
+
+	// the edits are applied from the end of the file so the offsets of the
+	// remaining ones are still valid.
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+
+	patched := append([]byte{}, bytes...)
+	for _, e := range edits {
+		patched = append(patched[:e.start], append([]byte(e.text), patched[e.end:]...)...)
+	}
+	return patched, nil
+}
+
+// jsonMemberRange extends the [start, end) range of an object member so
+// removing it also removes its separating comma and keeps the object valid.
+func jsonMemberRange(bytes []byte, start, end int) (int, int) {
+	isSpace := func(b byte) bool {
+		return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+	}
+
+	next := end
+	for next < len(bytes) && isSpace(bytes[next]) {
+		next++
+	}
+	if next < len(bytes) && bytes[next] == ',' {
+		// removes the member up to the next one.
+		next++
+		for next < len(bytes) && isSpace(bytes[next]) {
+			next++
+		}
+		return start, next
+	}
+
+	// last member: removes the comma separating it from the previous one.
+	prev := start
+	for prev > 0 && isSpace(bytes[prev-1]) {
+		prev--
+	}
+	if prev > 0 && bytes[prev-1] == ',' {
+		return prev - 1, end
+	}
+	return start, end
+}
+
+// vendoredSource returns the source of the vendored module relative to the
+// directory of the file using it.
+func vendoredSource(rootdir, fname string, info *modinfo) (string, error) {
+	relPath, err := filepath.Rel(
+		filepath.Dir(fname), filepath.Join(rootdir, filepath.FromSlash(info.vendoredAt.String())),
+	)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(filepath.Join(relPath, info.subdir)), nil
+}
+
+// jsonAttrString returns the string value of an attribute in JSON syntax.
+func jsonAttrString(attr *hhcl.Attribute) (string, bool) {
+	// an empty context is needed, otherwise JSON strings are not evaluated
+	// as templates and interpolations are returned literally.
+	val, diags := attr.Expr.Value(&hhcl.EvalContext{})
+	if diags.HasErrors() || val.IsNull() || val.Type() != cty.String {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+// attrString returns the unquoted string of a string literal attribute.
+func attrString(attr *hclwrite.Attribute) string {
+	str := string(attr.Expr().BuildTokens(nil).Bytes())
+	str = strings.TrimSpace(str)
+	if len(str) < 2 {
+		return str
+	}
+	return str[1 : len(str)-1] // unquote
 }
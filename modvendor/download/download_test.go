@@ -216,6 +216,84 @@ func TestDownloadVendor(t *testing.T) {
 				"git::{{.}}/another-module//sub/dir?ref=main",
 			},
 		},
+		{
+			name: "module with 1 remote dependency in .tofu file",
+			layout: []string{
+				"g:module-test",
+				"g:another-module",
+			},
+			source: "git::{{.}}/module-test?ref=main",
+			configs: []hclconfig{
+				{
+					repo: "module-test",
+					path: "module-test/main.tofu",
+					data: Module(
+						Labels("test"),
+						Str("source", "git::{{.}}/another-module?ref=main"),
+					),
+				},
+			},
+			wantFiles: map[vendorPathSpec]fmt.Stringer{
+				"git::{{.}}/module-test?ref=main#main.tofu": Module(
+					Labels("test"),
+					Str("source", "{{index . 1}}"),
+				),
+			},
+			wantVendored: []string{
+				"git::{{.}}/module-test?ref=main",
+				"git::{{.}}/another-module?ref=main",
+			},
+		},
+		{
+			name: "module with remote dependencies in .tf.json file",
+			layout: []string{
+				"g:module-test",
+				"g:another-module",
+			},
+			source: "git::{{.}}/module-test?ref=main",
+			configs: []hclconfig{
+				{
+					repo: "module-test",
+					path: "module-test/main.tf.json",
+					data: bytes.NewBufferString(`{
+  "module": {
+    "test": {
+      "source": "git::{{.}}/another-module?ref=main"
+    },
+    "subdir": {
+      "source": "git::{{.}}/another-module//sub/dir?ref=main",
+      "count": 1
+    },
+    "local": {
+      "source": "./modules/local"
+    }
+  }
+}
+`),
+				},
+			},
+			wantFiles: map[vendorPathSpec]fmt.Stringer{
+				"git::{{.}}/module-test?ref=main#main.tf.json": bytes.NewBufferString(`{
+  "module": {
+    "test": {
+      "source": "{{index . 1}}"
+    },
+    "subdir": {
+      "source": "{{index . 1}}/sub/dir",
+      "count": 1
+    },
+    "local": {
+      "source": "./modules/local"
+    }
+  }
+}
+`),
+			},
+			wantVendored: []string{
+				"git::{{.}}/module-test?ref=main",
+				"git::{{.}}/another-module?ref=main",
+			},
+		},
 		{
 			name: "module with N remote dependency and subdir",
 			layout: []string{
@@ -798,7 +876,7 @@ func TestDownloadVendor(t *testing.T) {
 			t.Parallel()
 
 			f := setup(t, tcase)
-			got := download.Vendor(f.rootdir, f.vendorDir, f.modsrc, nil)
+			got := download.Vendor(f.rootdir, f.vendorDir, f.modsrc, nil, nil)
 			want := applyReportTemplate(t, wantReport{
 				Vendored: tcase.wantVendored,
 				Ignored:  tcase.wantIgnored,
@@ -816,7 +894,7 @@ func TestDownloadVendor(t *testing.T) {
 			f := setup(t, tcase)
 
 			events := make(chan event.VendorRequest)
-			reports := download.HandleVendorRequests(f.rootdir, events, nil)
+			reports := download.HandleVendorRequests(f.rootdir, events, nil, nil)
 			events <- event.VendorRequest{
 				VendorDir: f.vendorDir,
 				Source:    f.modsrc,
@@ -923,7 +1001,7 @@ func checkWantedFiles(
 			return err
 		}
 
-		if filepath.Ext(path) != ".tf" {
+		if !tf.IsTerraformFile(path) {
 			return nil
 		}
 
@@ -1008,7 +1086,7 @@ func TestModVendorWithCommitIDRef(t *testing.T) {
 	assert.NoError(t, err)
 
 	vendordir := project.NewPath("/dir/reftest/vendor")
-	got := download.Vendor(rootdir, vendordir, source, nil)
+	got := download.Vendor(rootdir, vendordir, source, nil, nil)
 	assertVendorReport(t, download.Report{
 		Vendored: map[project.Path]download.Vendored{
 			modvendor.TargetDir(vendordir, source): {
@@ -1044,7 +1122,7 @@ func TestModVendorWithRef(t *testing.T) {
 	source := newSource(t, gitURI, ref)
 
 	vendordir := project.NewPath("/vendor")
-	got := download.Vendor(rootdir, vendordir, source, nil)
+	got := download.Vendor(rootdir, vendordir, source, nil, nil)
 	vendoredAt := modvendor.TargetDir(vendordir, source)
 	assertVendorReport(t, download.Report{
 		Vendored: map[project.Path]download.Vendored{
@@ -1082,7 +1160,7 @@ func TestModVendorWithRef(t *testing.T) {
 		Ref:  newRef,
 		Path: path,
 	}
-	got = download.Vendor(rootdir, vendordir, source, nil)
+	got = download.Vendor(rootdir, vendordir, source, nil, nil)
 
 	wantCloneDir = modvendor.TargetDir(vendordir, source)
 	newCloneDir := got.Vendored[wantCloneDir].Dir
@@ -1115,7 +1193,7 @@ func TestModVendorDoesNothingIfRefExists(t *testing.T) {
 	vendordir := project.NewPath("/vendor/fun")
 	clonedir := modvendor.AbsVendorDir(rootdir, vendordir, source)
 	test.MkdirAll(t, clonedir)
-	got := download.Vendor(rootdir, vendordir, source, nil)
+	got := download.Vendor(rootdir, vendordir, source, nil, nil)
 	want := download.Report{
 		Ignored: []download.IgnoredVendor{
 			{
@@ -1139,7 +1217,7 @@ func TestModVendorNoRefFails(t *testing.T) {
 
 	source, err := tf.ParseSource(fmt.Sprintf("git::%s", gitURI))
 	assert.NoError(t, err)
-	report := download.Vendor(rootdir, project.NewPath("/vendor"), source, nil)
+	report := download.Vendor(rootdir, project.NewPath("/vendor"), source, nil, nil)
 
 	assertVendorReport(t, download.Report{
 		Ignored: []download.IgnoredVendor{
@@ -327,7 +327,7 @@ func TestVendorEvents(t *testing.T) {
 				close(eventsHandled)
 			}()
 
-			download.Vendor(s.RootDir(), vendorDir, modsrc, eventsStream)
+			download.Vendor(s.RootDir(), vendorDir, modsrc, nil, eventsStream)
 			close(eventsStream)
 			<-eventsHandled
 
@@ -311,7 +311,7 @@ func TestVendorManifest(t *testing.T) {
 			source := newSource(t, gitURI, "main")
 
 			vendordir := project.NewPath("/vendor")
-			got := download.Vendor(rootdir, vendordir, source, nil)
+			got := download.Vendor(rootdir, vendordir, source, nil, nil)
 			assert.NoError(t, got.Error)
 
 			clonedir := modvendor.AbsVendorDir(rootdir, vendordir, source)
@@ -342,7 +342,7 @@ func testInvalidManifestFails(t *testing.T, configpath string) {
 	gitURL := uri.File(repoSandbox.RootDir())
 	source := newSource(t, gitURL, "main")
 
-	got := download.Vendor(t.TempDir(), project.NewPath("/vendor"), source, nil)
+	got := download.Vendor(t.TempDir(), project.NewPath("/vendor"), source, nil, nil)
 
 	assert.EqualInts(t, 0, len(got.Vendored), "vendored should be empty")
 	assert.EqualInts(t, 1, len(got.Ignored), "should have single ignored")
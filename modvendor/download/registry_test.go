@@ -0,0 +1,137 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package download_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/modvendor"
+	"github.com/terramate-io/terramate/modvendor/download"
+	"github.com/terramate-io/terramate/modvendor/registry"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test"
+	registrytest "github.com/terramate-io/terramate/test/registry"
+	"github.com/terramate-io/terramate/test/sandbox"
+	"github.com/terramate-io/terramate/tf"
+	"go.lsp.dev/uri"
+)
+
+func TestModVendorRegistry(t *testing.T) {
+	t.Parallel()
+
+	depRepo := sandbox.New(t)
+	depRepo.RootEntry().CreateFile("dep.tf", "# dep module")
+	depRepo.Git().CommitAll("add dep module")
+
+	reg := registrytest.New(t, map[string]registrytest.Module{
+		"test/example/null": {
+			Versions: []string{"1.0.0", "1.1.0", "2.0.0"},
+			Location: func(version string) string {
+				return "/archives/example-" + version + ".tar.gz//*"
+			},
+		},
+		"test/dep/null": {
+			Versions: []string{"1.0.0", "1.5.0"},
+			Location: func(string) string {
+				return fmt.Sprintf("git::%s?ref=main", uri.File(depRepo.RootDir()))
+			},
+		},
+	}, map[string]map[string]string{
+		"/archives/example-1.1.0.tar.gz": {
+			"pkg/main.tf": `module "dep" {
+  source  = "{{host}}/test/dep/null"
+  version = "~> 1.0"
+}
+`,
+			"pkg/dep.tf.json": `{
+  "module": {
+    "dep": {
+      "source": "{{host}}/test/dep/null",
+      "version": "~> 1.0"
+    }
+  }
+}
+`,
+			"pkg/modules/sub/sub.tf": "# submodule",
+		},
+	})
+
+	source, err := tf.ParseSource(reg.Host + "/test/example/null//modules/sub")
+	assert.NoError(t, err)
+	source.Ref = "~> 1.0"
+
+	rootdir := t.TempDir()
+	vendordir := project.NewPath("/vendor")
+	got := download.Vendor(rootdir, vendordir, source, reg.Client(), nil)
+	assert.NoError(t, got.Error)
+	if len(got.Ignored) > 0 {
+		t.Fatalf("unexpected ignored modules: %v", got.Ignored)
+	}
+
+	resolved := source
+	resolved.Ref = "1.1.0"
+
+	depSource, err := tf.ParseSource(reg.Host + "/test/dep/null")
+	assert.NoError(t, err)
+	depSource.Ref = "1.5.0"
+
+	assertVendorReport(t, download.Report{
+		Vendored: map[project.Path]download.Vendored{
+			modvendor.TargetDir(vendordir, resolved): {
+				Source: resolved,
+				Dir:    modvendor.TargetDir(vendordir, resolved),
+			},
+			modvendor.TargetDir(vendordir, depSource): {
+				Source: depSource,
+				Dir:    modvendor.TargetDir(vendordir, depSource),
+			},
+		},
+	}, got)
+
+	moddir := modvendor.AbsVendorDir(rootdir, vendordir, resolved)
+	assert.EqualStrings(t, "# submodule", string(test.ReadFile(t, moddir, "modules/sub/sub.tf")))
+	assert.EqualStrings(t, `module "dep" {
+  source = "../../../dep/null/1.5.0"
+}
+`, string(test.ReadFile(t, moddir, "main.tf")))
+	assert.EqualStrings(t, `{
+  "module": {
+    "dep": {
+      "source": "../../../dep/null/1.5.0"
+    }
+  }
+}
+`, string(test.ReadFile(t, moddir, "dep.tf.json")))
+
+	depdir := modvendor.AbsVendorDir(rootdir, vendordir, depSource)
+	assert.EqualStrings(t, "# dep module", string(test.ReadFile(t, depdir, "dep.tf")))
+	assertNoGitDir(t, depdir)
+}
+
+func TestModVendorRegistryNoMatchingVersion(t *testing.T) {
+	t.Parallel()
+
+	reg := registrytest.New(t, map[string]registrytest.Module{
+		"test/example/null": {
+			Versions: []string{"1.0.0"},
+		},
+	}, nil)
+
+	source, err := tf.ParseSource(reg.Host + "/test/example/null")
+	assert.NoError(t, err)
+	source.Ref = ">= 2.0"
+
+	got := download.Vendor(t.TempDir(), project.NewPath("/vendor"), source, reg.Client(), nil)
+	assertVendorReport(t, download.Report{
+		Ignored: []download.IgnoredVendor{
+			{
+				RawSource: source.Raw,
+				Reason:    errors.E(registry.ErrNoMatchingVersion),
+			},
+		},
+	}, got)
+}
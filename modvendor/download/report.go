@@ -62,8 +62,13 @@ func (r Report) String() string {
 
 	for _, source := range sources {
 		vendored := r.Vendored[source]
-		addLine("[+] %s", vendored.Source.URL)
-		addLine("    ref: %s", vendored.Source.Ref)
+		if vendored.Source.IsRegistry() {
+			addLine("[+] %s", vendored.Source.Registry)
+			addLine("    version: %s", vendored.Source.Ref)
+		} else {
+			addLine("[+] %s", vendored.Source.URL)
+			addLine("    ref: %s", vendored.Source.Ref)
+		}
 		addLine("    dir: %s", vendored.Dir)
 	}
 	for _, ignored := range r.Ignored {
@@ -0,0 +1,231 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package registry
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/errors"
+)
+
+// ErrUnsupportedArchive indicates that the module download location is not
+// a supported archive.
+const ErrUnsupportedArchive errors.Kind = "unsupported module archive"
+
+// archiveParam is the query parameter used to force the archive format.
+const archiveParam = "archive"
+
+// SplitSubdir splits a module download location into its URL and the
+// //subdir part, if any. Eg.: https://example.com/mod.tgz//sub?x=y returns
+// https://example.com/mod.tgz?x=y and sub.
+func SplitSubdir(location string) (string, string) {
+	schemeEnd := 0
+	if i := strings.Index(location, "://"); i != -1 {
+		schemeEnd = i + len("://")
+	}
+
+	rest := location[schemeEnd:]
+	query := ""
+	if i := strings.Index(rest, "?"); i != -1 {
+		rest, query = rest[:i], rest[i:]
+	}
+
+	i := strings.Index(rest, "//")
+	if i == -1 {
+		return location, ""
+	}
+	return location[:schemeEnd] + rest[:i] + query, rest[i+2:]
+}
+
+// IsArchive tells if the module download location is an HTTP(S) archive
+// supported by [Client.DownloadArchive].
+func IsArchive(location string) bool {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return archiveFormat(u) != ""
+}
+
+// DownloadArchive downloads the archive at location and extracts it into
+// dir. The archive format is taken from the `archive` query parameter or
+// from the URL path extension. Supported formats are tar.gz, tgz and zip.
+func (c *Client) DownloadArchive(ctx context.Context, location string, dir string) error {
+	u, err := url.Parse(location)
+	if err != nil {
+		return errors.E(ErrUnsupportedArchive, err, "parsing %q", location)
+	}
+	format := archiveFormat(u)
+	if format == "" {
+		return errors.E(ErrUnsupportedArchive, "unknown archive format of %q", location)
+	}
+
+	query := u.Query()
+	query.Del(archiveParam)
+	u.RawQuery = query.Encode()
+
+	logger := log.With().
+		Str("action", "registry.DownloadArchive()").
+		Stringer("url", u).
+		Str("format", format).
+		Logger()
+
+	logger.Trace().Msg("downloading module archive")
+
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return err
+	}
+	data, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.E(ErrRegistry, "unexpected status %s while getting %s", resp.Status, u)
+	}
+
+	logger.Trace().Msg("extracting module archive")
+
+	switch format {
+	case "zip":
+		return extractZip(data, dir)
+	default:
+		return extractTarGz(data, dir)
+	}
+}
+
+func archiveFormat(u *url.URL) string {
+	format := u.Query().Get(archiveParam)
+	if format == "" {
+		switch p := strings.ToLower(u.Path); {
+		case strings.HasSuffix(p, ".tar.gz"):
+			format = "tar.gz"
+		case strings.HasSuffix(p, ".tgz"):
+			format = "tgz"
+		case strings.HasSuffix(p, ".zip"):
+			format = "zip"
+		}
+	}
+	switch format {
+	case "tar.gz", "tgz", "zip":
+		return format
+	}
+	return ""
+}
+
+func extractTarGz(data []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return errors.E(ErrUnsupportedArchive, err, "reading gzip archive")
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.E(ErrUnsupportedArchive, err, "reading tar archive")
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			target, err := archiveTarget(dir, hdr.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return errors.E(err, "creating dir from archive")
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(dir, hdr.Name, hdr.FileInfo().Mode(), tr); err != nil {
+				return err
+			}
+		default:
+			log.Debug().
+				Str("action", "registry.extractTarGz()").
+				Str("name", hdr.Name).
+				Msg("ignoring archive entry which is not a file or directory")
+		}
+	}
+}
+
+func extractZip(data []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return errors.E(ErrUnsupportedArchive, err, "reading zip archive")
+	}
+	for _, file := range zr.File {
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			target, err := archiveTarget(dir, file.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return errors.E(err, "creating dir from archive")
+			}
+		case mode.IsRegular():
+			rc, err := file.Open()
+			if err != nil {
+				return errors.E(ErrUnsupportedArchive, err, "reading %s from zip archive", file.Name)
+			}
+			err = writeArchiveFile(dir, file.Name, mode, rc)
+			err = errors.L(err, rc.Close()).AsError()
+			if err != nil {
+				return err
+			}
+		default:
+			log.Debug().
+				Str("action", "registry.extractZip()").
+				Str("name", file.Name).
+				Msg("ignoring archive entry which is not a file or directory")
+		}
+	}
+	return nil
+}
+
+func writeArchiveFile(dir, name string, mode os.FileMode, r io.Reader) error {
+	target, err := archiveTarget(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return errors.E(err, "creating dir from archive")
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm()|0600)
+	if err != nil {
+		return errors.E(err, "creating file from archive")
+	}
+	_, err = io.Copy(f, r)
+	return errors.L(err, f.Close()).AsError()
+}
+
+// archiveTarget returns the host path of the archive entry name inside dir,
+// failing for entries that would be extracted outside of dir.
+func archiveTarget(dir, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) {
+		return "", errors.E(ErrUnsupportedArchive, "archive entry %q is an absolute path", name)
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return "", errors.E(ErrUnsupportedArchive, "archive entry %q escapes the module dir", name)
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(path.Clean(name))), nil
+}
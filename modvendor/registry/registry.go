@@ -0,0 +1,295 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+// Package registry implements a client for the Terraform Registry module
+// protocol, used to vendor modules published on a Terraform Registry.
+//
+// See: https://developer.hashicorp.com/terraform/internals/module-registry-protocol
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/tf"
+)
+
+const (
+	// ErrRegistry indicates that the registry returned an invalid or
+	// unexpected response.
+	ErrRegistry errors.Kind = "terraform registry error"
+
+	// ErrNoMatchingVersion indicates that no module version satisfies the
+	// version constraints.
+	ErrNoMatchingVersion errors.Kind = "no module version matches the constraints"
+
+	// ErrInvalidConstraint indicates that the version constraints are invalid.
+	ErrInvalidConstraint errors.Kind = "invalid version constraint"
+)
+
+// discoveryPath is the path of the service discovery document of a registry.
+const discoveryPath = "/.well-known/terraform.json"
+
+// modulesService is the service discovery key of the modules protocol.
+const modulesService = "modules.v1"
+
+// Client is a Terraform Registry HTTP client.
+type Client struct {
+	// HTTPClient sets the HTTP client used and then allows for advanced
+	// connection reuse schemes. If not set, a new http.Client is used.
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	services map[string]*url.URL // hostname -> modules service base URL
+}
+
+type versionsResponse struct {
+	Modules []struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	} `json:"modules"`
+}
+
+// ModuleVersions returns all the versions of the module available on the
+// registry.
+func (c *Client) ModuleVersions(ctx context.Context, addr tf.RegistryAddress) ([]string, error) {
+	u, err := c.moduleURL(ctx, addr, "versions")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.E(ErrRegistry, "unexpected status %s while getting %s", resp.Status, u)
+	}
+
+	var parsed versionsResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, errors.E(ErrRegistry, err, "parsing versions of %s", addr)
+	}
+
+	var versions []string
+	for _, mod := range parsed.Modules {
+		for _, v := range mod.Versions {
+			versions = append(versions, v.Version)
+		}
+	}
+	return versions, nil
+}
+
+// ResolveVersion returns the newest version of the module satisfying the
+// given version constraints, using the same syntax as the module `version`
+// attribute. Prerelease versions are only selected if they are explicitly
+// required by the constraints. An empty constraint selects the newest
+// version.
+func (c *Client) ResolveVersion(ctx context.Context, addr tf.RegistryAddress, constraints string) (string, error) {
+	var constraint version.Constraints
+	if strings.TrimSpace(constraints) != "" {
+		var err error
+		constraint, err = version.NewConstraint(constraints)
+		if err != nil {
+			return "", errors.E(ErrInvalidConstraint, err, "module %s", addr)
+		}
+	}
+
+	available, err := c.ModuleVersions(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates version.Collection
+	for _, raw := range available {
+		v, err := version.NewVersion(raw)
+		if err != nil {
+			log.Debug().
+				Str("action", "registry.ResolveVersion()").
+				Stringer("module", addr).
+				Str("version", raw).
+				Msg("ignoring invalid module version")
+			continue
+		}
+		if constraint == nil && v.Prerelease() != "" {
+			continue
+		}
+		if constraint != nil && !constraint.Check(v) {
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+
+	if len(candidates) == 0 {
+		return "", errors.E(ErrNoMatchingVersion, "module %s with constraints %q", addr, constraints)
+	}
+
+	sort.Sort(candidates)
+	return candidates[len(candidates)-1].Original(), nil
+}
+
+// DownloadSource returns the location of the given module version, as
+// returned by the registry in the X-Terraform-Get header. The location is
+// another module source, like a git or an HTTP archive URL. Relative
+// locations are resolved against the download URL.
+func (c *Client) DownloadSource(ctx context.Context, addr tf.RegistryAddress, version string) (string, error) {
+	u, err := c.moduleURL(ctx, addr, version, "download")
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	data, err := readBody(resp)
+	if err != nil {
+		return "", err
+	}
+
+	var location string
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		location = resp.Header.Get("X-Terraform-Get")
+	case http.StatusOK:
+		location = resp.Header.Get("X-Terraform-Get")
+		if location == "" {
+			// newer registries may send the location in the body.
+			var body struct {
+				Location string `json:"location"`
+			}
+			if err := json.Unmarshal(data, &body); err == nil {
+				location = body.Location
+			}
+		}
+	default:
+		return "", errors.E(ErrRegistry, "unexpected status %s while getting %s", resp.Status, u)
+	}
+
+	if location == "" {
+		return "", errors.E(ErrRegistry, "no download location for module %s version %s", addr, version)
+	}
+
+	if isRelativeLocation(location) {
+		ref, err := url.Parse(location)
+		if err != nil {
+			return "", errors.E(ErrRegistry, err, "parsing download location %q", location)
+		}
+		location = resp.Request.URL.ResolveReference(ref).String()
+	}
+	return location, nil
+}
+
+// moduleURL returns the URL of a module endpoint on the registry modules
+// service.
+func (c *Client) moduleURL(ctx context.Context, addr tf.RegistryAddress, elems ...string) (*url.URL, error) {
+	base, err := c.modulesBaseURL(ctx, addr.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	parts := append([]string{addr.Namespace, addr.Name, addr.Provider}, elems...)
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	ref, err := url.Parse(strings.Join(parts, "/"))
+	if err != nil {
+		return nil, errors.E(ErrRegistry, err)
+	}
+	return base.ResolveReference(ref), nil
+}
+
+// modulesBaseURL discovers the modules service of the registry at hostname.
+func (c *Client) modulesBaseURL(ctx context.Context, hostname string) (*url.URL, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if base, ok := c.services[hostname]; ok {
+		return base, nil
+	}
+
+	discoveryURL := &url.URL{Scheme: "https", Host: hostname, Path: discoveryPath}
+	resp, err := c.get(ctx, discoveryURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.E(ErrRegistry, "unexpected status %s while discovering services of %s", resp.Status, hostname)
+	}
+
+	var services map[string]interface{}
+	if err := json.Unmarshal(data, &services); err != nil {
+		return nil, errors.E(ErrRegistry, err, "parsing service discovery of %s", hostname)
+	}
+	rawBase, ok := services[modulesService].(string)
+	if !ok {
+		return nil, errors.E(ErrRegistry, "host %s does not provide a modules registry", hostname)
+	}
+	if !strings.HasSuffix(rawBase, "/") {
+		rawBase += "/"
+	}
+	ref, err := url.Parse(rawBase)
+	if err != nil {
+		return nil, errors.E(ErrRegistry, err, "parsing %s service URL of %s", modulesService, hostname)
+	}
+
+	base := resp.Request.URL.ResolveReference(ref)
+	if c.services == nil {
+		c.services = map[string]*url.URL{}
+	}
+	c.services[hostname] = base
+	return base, nil
+}
+
+func (c *Client) get(ctx context.Context, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.E(err, "creating request")
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, errors.E(ErrRegistry, err, "requesting GET %s", u)
+	}
+	return resp, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{}
+	}
+	return c.HTTPClient
+}
+
+func readBody(resp *http.Response) (data []byte, err error) {
+	defer func() {
+		err = errors.L(err, resp.Body.Close()).AsError()
+	}()
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.E(err, "reading response body")
+	}
+	return data, nil
+}
+
+func isRelativeLocation(location string) bool {
+	return strings.HasPrefix(location, "/") ||
+		strings.HasPrefix(location, "./") ||
+		strings.HasPrefix(location, "../")
+}
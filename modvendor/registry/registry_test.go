@@ -0,0 +1,214 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package registry_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/modvendor/registry"
+	registrytest "github.com/terramate-io/terramate/test/registry"
+	"github.com/terramate-io/terramate/tf"
+)
+
+func TestRegistryResolveVersion(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name        string
+		constraints string
+		want        string
+		wantErr     error
+	}
+
+	versions := []string{"1.0.0", "1.2.0", "1.10.1", "2.0.0-beta1", "2.0.0", "2.1.0"}
+
+	for _, tc := range []testcase{
+		{
+			name: "empty constraint selects newest version",
+			want: "2.1.0",
+		},
+		{
+			name:        "exact version",
+			constraints: "1.2.0",
+			want:        "1.2.0",
+		},
+		{
+			name:        "pessimistic constraint",
+			constraints: "~> 1.2",
+			want:        "1.10.1",
+		},
+		{
+			name:        "multiple constraints",
+			constraints: ">= 1.0, < 2.0.0",
+			want:        "1.10.1",
+		},
+		{
+			name:        "prerelease is selected only if required",
+			constraints: "2.0.0-beta1",
+			want:        "2.0.0-beta1",
+		},
+		{
+			name:        "no matching version",
+			constraints: "> 3",
+			wantErr:     errors.E(registry.ErrNoMatchingVersion),
+		},
+		{
+			name:        "invalid constraint",
+			constraints: "not a version",
+			wantErr:     errors.E(registry.ErrInvalidConstraint),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reg, addr := newExampleRegistry(t, versions)
+
+			got, err := reg.Client().ResolveVersion(context.Background(), addr, tc.constraints)
+			assert.IsError(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			assert.EqualStrings(t, tc.want, got)
+		})
+	}
+}
+
+func TestRegistryDownloadSource(t *testing.T) {
+	t.Parallel()
+
+	reg, addr := newExampleRegistry(t, []string{"1.0.0"})
+	client := reg.Client()
+
+	got, err := client.DownloadSource(context.Background(), addr, "1.0.0")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, reg.URL+"/archives/1.0.0.tar.gz//modules/sub", got)
+
+	_, err = client.DownloadSource(context.Background(), addr, "9.9.9")
+	assert.IsError(t, err, errors.E(registry.ErrRegistry))
+}
+
+func TestRegistryDownloadArchive(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"main.tf":            "# main",
+		"modules/sub/sub.tf": "# sub",
+	}
+
+	type testcase struct {
+		name    string
+		path    string
+		archive []byte
+		wantErr error
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:    "tar.gz extension",
+			path:    "/mod.tar.gz",
+			archive: registrytest.TarGz(t, files),
+		},
+		{
+			name:    "tgz extension",
+			path:    "/mod.tgz",
+			archive: registrytest.TarGz(t, files),
+		},
+		{
+			name:    "zip extension",
+			path:    "/mod.zip",
+			archive: registrytest.Zip(t, files),
+		},
+		{
+			name:    "archive query param",
+			path:    "/download?archive=zip",
+			archive: registrytest.Zip(t, files),
+		},
+		{
+			name:    "unknown format",
+			path:    "/mod.rar",
+			wantErr: errors.E(registry.ErrUnsupportedArchive),
+		},
+		{
+			name:    "entries escaping the module dir",
+			path:    "/mod.tar.gz",
+			archive: registrytest.TarGz(t, map[string]string{"../evil.tf": "# evil"}),
+			wantErr: errors.E(registry.ErrUnsupportedArchive),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Has("archive") {
+					t.Errorf("archive param must not be sent to the server")
+				}
+				_, _ = w.Write(tc.archive)
+			}))
+			t.Cleanup(srv.Close)
+
+			client := &registry.Client{HTTPClient: srv.Client()}
+			dir := t.TempDir()
+
+			err := client.DownloadArchive(context.Background(), srv.URL+tc.path, dir)
+			assert.IsError(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			for name, want := range files {
+				got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				assert.NoError(t, err)
+				assert.EqualStrings(t, want, string(got))
+			}
+		})
+	}
+}
+
+func TestRegistrySplitSubdir(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		location, url, subdir string
+	}{
+		{"https://example.com/mod.tgz", "https://example.com/mod.tgz", ""},
+		{"https://example.com/mod.tgz//sub/dir", "https://example.com/mod.tgz", "sub/dir"},
+		{"https://example.com/mod//sub?archive=zip", "https://example.com/mod?archive=zip", "sub"},
+		{"https://example.com/mod//*?archive=tar.gz", "https://example.com/mod?archive=tar.gz", "*"},
+	} {
+		url, subdir := registry.SplitSubdir(tc.location)
+		assert.EqualStrings(t, tc.url, url)
+		assert.EqualStrings(t, tc.subdir, subdir)
+	}
+}
+
+// newExampleRegistry starts a registry serving the versions of the
+// test/example/null module, returning it and the address of the module.
+// Every version is downloaded from an archive URL relative to the registry.
+func newExampleRegistry(t *testing.T, versions []string) (*registrytest.Registry, tf.RegistryAddress) {
+	t.Helper()
+
+	reg := registrytest.New(t, map[string]registrytest.Module{
+		"test/example/null": {
+			Versions: versions,
+			Location: func(version string) string {
+				return "/archives/" + version + ".tar.gz//modules/sub"
+			},
+		},
+	}, nil)
+
+	return reg, tf.RegistryAddress{
+		Hostname:  reg.Host,
+		Namespace: "test",
+		Name:      "example",
+		Provider:  "null",
+	}
+}
@@ -5,12 +5,16 @@ package run
 
 import (
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl/ast"
 	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/hcl/info"
 	"github.com/terramate-io/terramate/stdlib"
 
 	"github.com/rs/zerolog/log"
@@ -28,26 +32,122 @@ const (
 	// ErrInvalidEnvVarType indicates the env var attribute
 	// has an invalid type.
 	ErrInvalidEnvVarType errors.Kind = "invalid environment variable type"
+
+	// ErrEnvVarSource indicates the value of an environment variable could
+	// not be read from its file or host environment variable.
+	ErrEnvVarSource errors.Kind = "reading environment variable value"
 )
 
+// RedactedValue replaces the values of sensitive environment variables in
+// the output of Terramate.
+const RedactedValue = "(sensitive value)"
+
 // EnvVars represents a set of environment variables to be used
 // when running commands. Each string follows the same format used
 // on os.Environ and can be used to set env on exec.Cmd.
 type EnvVars []string
 
+// EnvVar is an environment variable defined for a stack.
+type EnvVar struct {
+	// Name of the environment variable.
+	Name string
+
+	// Value is the evaluated value of the environment variable.
+	Value string
+
+	// Origin is the range of the attribute defining the environment variable.
+	Origin info.Range
+
+	// Sensitive tells if the value must be redacted from the output of
+	// Terramate. It's still exported to the commands.
+	Sensitive bool
+}
+
+// String returns the environment variable in the format used by os.Environ.
+func (v EnvVar) String() string {
+	return v.Name + "=" + v.Value
+}
+
+// Redacted returns the environment variable in the format used by
+// os.Environ, with the value replaced by [RedactedValue] if it's sensitive.
+func (v EnvVar) Redacted() string {
+	if v.Sensitive {
+		return v.Name + "=" + RedactedValue
+	}
+	return v.String()
+}
+
+// Redact replaces the values of the sensitive environment variables found
+// in str with [RedactedValue].
+func Redact(vars []EnvVar, str string) string {
+	var secrets []string
+	for _, v := range vars {
+		if v.Sensitive && v.Value != "" {
+			secrets = append(secrets, v.Value)
+		}
+	}
+	// longer values first, so a secret containing another one is fully
+	// redacted.
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	for _, secret := range secrets {
+		str = strings.ReplaceAll(str, secret, RedactedValue)
+	}
+	return str
+}
+
 // LoadEnv will load environment variables to be exported when running any command
 // inside the given stack. The order of the env vars is guaranteed to be the same
 // and is ordered lexicographically.
 func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
+	vars, err := LoadEnvInfo(root, st)
+	if err != nil {
+		return nil, err
+	}
+	if vars == nil {
+		return nil, nil
+	}
+	envVars := EnvVars{}
+	for _, v := range vars {
+		envVars = append(envVars, v.String())
+	}
+	return envVars, nil
+}
+
+// LoadEnvInfo loads the same environment variables as [LoadEnv], together
+// with the attribute defining each of them.
+//
+// The environment variables are defined by the terramate.config.run.env block
+// and by the env blocks of the stack directory and all its parent directories.
+// The env blocks are merged from the project root to the stack directory, with
+// the definitions of child directories overriding the ones of their parents.
+//
+// Each attribute evaluates to the string value of the variable or to an
+// object with one of the keys:
+//
+//   - value: the string value of the variable.
+//   - from_file: the file containing the value, relative to the directory of
+//     the attribute's configuration file or, if it starts with /, to the
+//     project root. A single trailing newline is removed from the value.
+//   - from_env: the name of the host environment variable with the value.
+//
+// And optionally the sensitive key, which marks the value as sensitive.
+func LoadEnvInfo(root *config.Root, st *config.Stack) ([]EnvVar, error) {
 	logger := log.With().
-		Str("action", "run.Env()").
+		Str("action", "run.LoadEnvInfo()").
 		Str("root", root.HostDir()).
 		Stringer("stack", st).
 		Logger()
 
 	logger.Trace().Msg("checking if we have run env config")
 
-	if !root.Tree().Node.HasRunEnv() {
+	attrs, err := envAttributes(root, st)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(attrs) == 0 {
 		logger.Trace().Msg("no run env config found, nothing to do")
 		return nil, nil
 	}
@@ -66,13 +166,12 @@ func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 	evalctx.SetNamespace("global", globalsReport.Globals.AsValueMap())
 	evalctx.SetEnv(os.Environ())
 
-	envVars := EnvVars{}
-
-	attrs := root.Tree().Node.Terramate.Config.Run.Env.Attributes.SortedList()
+	envVars := []EnvVar{}
 
 	for _, attr := range attrs {
 		logger = logger.With().
 			Str("attribute", attr.Name).
+			Stringer("origin", attr.Range).
 			Logger()
 
 		logger.Trace().Msg("evaluating")
@@ -84,15 +183,11 @@ func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 
 		logger.Trace().Msg("checking evaluated value type")
 
-		if val.Type() != cty.String {
-			return nil, errors.E(
-				ErrInvalidEnvVarType,
-				attr.Range,
-				"attr has type %s but must be string",
-				val.Type().FriendlyName(),
-			)
+		envVar, err := newEnvVar(root, attr, val)
+		if err != nil {
+			return nil, err
 		}
-		envVars = append(envVars, attr.Name+"="+val.AsString())
+		envVars = append(envVars, envVar)
 
 		logger.Trace().Msg("env var loaded")
 	}
@@ -100,6 +195,122 @@ func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 	return envVars, nil
 }
 
+// newEnvVar creates the environment variable from the evaluated value of its
+// attribute.
+func newEnvVar(root *config.Root, attr ast.Attribute, val cty.Value) (EnvVar, error) {
+	envVar := EnvVar{
+		Name:   attr.Name,
+		Origin: attr.Range,
+	}
+
+	if val.Type() == cty.String {
+		envVar.Value = val.AsString()
+		return envVar, nil
+	}
+
+	if !val.Type().IsObjectType() {
+		return EnvVar{}, errors.E(
+			ErrInvalidEnvVarType,
+			attr.Range,
+			"attr has type %s but must be string or object",
+			val.Type().FriendlyName(),
+		)
+	}
+
+	var sources []string
+	for name, attrVal := range val.AsValueMap() {
+		switch name {
+		case "value", "from_file", "from_env":
+			if attrVal.Type() != cty.String || attrVal.IsNull() {
+				return EnvVar{}, errors.E(ErrInvalidEnvVarType, attr.Range,
+					"%s has type %s but must be string", name, attrVal.Type().FriendlyName())
+			}
+			sources = append(sources, name)
+		case "sensitive":
+			if attrVal.Type() != cty.Bool || attrVal.IsNull() {
+				return EnvVar{}, errors.E(ErrInvalidEnvVarType, attr.Range,
+					"sensitive has type %s but must be bool", attrVal.Type().FriendlyName())
+			}
+			envVar.Sensitive = attrVal.True()
+		default:
+			return EnvVar{}, errors.E(ErrInvalidEnvVarType, attr.Range,
+				"unrecognized key %q, expected value, from_file, from_env or sensitive", name)
+		}
+	}
+
+	if len(sources) != 1 {
+		return EnvVar{}, errors.E(ErrInvalidEnvVarType, attr.Range,
+			"exactly one of value, from_file or from_env must be set")
+	}
+
+	source := val.GetAttr(sources[0]).AsString()
+	switch sources[0] {
+	case "value":
+		envVar.Value = source
+	case "from_file":
+		path := source
+		if strings.HasPrefix(path, "/") {
+			path = filepath.Join(root.HostDir(), filepath.FromSlash(path))
+		} else {
+			path = filepath.Join(filepath.Dir(attr.Range.HostPath()), filepath.FromSlash(path))
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return EnvVar{}, errors.E(ErrEnvVarSource, attr.Range, err,
+				"reading value from file %s", source)
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		envVar.Value = strings.TrimSuffix(value, "\r")
+	case "from_env":
+		value, ok := os.LookupEnv(source)
+		if !ok {
+			return EnvVar{}, errors.E(ErrEnvVarSource, attr.Range,
+				"host environment variable %s is not set", source)
+		}
+		envVar.Value = value
+	}
+	return envVar, nil
+}
+
+// envAttributes returns the env attributes that apply to the stack, sorted
+// by name, with the attributes of child directories overriding the ones of
+// their parents.
+func envAttributes(root *config.Root, st *config.Stack) ([]ast.Attribute, error) {
+	tree, ok := root.Lookup(st.Dir)
+	if !ok {
+		return nil, errors.E("stack %s not found in the configuration tree", st.Dir)
+	}
+
+	var nodes []*config.Tree
+	for node := tree; node != nil; node = node.Parent {
+		nodes = append(nodes, node)
+	}
+
+	attrs := map[string]ast.Attribute{}
+	if root.Tree().Node.HasRunEnv() {
+		for name, attr := range root.Tree().Node.Terramate.Config.Run.Env.Attributes {
+			attrs[name] = attr
+		}
+	}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if !nodes[i].Node.HasEnv() {
+			continue
+		}
+		for name, attr := range nodes[i].Node.Env.Attributes {
+			attrs[name] = attr
+		}
+	}
+
+	sorted := make([]ast.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted, nil
+}
+
 func getEnv(key string, environ []string) (string, bool) {
 	for i := len(environ) - 1; i >= 0; i-- {
 		env := environ[i]
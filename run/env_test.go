@@ -132,6 +132,262 @@ func TestLoadRunEnv(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "env blocks are merged from the root to the stack",
+			layout: []string{
+				"s:stacks/stack-1",
+				"s:stacks/stack-2",
+				"s:other",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: runEnvCfg(
+						Str("ACCOUNT", "root"),
+						Str("REGION", "us-east-1"),
+						Str("ROOT", "root"),
+					),
+				},
+				{
+					path: "/",
+					add: Env(
+						Str("REGION", "eu-west-1"),
+					),
+				},
+				{
+					path: "/stacks",
+					add: Env(
+						Str("ACCOUNT", "stacks"),
+						Expr("STACK", "terramate.stack.name"),
+					),
+				},
+				{
+					path: "/stacks/stack-2",
+					add: Doc(
+						Globals(
+							Str("account", "stack-2"),
+						),
+						Env(
+							Expr("ACCOUNT", "global.account"),
+						),
+					),
+				},
+			},
+			want: map[string]result{
+				"stacks/stack-1": {
+					env: run.EnvVars{
+						"ACCOUNT=stacks",
+						"REGION=eu-west-1",
+						"ROOT=root",
+						"STACK=stack-1",
+					},
+				},
+				"stacks/stack-2": {
+					env: run.EnvVars{
+						"ACCOUNT=stack-2",
+						"REGION=eu-west-1",
+						"ROOT=root",
+						"STACK=stack-2",
+					},
+				},
+				"other": {
+					env: run.EnvVars{
+						"ACCOUNT=root",
+						"REGION=eu-west-1",
+						"ROOT=root",
+					},
+				},
+			},
+		},
+		{
+			name: "env blocks without run env config",
+			layout: []string{
+				"s:stack",
+				"s:stack/child",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack/child",
+					add: Env(
+						Str("CHILD", "child"),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {},
+				"stack/child": {
+					env: run.EnvVars{
+						"CHILD=child",
+					},
+				},
+			},
+		},
+		{
+			name: "fails on env attribute redefined in the same directory",
+			layout: []string{
+				"s:stack",
+				"f:stack/env.tm:env {\n  A = \"a\"\n}\n",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: Env(
+						Str("A", "b"),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {
+					cfgerr: errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "env values from objects",
+			hostenv: map[string]string{
+				"TESTING_RUN_ENV_SECRET": "secret",
+			},
+			layout: []string{
+				"s:stacks/stack",
+				"f:secrets/token:token\n",
+				"f:stacks/stack/token:stack token",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stacks",
+					add: Env(
+						Expr("FROM_VALUE", `{ value = "plain" }`),
+						Expr("FROM_ENV", `{ from_env = "TESTING_RUN_ENV_SECRET", sensitive = true }`),
+						Expr("FROM_ABS_FILE", `{ from_file = "/secrets/token" }`),
+						Expr("FROM_REL_FILE", `{ from_file = "stack/token", sensitive = false }`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stacks/stack": {
+					env: run.EnvVars{
+						"FROM_ABS_FILE=token",
+						"FROM_ENV=secret",
+						"FROM_REL_FILE=stack token",
+						"FROM_VALUE=plain",
+					},
+				},
+			},
+		},
+		{
+			name: "fails if env object has no source",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Env(
+						Expr("env", `{ sensitive = true }`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {
+					enverr: errors.E(run.ErrInvalidEnvVarType),
+				},
+			},
+		},
+		{
+			name: "fails if env object has many sources",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Env(
+						Expr("env", `{ value = "a", from_env = "HOME" }`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {
+					enverr: errors.E(run.ErrInvalidEnvVarType),
+				},
+			},
+		},
+		{
+			name: "fails if env object has unknown keys",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Env(
+						Expr("env", `{ value = "a", secret = true }`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {
+					enverr: errors.E(run.ErrInvalidEnvVarType),
+				},
+			},
+		},
+		{
+			name: "fails if sensitive is not a bool",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Env(
+						Expr("env", `{ value = "a", sensitive = "yes" }`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {
+					enverr: errors.E(run.ErrInvalidEnvVarType),
+				},
+			},
+		},
+		{
+			name: "fails if env file does not exist",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Env(
+						Expr("env", `{ from_file = "missing" }`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {
+					enverr: errors.E(run.ErrEnvVarSource),
+				},
+			},
+		},
+		{
+			name: "fails if host env var is not set",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Env(
+						Expr("env", `{ from_env = "TESTING_RUN_ENV_UNSET_VAR" }`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {
+					enverr: errors.E(run.ErrEnvVarSource),
+				},
+			},
+		},
 		{
 			name: "fails on invalid root config",
 			layout: []string{
@@ -247,6 +503,50 @@ func TestLoadRunEnv(t *testing.T) {
 	}
 }
 
+func TestLoadRunEnvInfo(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/stack",
+		"f:terramate.tm:terramate {\n  config {\n    run {\n      env {\n        A = \"root\"\n        B = \"root\"\n      }\n    }\n  }\n}\n",
+		"f:stacks/env.tm:env {\n  B = \"stacks\"\n}\n",
+	})
+
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+	stack, err := config.LoadStack(root, project.NewPath("/stacks/stack"))
+	assert.NoError(t, err)
+
+	vars, err := run.LoadEnvInfo(root, stack)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 2, len(vars))
+
+	assert.EqualStrings(t, "A=root", vars[0].String())
+	assert.EqualStrings(t, "/terramate.tm:5,9-19", vars[0].Origin.String())
+	assert.EqualStrings(t, "B=stacks", vars[1].String())
+	assert.EqualStrings(t, "/stacks/env.tm:2,3-15", vars[1].Origin.String())
+}
+
+func TestRedactSensitiveEnv(t *testing.T) {
+	t.Parallel()
+
+	vars := []run.EnvVar{
+		{Name: "PLAIN", Value: "plain"},
+		{Name: "TOKEN", Value: "abc", Sensitive: true},
+		{Name: "LONG_TOKEN", Value: "abcdef", Sensitive: true},
+		{Name: "EMPTY", Value: "", Sensitive: true},
+	}
+
+	assert.EqualStrings(t,
+		"deploy --token (sensitive value) --key (sensitive value) --name plain",
+		run.Redact(vars, "deploy --token abc --key abcdef --name plain"))
+
+	assert.EqualStrings(t, "PLAIN=plain", vars[0].Redacted())
+	assert.EqualStrings(t, "TOKEN=(sensitive value)", vars[1].Redacted())
+	assert.EqualStrings(t, "TOKEN=abc", vars[1].String())
+}
+
 func init() {
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 }
@@ -9,6 +9,8 @@ import (
 	"os/exec"
 	"os/signal"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/config"
@@ -21,12 +23,94 @@ const (
 	ErrFailed errors.Kind = "execution failed"
 	// ErrCanceled represents the error when the execution was canceled.
 	ErrCanceled errors.Kind = "execution canceled"
+	// ErrTimeout represents the error when the execution exceeded its timeout.
+	ErrTimeout errors.Kind = "execution timed out"
 )
 
 // ExecContext declares an stack execution context.
 type ExecContext struct {
 	Stack *config.Stack
 	Cmd   []string
+
+	// Env is the run environment of the stack, as loaded by [LoadEnvInfo].
+	// The sensitive values are redacted from the command line in the logs,
+	// errors and hooks.
+	Env []EnvVar
+
+	// DependsOn is the list of stacks that must finish executing before this
+	// stack is started. Stacks which are not part of the execution are ignored.
+	DependsOn project.Paths
+
+	// Timeout is the maximum duration of the command. When exceeded, the
+	// command and all its child processes are killed. Zero means no timeout.
+	Timeout time.Duration
+
+	// Deadline is the time when the command is killed, like Timeout, and
+	// after which the stack is no longer started. It's shared by all stacks
+	// to bound the duration of the whole run. Zero means no deadline.
+	Deadline time.Time
+
+	// Retry defines when the command is retried if it fails.
+	Retry RetryPolicy
+}
+
+// RetryPolicy defines when a failed command must be retried.
+type RetryPolicy struct {
+	// Attempts is the maximum number of retries.
+	Attempts int
+
+	// ExitCodes is the list of exit codes which cause a retry.
+	ExitCodes []int
+
+	// Backoff is the time waited before the first retry. It doubles on each
+	// subsequent retry.
+	Backoff time.Duration
+}
+
+// shouldRetry tells if a command that failed with err on the given attempt
+// must be retried.
+func (r RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if attempt > r.Attempts {
+		return false
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	for _, code := range r.ExitCodes {
+		if exitErr.ExitCode() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// timeout returns the maximum duration of a command started at the given time,
+// considering both the Timeout and the Deadline, and if the duration is
+// bounded by the deadline. Zero means no timeout.
+func (ec ExecContext) timeout(now time.Time) (time.Duration, bool) {
+	if ec.Deadline.IsZero() {
+		return ec.Timeout, false
+	}
+	untilDeadline := ec.Deadline.Sub(now)
+	if ec.Timeout > 0 && ec.Timeout < untilDeadline {
+		return ec.Timeout, false
+	}
+	return untilDeadline, true
+}
+
+// deadlineExceeded tells if the stack must not be started anymore.
+func (ec ExecContext) deadlineExceeded(now time.Time) bool {
+	return !ec.Deadline.IsZero() && !now.Before(ec.Deadline)
+}
+
+// backoff returns the time to wait before the given retry attempt.
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	d := r.Backoff
+	for i := 2; i < attempt; i++ {
+		d *= 2
+	}
+	return d
 }
 
 // ExecAll will execute the list of RunStack definitions. A RunStack
@@ -35,158 +119,376 @@ type ExecContext struct {
 // for signal handling will be changed so we can wait for the child
 // process to exit before exiting Terramate.
 //
+// At most parallel stacks are executed at the same time. A stack is only
+// started after all stacks it depends on finished executing. If parallel is
+// less than or equal to 1 then stacks are executed one at a time, in the given
+// order.
+//
 // If continue on error is true this function will continue to execute
 // commands on stacks even in face of failures, returning an error.L with all errors.
 // If continue on error is false it will return as soon as it finds an error,
-// returning a list with a single error inside.
+// returning a list with a single error inside. In parallel mode, the stacks
+// already running are waited before returning.
+//
+// The stdout and stderr of the commands are obtained from the given output,
+// which is closed for each stack after its command finishes.
+//
+// The before and after callbacks are always called from the goroutine
+// which called ExecAll, so they don't need to be concurrency safe.
 func ExecAll(
 	root *config.Root,
 	runStacks []ExecContext,
 	stdin io.Reader,
-	stdout io.Writer,
-	stderr io.Writer,
+	output Output,
 	continueOnError bool,
+	parallel int,
 	before func(s *config.Stack, cmd string),
 	after func(s *config.Stack, err error),
 ) error {
 	logger := log.With().
 		Str("action", "run.ExecAll()").
+		Int("parallel", parallel).
 		Logger()
 
 	const signalsBuffer = 10
 
+	if parallel < 1 {
+		parallel = 1
+	}
+
 	errs := errors.L()
-	stackEnvs := map[project.Path]EnvVars{}
 
-	logger.Trace().Msg("loading stacks run environment variables")
-	for _, elem := range runStacks {
-		env, err := LoadEnv(root, elem.Stack)
-		errs.Append(err)
-		stackEnvs[elem.Stack.Dir] = env
+	signals := make(chan os.Signal, signalsBuffer)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+
+	isExecuted := map[project.Path]bool{}
+	for _, run := range runStacks {
+		isExecuted[run.Stack.Dir] = true
 	}
 
-	if errs.AsError() != nil {
-		return errs.AsError()
+	finished := map[project.Path]bool{}
+	pending := make([]int, len(runStacks))
+	for i := range runStacks {
+		pending[i] = i
 	}
 
-	logger.Trace().Msg("loaded stacks run environment variables, running commands")
+	isReady := func(run ExecContext) bool {
+		for _, dep := range run.DependsOn {
+			if isExecuted[dep] && !finished[dep] {
+				return false
+			}
+		}
+		return true
+	}
 
-	signals := make(chan os.Signal, signalsBuffer)
-	signal.Notify(signals, os.Interrupt)
-	defer signal.Reset(os.Interrupt)
+	// nextReady returns the position in the pending list of the first stack
+	// that can be started. If nothing is running and no stack is ready then
+	// the first pending stack is returned, so a dependency list inconsistent
+	// with the given order never blocks the execution.
+	nextReady := func(running int) int {
+		for pos, i := range pending {
+			if isReady(runStacks[i]) {
+				return pos
+			}
+		}
+		if running == 0 && len(pending) > 0 {
+			return 0
+		}
+		return -1
+	}
 
-	cmds := make(chan *exec.Cmd)
-	defer close(cmds)
+	cancelPending := func() {
+		for _, i := range pending {
+			after(runStacks[i].Stack, errors.E(ErrCanceled))
+		}
+		pending = nil
+	}
 
-	cancelStacks := func(stacks []ExecContext) {
-		for _, run := range stacks {
-			after(run.Stack, errors.E(ErrCanceled))
+	results := make(chan cmdResult)
+	retries := make(chan int)
+	running := map[int]*runningCmd{}
+	interruptions := 0
+	stopScheduling := false
+
+	closeOutput := func(s *config.Stack) {
+		if err := output.Close(s); err != nil {
+			logger.Warn().
+				Err(err).
+				Stringer("stack", s).
+				Msg("failed to flush stack output")
 		}
 	}
 
-	results := startCmdRunner(cmds)
+	// spawn starts a new attempt of the command of the given running stack.
+	spawn := func(i int, rc *runningCmd) error {
+		run := runStacks[i]
+
+		rc.attempt++
+		atomic.StoreInt32(&rc.timedOut, 0)
+		rc.cmd = exec.Command(rc.path, run.Cmd[1:]...)
+		rc.cmd.Dir = run.Stack.HostDir(root)
+		rc.cmd.Env = rc.environ
+		rc.cmd.Stdin = stdin
+		rc.cmd.Stdout = rc.stdout
+		rc.cmd.Stderr = rc.stderr
+
+		rc.timeout, rc.byDeadline = run.timeout(time.Now())
+		if rc.timeout > 0 {
+			setProcessGroup(rc.cmd)
+		}
 
-	for i, run := range runStacks {
-		cmdStr := strings.Join(run.Cmd, " ")
-		logger := log.With().
-			Str("cmd", cmdStr).
+		log.Info().
+			Str("cmd", rc.cmdStr).
 			Stringer("stack", run.Stack).
-			Logger()
+			Int("attempt", rc.attempt).
+			Msg("running")
+
+		if err := rc.cmd.Start(); err != nil {
+			return errors.E(run.Stack, err, "running %s", rc.cmdStr)
+		}
+
+		if rc.timeout > 0 {
+			cmd := rc.cmd
+			rc.timer = time.AfterFunc(rc.timeout, func() {
+				atomic.StoreInt32(&rc.timedOut, 1)
+				if err := killProcessGroup(cmd); err != nil {
+					log.Debug().Err(err).Msg("unable to kill timed out process group")
+				}
+			})
+		}
+
+		cmd := rc.cmd
+		go func() {
+			results <- cmdResult{index: i, err: cmd.Wait()}
+		}()
+		return nil
+	}
+
+	startStack := func(i int) error {
+		run := runStacks[i]
+		cmdStr := Redact(run.Env, strings.Join(run.Cmd, " "))
+
+		if run.deadlineExceeded(time.Now()) {
+			err := errors.E(ErrTimeout, "running `%s` in stack %s not started: the run exceeded its timeout", cmdStr, run.Stack.Dir)
+			after(run.Stack, err)
+			return err
+		}
 
 		before(run.Stack, cmdStr)
 
 		environ := make([]string, len(os.Environ()))
 		copy(environ, os.Environ())
-		environ = append(environ, stackEnvs[run.Stack.Dir]...)
+		for _, env := range run.Env {
+			environ = append(environ, env.String())
+		}
 		cmdPath, err := lookPath(run.Cmd[0], environ)
 		if err != nil {
 			after(run.Stack, errors.E(err, ErrFailed))
-			errs.Append(errors.E(err, "running `%s` in stack %s", cmdStr, run.Stack.Dir))
-			if continueOnError {
-				continue
-			}
-			cancelStacks(runStacks[i+1:])
-			return errs.AsError()
+			return errors.E(err, "running `%s` in stack %s", cmdStr, run.Stack.Dir)
+		}
+		stdout, stderr, err := output.Open(run.Stack)
+		if err != nil {
+			after(run.Stack, errors.E(err, ErrFailed))
+			return errors.E(err, "running `%s` in stack %s", cmdStr, run.Stack.Dir)
 		}
-		cmd := exec.Command(cmdPath, run.Cmd[1:]...)
-		cmd.Dir = run.Stack.HostDir(root)
-		cmd.Env = environ
-		cmd.Stdin = stdin
-		cmd.Stdout = stdout
-		cmd.Stderr = stderr
 
-		logger.Info().Msg("running")
+		rc := &runningCmd{
+			path:    cmdPath,
+			cmdStr:  cmdStr,
+			environ: environ,
+			stdout:  stdout,
+			stderr:  stderr,
+		}
 
-		if err := cmd.Start(); err != nil {
+		if err := spawn(i, rc); err != nil {
+			closeOutput(run.Stack)
 			after(run.Stack, errors.E(err, ErrFailed))
-			errs.Append(errors.E(run.Stack, err, "running %s", cmd))
-			if continueOnError {
-				continue
+			return err
+		}
+
+		running[i] = rc
+		return nil
+	}
+
+	finish := func(i int, err error) {
+		run := runStacks[i]
+		delete(running, i)
+		finished[run.Stack.Dir] = true
+		closeOutput(run.Stack)
+
+		if err == nil {
+			after(run.Stack, nil)
+			return
+		}
+
+		after(run.Stack, err)
+		errs.Append(err)
+		if !continueOnError {
+			stopScheduling = true
+		}
+	}
+
+	for {
+		for !stopScheduling && len(running) < parallel {
+			pos := nextReady(len(running))
+			if pos == -1 {
+				break
 			}
-			cancelStacks(runStacks[i+1:])
-			return errs.AsError()
+
+			i := pending[pos]
+			pending = append(pending[:pos], pending[pos+1:]...)
+
+			if err := startStack(i); err != nil {
+				finished[runStacks[i].Stack.Dir] = true
+				errs.Append(err)
+				if !continueOnError {
+					stopScheduling = true
+				}
+			}
+		}
+
+		if len(running) == 0 {
+			break
 		}
 
-		cmds <- cmd
-		interruptions := 0
-		cmdIsRunning := true
+		select {
+		case sig := <-signals:
+			interruptions++
+			stopScheduling = true
 
-		for cmdIsRunning {
-			select {
-			case sig := <-signals:
-				interruptions++
+			logger.Info().
+				Str("signal", sig.String()).
+				Int("interruptions", interruptions).
+				Msg("received interruption signal")
 
-				logger.Info().
-					Str("signal", sig.String()).
-					Int("interruptions", interruptions).
-					Msg("received interruption signal")
+			for i, rc := range running {
+				if rc.cmd == nil {
+					// waiting for a retry: it is canceled right away if the
+					// retry was not fired yet.
+					if rc.timer.Stop() {
+						finish(i, errors.E(ErrCanceled, "retry of stack %s canceled", runStacks[i].Stack.Dir))
+					}
+					continue
+				}
 
 				if interruptions >= 3 {
 					logger.Info().Msg("interrupted 3x times or more, killing child process")
 
-					if err := cmd.Process.Kill(); err != nil {
-						logger.Debug().Err(err).Msg("unable to send kill signal to child process")
+					kill := rc.cmd.Process.Kill
+					if rc.timeout > 0 {
+						// the children on its own process group are
+						// killed too, like on timeout.
+						kill = func() error { return killProcessGroup(rc.cmd) }
 					}
-				}
-			case err := <-results:
-				logger.Trace().Msg("got command result")
-				if err != nil {
-					if interruptions >= 3 {
-						after(run.Stack, errors.E(ErrCanceled, err))
-					} else {
-						after(run.Stack, errors.E(ErrFailed, err))
+					if err := kill(); err != nil {
+						logger.Debug().Err(err).Msg("unable to send kill signal to child process")
 					}
-					errs.Append(errors.E(err, "running %s (at stack %s)", cmd, run.Stack.Dir))
-					if !continueOnError {
-						cancelStacks(runStacks[i+1:])
-						return errs.AsError()
+				} else if rc.timeout > 0 {
+					// the child process is on its own process group and
+					// doesn't get the signal from the terminal.
+					if err := signalProcessGroup(rc.cmd, sig); err != nil {
+						logger.Debug().Err(err).Msg("unable to forward signal to child process")
 					}
-				} else {
-					after(run.Stack, nil)
 				}
+			}
+		case i := <-retries:
+			rc := running[i]
+			run := runStacks[i]
+			if interruptions > 0 {
+				finish(i, errors.E(ErrCanceled, "retry of stack %s canceled", run.Stack.Dir))
+				continue
+			}
+			if run.deadlineExceeded(time.Now()) {
+				finish(i, errors.E(ErrTimeout, "retry of stack %s not started: the run exceeded its timeout", run.Stack.Dir))
+				continue
+			}
+			if err := spawn(i, rc); err != nil {
+				finish(i, errors.E(ErrFailed, err))
+			}
+		case res := <-results:
+			rc := running[res.index]
+			run := runStacks[res.index]
+			cmd := rc.cmdStr
 
-				cmdIsRunning = false
+			if rc.timer != nil {
+				rc.timer.Stop()
 			}
-		}
 
-		if interruptions > 0 {
-			logger.Info().Msg("interrupting execution of further stacks")
+			logger.Trace().
+				Stringer("stack", run.Stack).
+				Msg("got command result")
 
-			cancelStacks(runStacks[i+1:])
-			return errs.AsError()
+			switch {
+			case res.err == nil:
+				finish(res.index, nil)
+			case interruptions >= 3:
+				finish(res.index, errors.E(ErrCanceled, res.err,
+					"running %s (at stack %s)", cmd, run.Stack.Dir))
+			case atomic.LoadInt32(&rc.timedOut) == 1 && rc.byDeadline:
+				finish(res.index, errors.E(ErrTimeout, res.err,
+					"running %s (at stack %s) exceeded the timeout of the run",
+					cmd, run.Stack.Dir))
+			case atomic.LoadInt32(&rc.timedOut) == 1:
+				finish(res.index, errors.E(ErrTimeout, res.err,
+					"running %s (at stack %s) exceeded the timeout of %s",
+					cmd, run.Stack.Dir, rc.timeout))
+			case interruptions == 0 && run.Retry.shouldRetry(rc.attempt, res.err):
+				backoff := run.Retry.backoff(rc.attempt + 1)
+
+				logger.Info().
+					Stringer("stack", run.Stack).
+					Int("attempt", rc.attempt).
+					Dur("backoff", backoff).
+					Err(res.err).
+					Msg("command failed, retrying")
+
+				i := res.index
+				rc.cmd = nil
+				rc.timer = time.AfterFunc(backoff, func() {
+					retries <- i
+				})
+			default:
+				finish(res.index, errors.E(ErrFailed, res.err,
+					"running %s (at stack %s)", cmd, run.Stack.Dir))
+			}
 		}
 	}
 
+	if interruptions > 0 {
+		logger.Info().Msg("interrupting execution of further stacks")
+	}
+
+	cancelPending()
 	return errs.AsError()
 }
 
-func startCmdRunner(cmds <-chan *exec.Cmd) <-chan error {
-	errs := make(chan error)
-	go func() {
-		for cmd := range cmds {
-			errs <- cmd.Wait()
-		}
-		close(errs)
-	}()
-	return errs
+// runningCmd is the state of the command running on a stack, which may
+// span several attempts.
+type runningCmd struct {
+	path    string
+	environ []string
+
+	// cmdStr is the command line with the sensitive values redacted.
+	cmdStr string
+
+	stdout io.Writer
+	stderr io.Writer
+
+	cmd     *exec.Cmd
+	attempt int
+	timer   *time.Timer
+
+	// timeout of the current attempt and if it's bounded by the deadline of
+	// the run.
+	timeout    time.Duration
+	byDeadline bool
+
+	// timedOut is set atomically by the timeout timer.
+	timedOut int32
+}
+
+type cmdResult struct {
+	index int
+	err   error
 }
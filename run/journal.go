@@ -0,0 +1,139 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+)
+
+// JournalDir is the directory, relative to the project root, where the run
+// journal is stored. The directory is ignored by Terramate and by git.
+const JournalDir = ".terramate/run"
+
+// JournalFilename is the name of the run journal file inside [JournalDir].
+const JournalFilename = "run-journal.json"
+
+// ErrJournal indicates that the run journal could not be read or written.
+const ErrJournal errors.Kind = "run journal error"
+
+// Journal records the stacks where a command completed successfully, so a
+// failed or interrupted run can be resumed without executing them again.
+type Journal struct {
+	Command   []string `json:"command"`
+	Commit    string   `json:"commit"`
+	Completed []string `json:"completed"`
+
+	rootdir   string
+	completed map[project.Path]bool
+}
+
+// NewJournal creates an empty journal for the given command and git commit
+// on the project at rootdir. If resume is true and the journal saved on
+// rootdir was created for the same command and commit, the stacks completed
+// on the previous run are kept.
+func NewJournal(rootdir string, cmd []string, commit string, resume bool) (*Journal, error) {
+	j := &Journal{
+		Command:   cmd,
+		Commit:    commit,
+		Completed: []string{},
+		rootdir:   rootdir,
+		completed: map[project.Path]bool{},
+	}
+	if !resume {
+		return j, nil
+	}
+
+	data, err := os.ReadFile(j.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return j, nil
+		}
+		return nil, errors.E(ErrJournal, err)
+	}
+
+	var saved Journal
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, errors.E(ErrJournal, err, "parsing %s", j.path())
+	}
+
+	if saved.Commit != commit || !equalStrings(saved.Command, cmd) {
+		return j, nil
+	}
+
+	for _, stackdir := range saved.Completed {
+		j.markCompleted(project.NewPath(stackdir))
+	}
+	return j, nil
+}
+
+// IsCompleted tells if the command was already completed on the stack.
+func (j *Journal) IsCompleted(stackdir project.Path) bool {
+	return j.completed[stackdir]
+}
+
+// Complete records the stack as completed and saves the journal.
+func (j *Journal) Complete(stackdir project.Path) error {
+	j.markCompleted(stackdir)
+	return j.Save()
+}
+
+// Save writes the journal into the project.
+func (j *Journal) Save() error {
+	dir := filepath.Join(j.rootdir, filepath.FromSlash(JournalDir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.E(ErrJournal, err)
+	}
+
+	// the journal directory is local state and must never be committed nor
+	// be detected as untracked files by the git safeguards.
+	gitignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(gitignore); os.IsNotExist(err) {
+		if err := os.WriteFile(gitignore, []byte("*\n"), 0644); err != nil {
+			return errors.E(ErrJournal, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return errors.E(ErrJournal, err)
+	}
+
+	tmpfile := j.path() + ".tmp"
+	if err := os.WriteFile(tmpfile, data, 0644); err != nil {
+		return errors.E(ErrJournal, err)
+	}
+	if err := os.Rename(tmpfile, j.path()); err != nil {
+		return errors.E(ErrJournal, err)
+	}
+	return nil
+}
+
+func (j *Journal) markCompleted(stackdir project.Path) {
+	if j.completed[stackdir] {
+		return
+	}
+	j.completed[stackdir] = true
+	j.Completed = append(j.Completed, stackdir.String())
+}
+
+func (j *Journal) path() string {
+	return filepath.Join(j.rootdir, filepath.FromSlash(JournalDir), JournalFilename)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/run"
+)
+
+func TestJournalResume(t *testing.T) {
+	t.Parallel()
+
+	rootdir := t.TempDir()
+	cmd := []string{"terraform", "apply"}
+	stackA := project.NewPath("/stack-a")
+	stackB := project.NewPath("/stack-b")
+
+	journal, err := run.NewJournal(rootdir, cmd, "commit1", false)
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Save())
+	assert.NoError(t, journal.Complete(stackA))
+
+	resumed, err := run.NewJournal(rootdir, cmd, "commit1", true)
+	assert.NoError(t, err)
+	assert.IsTrue(t, resumed.IsCompleted(stackA))
+	assert.IsTrue(t, !resumed.IsCompleted(stackB))
+
+	notResumed, err := run.NewJournal(rootdir, cmd, "commit1", false)
+	assert.NoError(t, err)
+	assert.IsTrue(t, !notResumed.IsCompleted(stackA))
+
+	otherCommit, err := run.NewJournal(rootdir, cmd, "commit2", true)
+	assert.NoError(t, err)
+	assert.IsTrue(t, !otherCommit.IsCompleted(stackA))
+
+	otherCmd, err := run.NewJournal(rootdir, []string{"terraform", "plan"}, "commit1", true)
+	assert.NoError(t, err)
+	assert.IsTrue(t, !otherCmd.IsCompleted(stackA))
+}
+
+func TestJournalResumeWithoutJournal(t *testing.T) {
+	t.Parallel()
+
+	journal, err := run.NewJournal(t.TempDir(), []string{"cmd"}, "", true)
+	assert.NoError(t, err)
+	assert.IsTrue(t, !journal.IsCompleted(project.NewPath("/stack")))
+}
@@ -14,13 +14,27 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/run/dag"
 )
 
+// Deps maps a stack to the list of stacks that must finish executing before
+// it can be started.
+type Deps map[project.Path]project.Paths
+
 // Sort computes the final execution order for the given list of stacks.
 // In the case of multiple possible orders, it returns the lexicographic sorted
 // path.
 func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.List[*config.SortableStack], string, error) {
+	orderedStacks, _, reason, err := SortWithDeps(root, stacks)
+	return orderedStacks, reason, err
+}
+
+// SortWithDeps is like [Sort] but it also returns the dependencies between the
+// ordered stacks. The dependencies only contain stacks which are part of the
+// given list of stacks, and if a stack depends on another one through stacks that
+// are not selected then the dependency is kept.
+func SortWithDeps(root *config.Root, stacks config.List[*config.SortableStack]) (config.List[*config.SortableStack], Deps, string, error) {
 	d := dag.New()
 
 	logger := log.With().
@@ -35,6 +49,19 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 	}
 
 	sort.Sort(stacks)
+
+	// the implicit parent ordering is only needed to build the DAG, so the
+	// stacks are restored to their declared before list afterwards.
+	declaredBefore := make([][]string, len(stacks))
+	for i, elem := range stacks {
+		declaredBefore[i] = elem.Before
+	}
+	defer func() {
+		for i, elem := range stacks {
+			elem.Before = declaredBefore[i]
+		}
+	}()
+
 	for _, stackElem := range stacks {
 		for _, otherElem := range stacks {
 			if stackElem.Dir() == otherElem.Dir() {
@@ -73,7 +100,7 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 		)
 
 		if err != nil {
-			return nil, "", err
+			return nil, nil, "", err
 		}
 	}
 
@@ -81,7 +108,7 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 
 	reason, err := d.Validate()
 	if err != nil {
-		return nil, reason, err
+		return nil, nil, reason, err
 	}
 
 	logger.Trace().Msg("Get topologically order DAG.")
@@ -109,7 +136,7 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 	for _, id := range order {
 		val, err := d.Node(id)
 		if err != nil {
-			return nil, "", fmt.Errorf("calculating run-order: %w", err)
+			return nil, nil, "", fmt.Errorf("calculating run-order: %w", err)
 		}
 		s := val.(*config.Stack)
 		if !isSelectedStack(s) {
@@ -121,7 +148,71 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 		orderedStacks = append(orderedStacks, s.Sortable())
 	}
 
-	return orderedStacks, "", nil
+	logger.Trace().Msg("Compute dependencies of ordered stacks.")
+
+	deps := Deps{}
+	for _, elem := range orderedStacks {
+		deps[elem.Dir()] = selectedAncestorsOf(d, dag.ID(elem.Dir().String()), isSelectedStack)
+	}
+
+	return orderedStacks, deps, "", nil
+}
+
+// Reverse returns the dependencies for a reversed order of execution, ie. if
+// stack A depends on stack B then on the reversed dependencies the stack B
+// depends on stack A.
+func (deps Deps) Reverse() Deps {
+	reversed := Deps{}
+	for stackdir, ancestors := range deps {
+		if _, ok := reversed[stackdir]; !ok {
+			reversed[stackdir] = project.Paths{}
+		}
+		for _, ancestor := range ancestors {
+			reversed[ancestor] = append(reversed[ancestor], stackdir)
+		}
+	}
+	for _, paths := range reversed {
+		paths.Sort()
+	}
+	return reversed
+}
+
+// selectedAncestorsOf returns the ancestors of the given node which are
+// selected. Unselected ancestors are traversed so the dependencies through
+// them are also returned.
+func selectedAncestorsOf(d *dag.DAG, id dag.ID, isSelected func(s *config.Stack) bool) project.Paths {
+	found := map[project.Path]struct{}{}
+	visited := dag.Visited{}
+
+	var walk func(id dag.ID)
+	walk = func(id dag.ID) {
+		for _, ancestorID := range d.AncestorsOf(id) {
+			if _, ok := visited[ancestorID]; ok {
+				continue
+			}
+			visited[ancestorID] = struct{}{}
+
+			val, err := d.Node(ancestorID)
+			if err != nil {
+				continue
+			}
+			s := val.(*config.Stack)
+			if isSelected(s) {
+				found[s.Dir] = struct{}{}
+				continue
+			}
+			walk(ancestorID)
+		}
+	}
+
+	walk(id)
+
+	paths := make(project.Paths, 0, len(found))
+	for p := range found {
+		paths = append(paths, p)
+	}
+	paths.Sort()
+	return paths
 }
 
 // BuildDAG builds a run order DAG for the given stack.
@@ -0,0 +1,259 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+)
+
+// OutputMode defines how the output of the stacks commands is written.
+type OutputMode string
+
+// Supported output modes.
+const (
+	// OutputStream writes the output of the commands directly, as it is
+	// produced. If commands run in parallel their output is interleaved.
+	OutputStream OutputMode = "stream"
+
+	// OutputPrefix writes each line of output prefixed by the stack path.
+	OutputPrefix OutputMode = "prefix"
+
+	// OutputGroup buffers the output of each stack and writes it at once
+	// when the command on the stack finishes.
+	OutputGroup OutputMode = "group"
+)
+
+const (
+	// ErrInvalidOutputMode indicates that the output mode is not supported.
+	ErrInvalidOutputMode errors.Kind = "invalid output mode"
+
+	// ErrOutputFile indicates that the stack output file could not be
+	// created or written.
+	ErrOutputFile errors.Kind = "writing stack output file"
+)
+
+// Output creates the writers used by the commands executed on each stack.
+// The methods of Output are called only from the goroutine running [ExecAll]
+// but the returned writers may be written concurrently.
+type Output interface {
+	// Open returns the stdout and stderr writers for the given stack.
+	Open(s *config.Stack) (stdout io.Writer, stderr io.Writer, err error)
+
+	// Close is called when the command on the given stack has finished and
+	// its output must be flushed.
+	Close(s *config.Stack) error
+}
+
+// NewOutput creates an [Output] writing to the given stdout and stderr using
+// the provided mode. If dir is not empty then the output of each stack is
+// also written to the stdout.log and stderr.log files inside a directory named
+// after the stack path, relative to dir.
+func NewOutput(stdout, stderr io.Writer, mode OutputMode, dir string) (Output, error) {
+	switch mode {
+	case "", OutputStream, OutputPrefix, OutputGroup:
+	default:
+		return nil, errors.E(ErrInvalidOutputMode, "mode %q", mode)
+	}
+	if mode == "" {
+		mode = OutputStream
+	}
+	return &output{
+		stdout:     stdout,
+		stderr:     stderr,
+		syncStdout: &syncWriter{w: stdout},
+		syncStderr: &syncWriter{w: stderr},
+		mode:       mode,
+		dir:        dir,
+		stacks:     map[*config.Stack]*stackOutput{},
+	}, nil
+}
+
+type output struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	// syncStdout and syncStderr are used when the output of the stacks is
+	// rewritten, so concurrent stacks never mix partial writes.
+	syncStdout *syncWriter
+	syncStderr *syncWriter
+
+	mode OutputMode
+	dir  string
+
+	stacks map[*config.Stack]*stackOutput
+}
+
+type stackOutput struct {
+	stdout flushWriter
+	stderr flushWriter
+	files  []*os.File
+}
+
+// flushWriter is a writer which may hold data until it is flushed.
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+func (o *output) Open(s *config.Stack) (io.Writer, io.Writer, error) {
+	so := &stackOutput{}
+
+	switch o.mode {
+	case OutputPrefix:
+		prefix := []byte("[" + s.Dir.String() + "] ")
+		so.stdout = &prefixWriter{w: o.syncStdout, prefix: prefix}
+		so.stderr = &prefixWriter{w: o.syncStderr, prefix: prefix}
+	case OutputGroup:
+		so.stdout = &groupWriter{w: o.syncStdout}
+		so.stderr = &groupWriter{w: o.syncStderr}
+	default:
+		so.stdout = nopFlusher{o.stdout}
+		so.stderr = nopFlusher{o.stderr}
+	}
+
+	var stdout, stderr io.Writer = so.stdout, so.stderr
+	if o.mode == OutputStream {
+		// the original writers are used so the child process may still
+		// detect a terminal when running stacks sequentially.
+		stdout, stderr = o.stdout, o.stderr
+	}
+	if o.dir == "" {
+		o.stacks[s] = so
+		return stdout, stderr, nil
+	}
+
+	stackdir := filepath.Join(o.dir, filepath.FromSlash(s.Dir.String()))
+	if err := os.MkdirAll(stackdir, 0700); err != nil {
+		return nil, nil, errors.E(ErrOutputFile, err, "creating dir %s", stackdir)
+	}
+
+	stdoutFile, err := os.Create(filepath.Join(stackdir, "stdout.log"))
+	if err != nil {
+		return nil, nil, errors.E(ErrOutputFile, err)
+	}
+	stderrFile, err := os.Create(filepath.Join(stackdir, "stderr.log"))
+	if err != nil {
+		_ = stdoutFile.Close()
+		return nil, nil, errors.E(ErrOutputFile, err)
+	}
+	so.files = []*os.File{stdoutFile, stderrFile}
+
+	// the stack is only tracked once all its files are open, as Close isn't
+	// called if Open fails.
+	o.stacks[s] = so
+
+	return io.MultiWriter(stdoutFile, stdout), io.MultiWriter(stderrFile, stderr), nil
+}
+
+func (o *output) Close(s *config.Stack) error {
+	so, ok := o.stacks[s]
+	if !ok {
+		return nil
+	}
+	delete(o.stacks, s)
+
+	errs := errors.L()
+	errs.Append(so.stdout.Flush())
+	errs.Append(so.stderr.Flush())
+	for _, f := range so.files {
+		errs.Append(f.Close())
+	}
+	return errs.AsError()
+}
+
+// syncWriter serializes the writes from multiple stacks into the same writer.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p)
+}
+
+type nopFlusher struct {
+	io.Writer
+}
+
+func (nopFlusher) Flush() error { return nil }
+
+// prefixWriter writes each complete line prefixed. Incomplete lines are kept
+// until a newline is written or the writer is flushed.
+type prefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i == -1 {
+			return len(p), nil
+		}
+		if err := pw.writeLine(pw.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+}
+
+func (pw *prefixWriter) Flush() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if len(pw.buf) == 0 {
+		return nil
+	}
+	line := append(pw.buf, '\n')
+	pw.buf = nil
+	return pw.writeLine(line)
+}
+
+func (pw *prefixWriter) writeLine(line []byte) error {
+	data := make([]byte, 0, len(pw.prefix)+len(line))
+	data = append(data, pw.prefix...)
+	data = append(data, line...)
+	_, err := pw.w.Write(data)
+	return err
+}
+
+// groupWriter keeps all the output until flushed.
+type groupWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (gw *groupWriter) Write(p []byte) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	return gw.buf.Write(p)
+}
+
+func (gw *groupWriter) Flush() error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	if gw.buf.Len() == 0 {
+		return nil
+	}
+	_, err := gw.w.Write(gw.buf.Bytes())
+	gw.buf.Reset()
+	return err
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/test"
+)
+
+func TestOutputModes(t *testing.T) {
+	t.Parallel()
+
+	type write struct {
+		stack  *config.Stack
+		stdout string
+		stderr string
+	}
+
+	type testcase struct {
+		name       string
+		mode       run.OutputMode
+		writes     []write
+		wantStdout string
+		wantStderr string
+	}
+
+	s1 := &config.Stack{Dir: project.NewPath("/s1")}
+	s2 := &config.Stack{Dir: project.NewPath("/s2")}
+
+	for _, tc := range []testcase{
+		{
+			name: "stream writes output as produced",
+			mode: run.OutputStream,
+			writes: []write{
+				{stack: s1, stdout: "s1 out", stderr: "s1 err\n"},
+				{stack: s2, stdout: "s2 out\n"},
+				{stack: s1, stdout: " continued\n"},
+			},
+			wantStdout: "s1 outs2 out\n continued\n",
+			wantStderr: "s1 err\n",
+		},
+		{
+			name: "prefix writes whole lines prefixed by the stack",
+			mode: run.OutputPrefix,
+			writes: []write{
+				{stack: s1, stdout: "s1 out", stderr: "s1 err\nlast"},
+				{stack: s2, stdout: "s2 out\n"},
+				{stack: s1, stdout: " continued\n"},
+			},
+			wantStdout: "[/s2] s2 out\n[/s1] s1 out continued\n",
+			wantStderr: "[/s1] s1 err\n[/s1] last\n",
+		},
+		{
+			name: "group writes the output of each stack when closed",
+			mode: run.OutputGroup,
+			writes: []write{
+				{stack: s1, stdout: "s1 out\n", stderr: "s1 err\n"},
+				{stack: s2, stdout: "s2 out\n"},
+				{stack: s1, stdout: "s1 continued\n"},
+			},
+			wantStdout: "s1 out\ns1 continued\ns2 out\n",
+			wantStderr: "s1 err\n",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			output, err := run.NewOutput(&stdout, &stderr, tc.mode, "")
+			assert.NoError(t, err)
+
+			writers := map[*config.Stack][2]io.Writer{}
+			for _, s := range []*config.Stack{s1, s2} {
+				stdout, stderr, err := output.Open(s)
+				assert.NoError(t, err)
+				writers[s] = [2]io.Writer{stdout, stderr}
+			}
+
+			for _, w := range tc.writes {
+				if w.stdout != "" {
+					_, err := writers[w.stack][0].Write([]byte(w.stdout))
+					assert.NoError(t, err)
+				}
+				if w.stderr != "" {
+					_, err := writers[w.stack][1].Write([]byte(w.stderr))
+					assert.NoError(t, err)
+				}
+			}
+
+			assert.NoError(t, output.Close(s1))
+			assert.NoError(t, output.Close(s2))
+
+			assert.EqualStrings(t, tc.wantStdout, stdout.String())
+			assert.EqualStrings(t, tc.wantStderr, stderr.String())
+		})
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	dir := t.TempDir()
+	output, err := run.NewOutput(&stdout, &stderr, run.OutputPrefix, dir)
+	assert.NoError(t, err)
+
+	s := &config.Stack{Dir: project.NewPath("/parent/child")}
+	o, e, err := output.Open(s)
+	assert.NoError(t, err)
+
+	_, err = o.Write([]byte("out\n"))
+	assert.NoError(t, err)
+	_, err = e.Write([]byte("err\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, output.Close(s))
+
+	assert.EqualStrings(t, "[/parent/child] out\n", stdout.String())
+	assert.EqualStrings(t, "[/parent/child] err\n", stderr.String())
+
+	stackdir := filepath.Join(dir, "parent", "child")
+	assert.EqualStrings(t, "out\n", string(test.ReadFile(t, stackdir, "stdout.log")))
+	assert.EqualStrings(t, "err\n", string(test.ReadFile(t, stackdir, "stderr.log")))
+}
+
+func TestOutputDirFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	output, err := run.NewOutput(&bytes.Buffer{}, &bytes.Buffer{}, run.OutputPrefix, dir)
+	assert.NoError(t, err)
+
+	// stderr.log can't be created when it's a directory.
+	stderrLog := filepath.Join(dir, "stack", "stderr.log")
+	assert.NoError(t, os.MkdirAll(stderrLog, 0700))
+
+	s := &config.Stack{Dir: project.NewPath("/stack")}
+	_, _, err = output.Open(s)
+	assert.IsTrue(t, errors.IsKind(err, run.ErrOutputFile), "unexpected error: %v", err)
+
+	// the stack output is not kept open after the failure.
+	assert.NoError(t, output.Close(s))
+
+	assert.NoError(t, os.Remove(stderrLog))
+	_, _, err = output.Open(s)
+	assert.NoError(t, err)
+	assert.NoError(t, output.Close(s))
+}
+
+func TestOutputInvalidMode(t *testing.T) {
+	t.Parallel()
+
+	_, err := run.NewOutput(&bytes.Buffer{}, &bytes.Buffer{}, run.OutputMode("invalid"), "")
+	assert.IsTrue(t, errors.IsKind(err, run.ErrInvalidOutputMode))
+}
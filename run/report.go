@@ -0,0 +1,233 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os/exec"
+	"text/tabwriter"
+	"time"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+)
+
+// StackStatus is the final status of a stack execution.
+type StackStatus string
+
+// Possible stack execution statuses.
+const (
+	StatusSuccess  StackStatus = "success"
+	StatusFailed   StackStatus = "failed"
+	StatusCanceled StackStatus = "canceled"
+	StatusSkipped  StackStatus = "skipped"
+)
+
+// StackResult is the result of the execution of a command on a stack.
+type StackResult struct {
+	Stack   string      `json:"stack"`
+	Command string      `json:"command"`
+	Status  StackStatus `json:"status"`
+
+	// ExitCode is the exit code of the command or -1 if the command
+	// didn't exit normally or was never started.
+	ExitCode int `json:"exit_code"`
+
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Duration   time.Duration `json:"duration_ns"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Report collects the results of the stacks executed by [ExecAll]. The
+// [Report.Before] and [Report.After] methods can be called from the
+// before and after callbacks of [ExecAll].
+type Report struct {
+	Results []StackResult `json:"stacks"`
+
+	now     func() time.Time
+	started map[*config.Stack]startedStack
+}
+
+type startedStack struct {
+	at  time.Time
+	cmd string
+}
+
+// NewReport creates a new empty report.
+func NewReport() *Report {
+	return &Report{
+		Results: []StackResult{},
+		now:     time.Now,
+		started: map[*config.Stack]startedStack{},
+	}
+}
+
+// Before records the start of the command on the stack.
+func (r *Report) Before(s *config.Stack, cmd string) {
+	r.started[s] = startedStack{at: r.now(), cmd: cmd}
+}
+
+// After records the result of the command on the stack.
+func (r *Report) After(s *config.Stack, err error) {
+	res := StackResult{
+		Stack:    s.Dir.String(),
+		ExitCode: -1,
+	}
+
+	started, wasStarted := r.started[s]
+	if wasStarted {
+		delete(r.started, s)
+		finishedAt := r.now()
+		res.Command = started.cmd
+		res.StartedAt = &started.at
+		res.FinishedAt = &finishedAt
+		res.Duration = finishedAt.Sub(started.at)
+	}
+
+	switch {
+	case err == nil:
+		res.Status = StatusSuccess
+		res.ExitCode = 0
+	case errors.IsKind(err, ErrCanceled) && !wasStarted:
+		res.Status = StatusSkipped
+	case errors.IsKind(err, ErrCanceled):
+		res.Status = StatusCanceled
+	default:
+		res.Status = StatusFailed
+	}
+
+	if err != nil {
+		res.Error = err.Error()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			res.ExitCode = exitErr.ExitCode()
+		}
+	}
+
+	r.Results = append(r.Results, res)
+}
+
+// Count returns the number of stacks with the given status.
+func (r *Report) Count(status StackStatus) int {
+	count := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteTable writes a human readable summary table of the report.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STACK\tSTATUS\tEXIT CODE\tDURATION")
+	for _, res := range r.Results {
+		exitCode := "-"
+		if res.ExitCode != -1 {
+			exitCode = fmt.Sprint(res.ExitCode)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			res.Stack, res.Status, exitCode, res.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(tw, "\n%d succeeded, %d failed, %d canceled, %d skipped\n",
+		r.Count(StatusSuccess),
+		r.Count(StatusFailed),
+		r.Count(StatusCanceled),
+		r.Count(StatusSkipped),
+	)
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML. Each stack is a test case,
+// failed stacks are failures, canceled stacks are errors and skipped stacks
+// are skipped test cases.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:     "terramate run",
+		Tests:    len(r.Results),
+		Failures: r.Count(StatusFailed),
+		Errors:   r.Count(StatusCanceled),
+		Skipped:  r.Count(StatusSkipped),
+	}
+
+	var total time.Duration
+	for _, res := range r.Results {
+		total += res.Duration
+
+		tc := junitTestCase{
+			Name:      res.Stack,
+			ClassName: "terramate.run",
+			Time:      junitSeconds(res.Duration),
+		}
+		msg := &junitMessage{Message: string(res.Status), Text: res.Error}
+		switch res.Status {
+		case StatusFailed:
+			tc.Failure = msg
+		case StatusCanceled:
+			tc.Error = msg
+		case StatusSkipped:
+			tc.Skipped = msg
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
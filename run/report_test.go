@@ -0,0 +1,79 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/run"
+)
+
+func TestReportStatuses(t *testing.T) {
+	t.Parallel()
+
+	success := &config.Stack{Dir: project.NewPath("/success")}
+	failed := &config.Stack{Dir: project.NewPath("/failed")}
+	canceled := &config.Stack{Dir: project.NewPath("/canceled")}
+	skipped := &config.Stack{Dir: project.NewPath("/skipped")}
+
+	report := run.NewReport()
+	report.Before(success, "cmd")
+	report.After(success, nil)
+	report.Before(failed, "cmd")
+	report.After(failed, errors.E(run.ErrFailed, "failed"))
+	report.Before(canceled, "cmd")
+	report.After(canceled, errors.E(run.ErrCanceled))
+	report.After(skipped, errors.E(run.ErrCanceled))
+
+	want := []struct {
+		stack    string
+		status   run.StackStatus
+		exitCode int
+	}{
+		{"/success", run.StatusSuccess, 0},
+		{"/failed", run.StatusFailed, -1},
+		{"/canceled", run.StatusCanceled, -1},
+		{"/skipped", run.StatusSkipped, -1},
+	}
+
+	assert.EqualInts(t, len(want), len(report.Results))
+	for i, w := range want {
+		got := report.Results[i]
+		assert.EqualStrings(t, w.stack, got.Stack)
+		assert.EqualStrings(t, string(w.status), string(got.Status))
+		assert.EqualInts(t, w.exitCode, got.ExitCode)
+	}
+	assert.IsTrue(t, report.Results[0].StartedAt != nil)
+	assert.IsTrue(t, report.Results[3].StartedAt == nil)
+	assert.EqualStrings(t, "cmd", report.Results[0].Command)
+
+	var table bytes.Buffer
+	assert.NoError(t, report.WriteTable(&table))
+	assert.IsTrue(t, strings.Contains(table.String(),
+		"1 succeeded, 1 failed, 1 canceled, 1 skipped"), table.String())
+
+	var data bytes.Buffer
+	assert.NoError(t, report.WriteJSON(&data))
+
+	var decoded run.Report
+	assert.NoError(t, json.Unmarshal(data.Bytes(), &decoded))
+	assert.EqualInts(t, len(want), len(decoded.Results))
+
+	var junit bytes.Buffer
+	assert.NoError(t, report.WriteJUnit(&junit))
+	for _, want := range []string{
+		`tests="4" failures="1" errors="1" skipped="1"`,
+		`<testcase name="/failed"`,
+		`<skipped message="skipped">`,
+	} {
+		assert.IsTrue(t, strings.Contains(junit.String(), want), junit.String())
+	}
+}
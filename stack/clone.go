@@ -134,42 +134,44 @@ func UpdateStackID(stackdir string) (string, error) {
 		return "", errors.E(err, "reading stack definition file")
 	}
 
-	logger.Trace().Msg("parsing stack file")
-
-	parsed, diags := hclwrite.ParseConfig([]byte(stackContents), stackFilePath, hhcl.InitialPos)
-	if diags.HasErrors() {
-		return "", errors.E(diags, "parsing stack configuration")
+	id, updated, err := SetStackID(stackFilePath, stackContents)
+	if err != nil {
+		return "", err
 	}
 
-	blocks := parsed.Body().Blocks()
+	logger.Trace().Msg("saving updated file")
+
+	err = os.WriteFile(stackFilePath, updated, originalFileMode)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
 
-	logger.Trace().Msg("searching for stack ID attribute")
+// SetStackID sets a new random stack.id in the stack block of the given stack
+// file content. It returns the generated ID and the updated content.
+func SetStackID(filename string, content []byte) (string, []byte, error) {
+	parsed, diags := hclwrite.ParseConfig(content, filename, hhcl.InitialPos)
+	if diags.HasErrors() {
+		return "", nil, errors.E(diags, "parsing stack configuration")
+	}
 
-	for _, block := range blocks {
+	for _, block := range parsed.Body().Blocks() {
 		if block.Type() != hcl.StackBlockType {
 			continue
 		}
 
 		uuid, err := uuid.NewRandom()
 		if err != nil {
-			return "", errors.E(err, "creating new ID for stack")
+			return "", nil, errors.E(err, "creating new ID for stack")
 		}
 
 		id := uuid.String()
-
-		body := block.Body()
-		body.SetAttributeValue("id", cty.StringVal(id))
-
-		logger.Trace().Msg("saving updated file")
-
-		err = os.WriteFile(stackFilePath, parsed.Bytes(), originalFileMode)
-		if err != nil {
-			return "", err
-		}
-		return id, nil
+		block.Body().SetAttributeValue("id", cty.StringVal(id))
+		return id, parsed.Bytes(), nil
 	}
 
-	return "", errors.E("stack block not found")
+	return "", nil, errors.E("stack block not found")
 }
 
 func getStackFilepath(parser *hcl.TerramateParser) string {
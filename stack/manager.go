@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
-	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -16,6 +15,7 @@ import (
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/git"
+	"github.com/terramate-io/terramate/modvendor"
 	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/run"
 	"github.com/terramate-io/terramate/run/dag"
@@ -28,6 +28,26 @@ type (
 	Manager struct {
 		root       *config.Root // whole config
 		gitBaseRef string       // gitBaseRef is the git ref where we compare changes.
+
+		// vendorDir is the project vendor directory, used to map remote
+		// module sources to their vendored copies.
+		vendorDir project.Path
+
+		// repoPath is the normalized path of the project's default remote,
+		// used to map remote module sources to the project itself.
+		repoPath string
+
+		// realRootDir is the project root with all symbolic links resolved
+		// and changedFiles is the set of files changed in the project, with
+		// changedDirs being the set of directories containing them. They
+		// are used to resolve symbolic links when detecting changes.
+		realRootDir  string
+		changedFiles map[project.Path]bool
+		changedDirs  map[project.Path]bool
+
+		// symlinks caches the symbolic links resolving to changed files,
+		// by the directory walked to find them.
+		symlinks map[project.Path][]changedLink
 	}
 
 	// Report is the report of project's stacks and the result of its default checks.
@@ -63,6 +83,13 @@ func NewManager(root *config.Root, gitBaseRef string) *Manager {
 	}
 }
 
+// SetVendorDir sets the project vendor directory. When detecting changes,
+// remote module sources vendored inside this directory are checked as if
+// they were local modules.
+func (m *Manager) SetVendorDir(dir project.Path) {
+	m.vendorDir = dir
+}
+
 // List walks the basedir directory looking for terraform stacks.
 // It returns a lexicographic sorted list of stack directories.
 func (m *Manager) List() (*Report, error) {
@@ -139,6 +166,8 @@ func (m *Manager) ListChanged() (*Report, error) {
 		return nil, errors.E(errListChanged, err)
 	}
 
+	m.repoPath = m.defaultRemoteRepoPath(g)
+
 	logger.Debug().Msg("List changed files.")
 
 	changedFiles, err := listChangedFiles(m.root.HostDir(), m.gitBaseRef)
@@ -146,6 +175,25 @@ func (m *Manager) ListChanged() (*Report, error) {
 		return nil, errors.E(errListChanged, err)
 	}
 
+	m.realRootDir, err = filepath.EvalSymlinks(m.root.HostDir())
+	if err != nil {
+		return nil, errors.E(errListChanged, err)
+	}
+
+	m.changedFiles = map[project.Path]bool{}
+	m.changedDirs = map[project.Path]bool{}
+	m.symlinks = map[project.Path][]changedLink{}
+	for _, file := range changedFiles {
+		changed := project.NewPath("/" + file)
+		m.changedFiles[changed] = true
+		for dir := changed.Dir(); !m.changedDirs[dir]; dir = dir.Dir() {
+			m.changedDirs[dir] = true
+			if dir.String() == "/" {
+				break
+			}
+		}
+	}
+
 	stackSet := map[project.Path]Entry{}
 
 	for _, path := range changedFiles {
@@ -187,6 +235,7 @@ func (m *Manager) ListChanged() (*Report, error) {
 				return nil, errors.E(errListChanged, err)
 			}
 
+			s.IsChanged = true
 			stackSet[s.Dir] = Entry{
 				Stack:  s,
 				Reason: "stack has been triggered by: " + projpath.String(),
@@ -229,6 +278,7 @@ func (m *Manager) ListChanged() (*Report, error) {
 			return nil, errors.E(errListChanged, err)
 		}
 
+		s.IsChanged = true
 		stackSet[s.Dir] = Entry{
 			Stack:  s,
 			Reason: "stack has unmerged changes",
@@ -272,12 +322,32 @@ rangeStacks:
 			continue rangeStacks
 		}
 
+		logger.Debug().
+			Stringer("stack", stack).
+			Msg("Check for changed symbolic links.")
+
+		changed, why, err := m.changedSymlink(stack.HostDir(m.root), func(dir string) bool {
+			cfg, found := m.root.Lookup(project.PrjAbsPath(m.root.HostDir(), dir))
+			return found && cfg.IsStack()
+		})
+		if err != nil {
+			return nil, errors.E(errListChanged, err)
+		}
+		if changed {
+			stack.IsChanged = true
+			stackSet[stack.Dir] = Entry{
+				Stack:  stack,
+				Reason: "stack changed because " + why,
+			}
+			continue rangeStacks
+		}
+
 		logger.Debug().
 			Stringer("stack", stack).
 			Msg("Apply function to stack.")
 
-		err := m.filesApply(stack.HostDir(m.root), func(file fs.DirEntry) error {
-			if path.Ext(file.Name()) != ".tf" {
+		err = m.filesApply(stack.HostDir(m.root), func(file fs.DirEntry) error {
+			if !tf.IsTerraformFile(file.Name()) {
 				return nil
 			}
 
@@ -460,6 +530,20 @@ func (m *Manager) filesApply(dir string, apply func(file fs.DirEntry) error) err
 			continue
 		}
 
+		if file.Type()&fs.ModeSymlink != 0 {
+			st, err := os.Stat(filepath.Join(dir, file.Name()))
+			if err != nil {
+				logger.Debug().
+					Err(err).
+					Str("file", file.Name()).
+					Msg("ignoring broken symbolic link")
+				continue
+			}
+			if st.IsDir() {
+				continue
+			}
+		}
+
 		logger.Debug().
 			Msg("Apply function to file.")
 		err := apply(file)
@@ -486,29 +570,56 @@ func (m *Manager) moduleChanged(
 		return false, "", nil
 	}
 
+	var (
+		modPath string
+		modDesc = fmt.Sprintf("%q", mod.Source)
+	)
+
 	logger.Trace().
 		Str("path", basedir).
 		Msg("Check if module source is local directory.")
-	if !mod.IsLocal() {
-		// if the source is a remote path (URL, VCS path, S3 bucket, etc) then
-		// we assume it's not changed.
-		return false, "", nil
-	}
+	if mod.IsLocal() {
+		logger.Trace().
+			Str("path", basedir).
+			Msg("Get module path.")
+		modPath = filepath.Join(basedir, mod.Source)
 
-	logger.Trace().
-		Str("path", basedir).
-		Msg("Get module path.")
-	modPath := filepath.Join(basedir, mod.Source)
+		logger.Trace().
+			Str("path", modPath).
+			Msg("Get module path info.")
+		st, err := os.Stat(modPath)
+		if err != nil || !st.IsDir() {
+			return false, "", errors.E("\"source\" path %q is not a directory", modPath)
+		}
 
-	logger.Trace().
-		Str("path", modPath).
-		Msg("Get module path info.")
-	st, err := os.Stat(modPath)
+		chain, isLink, err := m.followSymlink(modPath)
+		if err != nil {
+			return false, "", errors.E(err, "resolving module %q", mod.Source)
+		}
+		if isLink {
+			logger.Debug().
+				Str("source", mod.Source).
+				Stringer("chain", chain).
+				Msg("module source is a symbolic link")
 
-	// TODO(i4k): resolve symlinks
+			modPath = chain.target().HostPath(m.realRootDir)
+			modDesc = fmt.Sprintf("%q (symlink %s)", mod.Source, chain)
+		}
+	} else {
+		localDir, how, ok := m.remoteModuleDir(mod)
+		if !ok {
+			// if the source is a remote path (URL, VCS path, S3 bucket, etc)
+			// not available in the project then we assume it's not changed.
+			return false, "", nil
+		}
 
-	if err != nil || !st.IsDir() {
-		return false, "", errors.E("\"source\" path %q is not a directory", modPath)
+		logger.Debug().
+			Str("source", mod.Source).
+			Stringer("dir", localDir).
+			Msg("remote module source mapped to local directory")
+
+		modPath = filepath.Join(m.root.HostDir(), filepath.FromSlash(localDir.String()))
+		modDesc = fmt.Sprintf("%q (%s %s)", mod.Source, how, localDir)
 	}
 
 	logger.Debug().
@@ -522,7 +633,15 @@ func (m *Manager) moduleChanged(
 	}
 
 	if len(changedFiles) > 0 {
-		return true, fmt.Sprintf("module %q has unmerged changes", mod.Source), nil
+		return true, fmt.Sprintf("module %s has unmerged changes", modDesc), nil
+	}
+
+	changed, why, err = m.changedSymlink(modPath, func(string) bool { return false })
+	if err != nil {
+		return false, "", errors.E(err, "checking module %q", mod.Source)
+	}
+	if changed {
+		return true, fmt.Sprintf("module %s changed because %s", modDesc, why), nil
 	}
 
 	visited[mod.Source] = true
@@ -534,7 +653,7 @@ func (m *Manager) moduleChanged(
 		if changed {
 			return nil
 		}
-		if path.Ext(file.Name()) != ".tf" {
+		if !tf.IsTerraformFile(file.Name()) {
 			return nil
 		}
 
@@ -576,7 +695,89 @@ func (m *Manager) moduleChanged(
 		return false, "", err
 	}
 
-	return changed, fmt.Sprintf("module %q changed because %s", mod.Source, why), nil
+	return changed, fmt.Sprintf("module %s changed because %s", modDesc, why), nil
+}
+
+// remoteModuleDir maps a remote module source to a directory inside the
+// project. Vendored copies of the module have precedence over sources
+// pointing to the project's own repository. It returns the project
+// directory of the module and a description of how it was mapped.
+func (m *Manager) remoteModuleDir(mod tf.Module) (project.Path, string, bool) {
+	modsrc, err := tf.ParseSource(mod.Source)
+	if err != nil {
+		return project.Path{}, "", false
+	}
+
+	if modsrc.IsRegistry() {
+		// only exact versions can be mapped to a vendored module.
+		modsrc.Ref = mod.Version
+	}
+
+	if m.vendorDir.String() != "" && modsrc.Ref != "" {
+		vendored := modvendor.TargetDir(m.vendorDir, modsrc).Join(modsrc.Subdir)
+		if isProjectDir(m.root, vendored) {
+			return vendored, "vendored at", true
+		}
+	}
+
+	if m.repoPath != "" && modsrc.Path == m.repoPath {
+		dir := project.NewPath("/").Join(modsrc.Subdir)
+		if isProjectDir(m.root, dir) {
+			return dir, "same repository at", true
+		}
+	}
+	return project.Path{}, "", false
+}
+
+// defaultRemoteRepoPath returns the normalized path of the repository of the
+// project's default remote, in the same format as [tf.Source.Path], or an
+// empty string if it can't be determined.
+func (m *Manager) defaultRemoteRepoPath(g *git.Git) string {
+	remote := "origin"
+	cfg := m.root.Tree().Node
+	if cfg.Terramate != nil &&
+		cfg.Terramate.Config != nil &&
+		cfg.Terramate.Config.Git != nil &&
+		cfg.Terramate.Config.Git.DefaultRemote != "" {
+		remote = cfg.Terramate.Config.Git.DefaultRemote
+	}
+
+	url, err := g.URL(remote)
+	if err != nil {
+		log.Debug().
+			Err(err).
+			Str("remote", remote).
+			Msg("unable to get URL of the default remote")
+		return ""
+	}
+	return repoPathFromURL(url)
+}
+
+// repoPathFromURL normalizes a git remote URL into the path of the
+// repository, as computed by [tf.ParseSource] for module sources.
+func repoPathFromURL(url string) string {
+	var src string
+	switch {
+	case strings.HasPrefix(url, "git@"), strings.HasPrefix(url, "github.com"):
+		src = url
+	case strings.Contains(url, "://"):
+		src = "git::" + url
+	case filepath.IsAbs(url):
+		src = "git::file://" + filepath.ToSlash(url)
+	default:
+		return ""
+	}
+
+	modsrc, err := tf.ParseSource(src)
+	if err != nil {
+		return ""
+	}
+	return modsrc.Path
+}
+
+func isProjectDir(root *config.Root, dir project.Path) bool {
+	st, err := os.Stat(filepath.Join(root.HostDir(), filepath.FromSlash(dir.String())))
+	return err == nil && st.IsDir()
 }
 
 // listChangedFiles lists all changed files in the dir directory.
@@ -0,0 +1,236 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+)
+
+// errSymlinkLoop indicates that a chain of symbolic links never resolves.
+const errSymlinkLoop errors.Kind = "symbolic link loop"
+
+// symlinkChain is the chain of project paths followed when resolving a
+// symbolic link. The first element is the link and the last is the target.
+type symlinkChain []project.Path
+
+func (c symlinkChain) target() project.Path {
+	return c[len(c)-1]
+}
+
+func (c symlinkChain) String() string {
+	return strings.Join(project.Paths(c).Strings(), " -> ")
+}
+
+// followSymlink follows the chain of symbolic links starting at the host
+// path p. The ok result is false if p is not a symbolic link or if any link
+// in the chain points outside the project root.
+func (m *Manager) followSymlink(p string) (chain symlinkChain, ok bool, err error) {
+	visited := map[string]bool{}
+	cur := p
+	for {
+		resolved, inside, err := m.resolveParents(cur)
+		if err != nil {
+			return nil, false, err
+		}
+		if !inside {
+			return nil, false, nil
+		}
+		if resolved != cur {
+			// show the links in the parent directories as part of the chain.
+			if lexical, ok := m.lexicalPrjPath(cur); ok && lexical != m.prjPath(resolved) {
+				chain = append(chain, lexical)
+			}
+		}
+		cur = resolved
+
+		if visited[cur] {
+			return nil, false, errors.E(errSymlinkLoop, "resolving %s", p)
+		}
+		visited[cur] = true
+		chain = append(chain, m.prjPath(cur))
+
+		st, err := os.Lstat(cur)
+		if err != nil {
+			return nil, false, errors.E(err, "resolving symlink %s", p)
+		}
+		if st.Mode()&fs.ModeSymlink == 0 {
+			return chain, len(chain) > 1, nil
+		}
+
+		link, err := os.Readlink(cur)
+		if err != nil {
+			return nil, false, errors.E(err, "reading symlink %s", cur)
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(cur), link)
+		}
+		cur = link
+	}
+}
+
+// resolveParents resolves any symbolic links in the parent directories of
+// the host path p, keeping its last element untouched. It returns false if
+// the resolved path is outside the project root.
+func (m *Manager) resolveParents(p string) (string, bool, error) {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(p))
+	if err != nil {
+		return "", false, errors.E(err, "resolving parent directories of %s", p)
+	}
+	resolved := filepath.Join(dir, filepath.Base(p))
+	return resolved, m.isInsideRoot(resolved), nil
+}
+
+func (m *Manager) isInsideRoot(p string) bool {
+	rel, err := filepath.Rel(m.realRootDir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (m *Manager) prjPath(hostpath string) project.Path {
+	return project.PrjAbsPath(m.realRootDir, hostpath)
+}
+
+// lexicalPrjPath returns the project path of hostpath without resolving any
+// symbolic links.
+func (m *Manager) lexicalPrjPath(hostpath string) (project.Path, bool) {
+	for _, rootdir := range []string{m.root.HostDir(), m.realRootDir} {
+		rel, err := filepath.Rel(rootdir, hostpath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return project.PrjAbsPath(rootdir, hostpath), true
+		}
+	}
+	return project.Path{}, false
+}
+
+// changedLink is a symbolic link resolving to a changed file or to a
+// directory with changed files.
+type changedLink struct {
+	path  project.Path
+	chain symlinkChain
+}
+
+// changedSymlink looks for symbolic links inside dir, not descending into
+// hidden directories nor into the directories for which skipDir returns true,
+// and tells if any of them resolves to a changed file or to a directory with
+// changed files. The returned reason includes the chain of links followed.
+//
+// The symbolic links of the whole project are resolved only once for each
+// [Manager.ListChanged] call, unless dir is inside a hidden directory.
+func (m *Manager) changedSymlink(dir string, skipDir func(path string) bool) (changed bool, why string, err error) {
+	dirpath, ok := m.lexicalPrjPath(dir)
+	if !ok {
+		return false, "", nil
+	}
+
+	walkRoot := project.NewPath("/")
+	if isHiddenPath(dirpath) {
+		walkRoot = dirpath
+	}
+
+	links, err := m.changedLinks(walkRoot)
+	if err != nil {
+		return false, "", errors.E(err, "checking symbolic links in %s", dir)
+	}
+
+	prefix := dirpath.String()
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+nextLink:
+	for _, link := range links {
+		if !strings.HasPrefix(link.path.String(), prefix) {
+			continue
+		}
+		for d := link.path.Dir(); d != dirpath; d = d.Dir() {
+			if strings.HasPrefix(path.Base(d.String()), ".") || skipDir(d.HostPath(m.root.HostDir())) {
+				continue nextLink
+			}
+		}
+		return true, fmt.Sprintf("symlink %s changed", link.chain), nil
+	}
+	return false, "", nil
+}
+
+// changedLinks returns the symbolic links inside dir, not descending into
+// hidden directories, which resolve to changed files. The result is cached.
+func (m *Manager) changedLinks(dir project.Path) ([]changedLink, error) {
+	if links, ok := m.symlinks[dir]; ok {
+		return links, nil
+	}
+
+	logger := log.With().
+		Str("action", "changedLinks()").
+		Stringer("path", dir).
+		Logger()
+
+	hostdir := dir.HostPath(m.root.HostDir())
+	links := []changedLink{}
+	err := filepath.WalkDir(hostdir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != hostdir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		chain, ok, err := m.followSymlink(p)
+		if err != nil {
+			logger.Warn().
+				Err(err).
+				Str("symlink", p).
+				Msg("ignoring symbolic link")
+			return nil
+		}
+		if !ok {
+			logger.Debug().
+				Str("symlink", p).
+				Msg("ignoring symbolic link pointing outside the project")
+			return nil
+		}
+
+		if m.hasChangedFiles(chain.target()) {
+			links = append(links, changedLink{
+				path:  project.PrjAbsPath(m.root.HostDir(), p),
+				chain: chain,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.symlinks[dir] = links
+	return links, nil
+}
+
+// isHiddenPath tells if any element of the path is hidden.
+func isHiddenPath(p project.Path) bool {
+	for _, elem := range strings.Split(p.String(), "/") {
+		if strings.HasPrefix(elem, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasChangedFiles tells if the project path, a file or a directory, has
+// changed files.
+func (m *Manager) hasChangedFiles(target project.Path) bool {
+	return m.changedFiles[target] || m.changedDirs[target]
+}
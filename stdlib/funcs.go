@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sync"
 
 	resyntax "regexp/syntax"
 
@@ -25,10 +26,28 @@ import (
 	"github.com/zclconf/go-cty/cty/function"
 )
 
-var regexCache map[string]*regexp.Regexp
+// regexCache is shared by the functions of all evaluation contexts, which may
+// be used concurrently.
+var regexCache struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}
 
 func init() {
-	regexCache = map[string]*regexp.Regexp{}
+	regexCache.patterns = map[string]*regexp.Regexp{}
+}
+
+func cachedRegex(pattern string) (*regexp.Regexp, bool) {
+	regexCache.Lock()
+	defer regexCache.Unlock()
+	re, ok := regexCache.patterns[pattern]
+	return re, ok
+}
+
+func cacheRegex(pattern string, re *regexp.Regexp) {
+	regexCache.Lock()
+	defer regexCache.Unlock()
+	regexCache.patterns[pattern] = re
 }
 
 // Functions returns all the Terramate default functions.
@@ -122,7 +141,7 @@ func Regex() function.Function {
 				return cty.DynamicVal, nil
 			}
 
-			re, ok := regexCache[args[0].AsString()]
+			re, ok := cachedRegex(args[0].AsString())
 			if !ok {
 				panic("should be in the cache")
 			}
@@ -146,7 +165,7 @@ func Regex() function.Function {
 // Returns an error if parsing fails or if the pattern uses a mixture of
 // named and unnamed capture groups, which is not permitted.
 func regexPatternResultType(pattern string) (cty.Type, error) {
-	re, ok := regexCache[pattern]
+	re, ok := cachedRegex(pattern)
 	if !ok {
 		var rawErr error
 		re, rawErr = regexp.Compile(pattern)
@@ -159,7 +178,7 @@ func regexPatternResultType(pattern string) (cty.Type, error) {
 			return cty.NilType, fmt.Errorf("error parsing pattern: %s", err)
 		}
 
-		regexCache[pattern] = re
+		cacheRegex(pattern, re)
 	}
 
 	allNames := re.SubexpNames()[1:]
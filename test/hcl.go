@@ -65,6 +65,7 @@ func AssertTerramateConfig(t *testing.T, got, want hcl.Config) {
 	assertStackBlock(t, got.Stack, want.Stack)
 	assertAssertsBlock(t, got.Asserts, want.Asserts, "terramate asserts")
 	AssertDiff(t, got.Vendor, want.Vendor, "terramate vendor")
+	assertRunEnv(t, got.Env, want.Env, "env")
 	assertGenHCLBlocks(t, got.Generate.HCLs, want.Generate.HCLs)
 	assertGenFileBlocks(t, got.Generate.Files, want.Generate.Files)
 }
@@ -284,15 +285,23 @@ func assertTerramateRunBlock(t *testing.T, got, want *hcl.RunConfig) {
 		"want.Run.CheckGenCode %v != got.Run.CheckGenCode %v",
 		want.CheckGenCode, got.CheckGenCode)
 
-	if (want.Env == nil) != (got.Env == nil) {
-		t.Fatalf(
-			"want.Run.Env[%+v] != got.Run.Env[%+v]",
-			want.Env,
-			got.Env,
-		)
+	assert.IsTrue(t, want.Timeout == got.Timeout,
+		"want.Run.Timeout %v != got.Run.Timeout %v",
+		want.Timeout, got.Timeout)
+
+	AssertDiff(t, got.Retry, want.Retry, "run.retry mismatch")
+
+	assertRunEnv(t, got.Env, want.Env, "run.env")
+}
+
+func assertRunEnv(t *testing.T, got, want *hcl.RunEnv, ctx string) {
+	t.Helper()
+
+	if (want == nil) != (got == nil) {
+		t.Fatalf("%s: want[%+v] != got[%+v]", ctx, want, got)
 	}
 
-	if want.Env == nil {
+	if want == nil {
 		return
 	}
 
@@ -301,10 +310,10 @@ func assertTerramateRunBlock(t *testing.T, got, want *hcl.RunConfig) {
 	// So we do this hack in an attempt of comparing the attributes
 	// original expressions (no eval involved).
 
-	gotHCL := hclFromAttributes(t, got.Env.Attributes)
-	wantHCL := hclFromAttributes(t, want.Env.Attributes)
+	gotHCL := hclFromAttributes(t, got.Attributes)
+	wantHCL := hclFromAttributes(t, want.Attributes)
 
-	AssertDiff(t, gotHCL, wantHCL)
+	AssertDiff(t, gotHCL, wantHCL, ctx)
 }
 
 // hclFromAttributes ensures that we always build the same HCL document
@@ -106,25 +106,189 @@ func (e *Editor) Open(path string) {
 	}
 }
 
-// Change sends a didChange request to the language server.
+// Change sends a didChange request to the language server replacing the
+// whole content of the file.
 func (e *Editor) Change(path, content string) {
+	e.t.Helper()
+	e.change(path, contentChange{Text: content})
+}
+
+// ChangeRange sends a didChange request to the language server replacing the
+// given range of the file with text.
+func (e *Editor) ChangeRange(path string, rng lsp.Range, text string) {
+	e.t.Helper()
+	e.change(path, contentChange{Range: &rng, Text: text})
+}
+
+// contentChange is a lsp.TextDocumentContentChangeEvent which omits the range
+// when the whole content changes.
+type contentChange struct {
+	Range *lsp.Range `json:"range,omitempty"`
+	Text  string     `json:"text"`
+}
+
+func (e *Editor) change(path string, change contentChange) {
 	t := e.t
 	t.Helper()
 	abspath := filepath.Join(e.sandbox.RootDir(), path)
 	var changeResult interface{}
-	_, err := e.call(lsp.MethodTextDocumentDidChange, lsp.DidChangeTextDocumentParams{
-		TextDocument: lsp.VersionedTextDocumentIdentifier{
+	_, err := e.call(lsp.MethodTextDocumentDidChange, map[string]interface{}{
+		"textDocument": lsp.VersionedTextDocumentIdentifier{
 			TextDocumentIdentifier: lsp.TextDocumentIdentifier{
 				URI: uri.File(abspath),
 			},
 		},
-		ContentChanges: []lsp.TextDocumentContentChangeEvent{
+		"contentChanges": []contentChange{change},
+	}, &changeResult)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentDidChange)
+}
+
+// Save sends a didSave request to the language server.
+func (e *Editor) Save(path string) {
+	t := e.t
+	t.Helper()
+	abspath := filepath.Join(e.sandbox.RootDir(), path)
+	var saveResult interface{}
+	_, err := e.call(lsp.MethodTextDocumentDidSave, lsp.DidSaveTextDocumentParams{
+		TextDocument: lsp.TextDocumentIdentifier{
+			URI: uri.File(abspath),
+		},
+	}, &saveResult)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentDidSave)
+}
+
+// FileChanged sends a didChangeWatchedFiles request to the language server
+// telling the file changed on disk.
+func (e *Editor) FileChanged(path string) {
+	t := e.t
+	t.Helper()
+	abspath := filepath.Join(e.sandbox.RootDir(), path)
+	var changeResult interface{}
+	_, err := e.call(lsp.MethodWorkspaceDidChangeWatchedFiles, lsp.DidChangeWatchedFilesParams{
+		Changes: []*lsp.FileEvent{
 			{
-				Text: content,
+				URI:  uri.File(abspath),
+				Type: lsp.FileChangeTypeChanged,
 			},
 		},
 	}, &changeResult)
-	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentDidChange)
+	assert.NoError(t, err, "call %q", lsp.MethodWorkspaceDidChangeWatchedFiles)
+}
+
+// Hover sends a hover request to the language server for the given position
+// of the file and returns the result, which is nil if no hover is available.
+func (e *Editor) Hover(path string, line, char uint32) *lsp.Hover {
+	t := e.t
+	t.Helper()
+	var result *lsp.Hover
+	_, err := e.call(lsp.MethodTextDocumentHover, lsp.HoverParams{
+		TextDocumentPositionParams: e.positionParams(path, line, char),
+	}, &result)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentHover)
+	return result
+}
+
+// Definition sends a definition request to the language server for the given
+// position of the file and returns the locations found.
+func (e *Editor) Definition(path string, line, char uint32) []lsp.Location {
+	t := e.t
+	t.Helper()
+	var result []lsp.Location
+	_, err := e.call(lsp.MethodTextDocumentDefinition, lsp.DefinitionParams{
+		TextDocumentPositionParams: e.positionParams(path, line, char),
+	}, &result)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentDefinition)
+	return result
+}
+
+// Completion sends a completion request to the language server for the given
+// position of the file and returns the labels of the completion items.
+func (e *Editor) Completion(path string, line, char uint32) []string {
+	t := e.t
+	t.Helper()
+	var result lsp.CompletionList
+	_, err := e.call(lsp.MethodTextDocumentCompletion, lsp.CompletionParams{
+		TextDocumentPositionParams: e.positionParams(path, line, char),
+	}, &result)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentCompletion)
+
+	labels := []string{}
+	for _, item := range result.Items {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+// Formatting sends a formatting request to the language server and returns
+// the edits formatting the file.
+func (e *Editor) Formatting(path string) []lsp.TextEdit {
+	t := e.t
+	t.Helper()
+	var result []lsp.TextEdit
+	_, err := e.call(lsp.MethodTextDocumentFormatting, lsp.DocumentFormattingParams{
+		TextDocument: e.documentID(path),
+	}, &result)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentFormatting)
+	return result
+}
+
+// RangeFormatting sends a range formatting request to the language server and
+// returns the edits formatting the range of the file.
+func (e *Editor) RangeFormatting(path string, rng lsp.Range) []lsp.TextEdit {
+	t := e.t
+	t.Helper()
+	var result []lsp.TextEdit
+	_, err := e.call(lsp.MethodTextDocumentRangeFormatting, lsp.DocumentRangeFormattingParams{
+		TextDocument: e.documentID(path),
+		Range:        rng,
+	}, &result)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentRangeFormatting)
+	return result
+}
+
+// DocumentSymbols sends a document symbol request to the language server and
+// returns the symbols of the file.
+func (e *Editor) DocumentSymbols(path string) []lsp.DocumentSymbol {
+	t := e.t
+	t.Helper()
+	var result []lsp.DocumentSymbol
+	_, err := e.call(lsp.MethodTextDocumentDocumentSymbol, lsp.DocumentSymbolParams{
+		TextDocument: e.documentID(path),
+	}, &result)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentDocumentSymbol)
+	return result
+}
+
+// CodeActions sends a code action request to the language server for the
+// given range of the file and returns the actions available.
+func (e *Editor) CodeActions(path string, rng lsp.Range) []lsp.CodeAction {
+	t := e.t
+	t.Helper()
+	var result []lsp.CodeAction
+	_, err := e.call(lsp.MethodTextDocumentCodeAction, lsp.CodeActionParams{
+		TextDocument: e.documentID(path),
+		Range:        rng,
+	}, &result)
+	assert.NoError(t, err, "call %q", lsp.MethodTextDocumentCodeAction)
+	return result
+}
+
+func (e *Editor) documentID(path string) lsp.TextDocumentIdentifier {
+	return lsp.TextDocumentIdentifier{
+		URI: uri.File(filepath.Join(e.sandbox.RootDir(), path)),
+	}
+}
+
+func (e *Editor) positionParams(path string, line, char uint32) lsp.TextDocumentPositionParams {
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{
+			URI: uri.File(filepath.Join(e.sandbox.RootDir(), path)),
+		},
+		Position: lsp.Position{
+			Line:      line,
+			Character: char,
+		},
+	}
 }
 
 // DefaultInitializeResult is the default server response for the initialization
@@ -132,11 +296,19 @@ func (e *Editor) Change(path, content string) {
 func DefaultInitializeResult() lsp.InitializeResult {
 	return lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
-			CompletionProvider: &lsp.CompletionOptions{},
-			DefinitionProvider: false,
-			HoverProvider:      false,
+			CompletionProvider: &lsp.CompletionOptions{
+				TriggerCharacters: []string{"."},
+			},
+			DefinitionProvider:              true,
+			HoverProvider:                   true,
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			DocumentSymbolProvider:          true,
+			CodeActionProvider: map[string]interface{}{
+				"codeActionKinds": []interface{}{"quickfix"},
+			},
 			TextDocumentSync: map[string]interface{}{
-				"change":    float64(1),
+				"change":    float64(2),
 				"openClose": true,
 				"save":      map[string]interface{}{},
 			},
@@ -0,0 +1,6 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+// Package registry provides a stand-in Terraform Registry used when testing
+// the vendoring of registry modules.
+package registry
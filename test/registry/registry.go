@@ -0,0 +1,135 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package registry
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/modvendor/registry"
+)
+
+// Module is a module served by the fake registry.
+type Module struct {
+	// Versions are the available versions of the module.
+	Versions []string
+
+	// Location returns the download location of the given version.
+	Location func(version string) string
+}
+
+// Registry is a fake Terraform Registry served over TLS.
+type Registry struct {
+	// URL is the base URL of the registry.
+	URL string
+
+	// Host is the host of the registry, as used in module sources.
+	Host string
+
+	srv *httptest.Server
+}
+
+// New starts a fake registry serving the given modules, keyed by their
+// namespace/name/provider, and tar.gz archives of the given files, keyed by
+// their URL path. Any {{host}} in the archive files is replaced by the host
+// of the registry. The registry is closed when the test finishes.
+func New(t *testing.T, modules map[string]Module, archives map[string]map[string]string) *Registry {
+	t.Helper()
+
+	reg := &Registry{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"modules.v1": "/api/modules/v1/"}`)
+	})
+	for name, mod := range modules {
+		mod := mod
+		mux.HandleFunc("/api/modules/v1/"+name+"/versions", func(w http.ResponseWriter, r *http.Request) {
+			var versions []string
+			for _, v := range mod.Versions {
+				versions = append(versions, fmt.Sprintf(`{"version": %q}`, v))
+			}
+			fmt.Fprintf(w, `{"modules": [{"versions": [%s]}]}`, strings.Join(versions, ","))
+		})
+		for _, v := range mod.Versions {
+			v := v
+			mux.HandleFunc("/api/modules/v1/"+name+"/"+v+"/download", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Terraform-Get", mod.Location(v))
+				w.WriteHeader(http.StatusNoContent)
+			})
+		}
+	}
+	for p, files := range archives {
+		files := files
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			contents := map[string]string{}
+			for name, content := range files {
+				contents[name] = strings.ReplaceAll(content, "{{host}}", reg.Host)
+			}
+			_, _ = w.Write(TarGz(t, contents))
+		})
+	}
+
+	reg.srv = httptest.NewTLSServer(mux)
+	t.Cleanup(reg.srv.Close)
+
+	u, err := url.Parse(reg.srv.URL)
+	assert.NoError(t, err)
+
+	reg.URL = reg.srv.URL
+	reg.Host = u.Host
+	return reg
+}
+
+// Client returns a registry client trusting the registry certificate.
+func (reg *Registry) Client() *registry.Client {
+	return &registry.Client{HTTPClient: reg.srv.Client()}
+}
+
+// TarGz returns a tar.gz archive of the given files, keyed by their path.
+func TarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// Zip returns a zip archive of the given files, keyed by their path.
+func Zip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
@@ -6,6 +6,7 @@ package tf
 import (
 	"net/url"
 	"path"
+	"regexp"
 	"strings"
 
 	"github.com/terramate-io/terramate/errors"
@@ -32,6 +33,43 @@ type Source struct {
 
 	// Raw source
 	Raw string
+
+	// Registry is the address of the module on a Terraform Registry. It is
+	// empty for sources which are not registry sources, see [Source.IsRegistry].
+	// For registry sources the Ref is the module version.
+	Registry RegistryAddress
+}
+
+// IsRegistry tells if the source is a Terraform Registry source.
+func (s Source) IsRegistry() bool {
+	return s.PathScheme == "registry"
+}
+
+// RegistryAddress is the address of a module in a Terraform Registry, as
+// documented in:
+//
+// - https://developer.hashicorp.com/terraform/language/modules/sources#terraform-registry
+type RegistryAddress struct {
+	Hostname  string
+	Namespace string
+	Name      string
+	Provider  string
+}
+
+// DefaultRegistryHostname is the hostname of the public Terraform Registry,
+// used when the registry source has no hostname.
+const DefaultRegistryHostname = "registry.terraform.io"
+
+var (
+	registryNameRegex     = regexp.MustCompile(`^[0-9A-Za-z](?:[0-9A-Za-z-_]{0,62}[0-9A-Za-z])?$`)
+	registryProviderRegex = regexp.MustCompile(`^[0-9a-z]{1,64}$`)
+	registryHostnameRegex = regexp.MustCompile(`^[0-9A-Za-z](?:[0-9A-Za-z.-]*[0-9A-Za-z])?(?::[0-9]+)?$`)
+)
+
+// String returns the registry address in the <hostname>/<namespace>/<name>/<provider>
+// form.
+func (a RegistryAddress) String() string {
+	return path.Join(a.Hostname, a.Namespace, a.Name, a.Provider)
 }
 
 const (
@@ -47,7 +85,10 @@ const (
 //
 // - https://www.terraform.io/language/modules/sources
 //
-// Source references that are not Git/Github are not supported.
+// Terraform Registry sources are also supported, in which case the returned
+// source has no URL and the Ref must be set to the module version.
+//
+// Other source references are not supported.
 func ParseSource(modsource string) (Source, error) {
 	switch {
 	case strings.HasPrefix(modsource, "github.com"):
@@ -141,10 +182,66 @@ func ParseSource(modsource string) (Source, error) {
 		}, nil
 
 	default:
+		if src, ok := parseRegistrySource(modsource); ok {
+			return src, nil
+		}
 		return Source{}, errors.E(ErrUnsupportedModSrc)
 	}
 }
 
+// parseRegistrySource parses Terraform Registry sources in the forms:
+//
+// - <namespace>/<name>/<provider>
+// - <hostname>/<namespace>/<name>/<provider>
+//
+// Both forms may have a //subdir suffix.
+func parseRegistrySource(modsource string) (Source, bool) {
+	if strings.ContainsAny(modsource, "?#") || strings.Contains(modsource, "::") {
+		return Source{}, false
+	}
+
+	addr, subdir := parseSubdir(modsource)
+	parts := strings.Split(addr, "/")
+
+	var reg RegistryAddress
+	switch len(parts) {
+	case 3:
+		reg.Hostname = DefaultRegistryHostname
+	case 4:
+		// a hostname must have at least a dot or a port, otherwise it is a
+		// namespace.
+		if !strings.ContainsAny(parts[0], ".:") || !registryHostnameRegex.MatchString(parts[0]) {
+			return Source{}, false
+		}
+		reg.Hostname = strings.ToLower(parts[0])
+		parts = parts[1:]
+	default:
+		return Source{}, false
+	}
+
+	reg.Namespace, reg.Name, reg.Provider = parts[0], parts[1], parts[2]
+
+	// VCS hosts shorthands are not registry addresses.
+	switch strings.ToLower(reg.Namespace) {
+	case "github.com", "bitbucket.org":
+		return Source{}, false
+	}
+
+	if !registryNameRegex.MatchString(reg.Namespace) ||
+		!registryNameRegex.MatchString(reg.Name) ||
+		!registryProviderRegex.MatchString(reg.Provider) {
+		return Source{}, false
+	}
+
+	return Source{
+		Raw:        modsource,
+		Path:       reg.String(),
+		PathScheme: "registry",
+		Subdir:     subdir,
+		Registry:   reg,
+	}, true
+}
+
 func parseSubdir(s string) (string, string) {
 	if !strings.Contains(s, "//") {
 		return s, ""
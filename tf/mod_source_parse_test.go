@@ -435,15 +435,87 @@ func TestParseGitSources(t *testing.T) {
 			},
 		},
 		{
-			name:   "terraform registry is not supported",
+			name:   "terraform registry source",
 			source: "hashicorp/consul/aws",
 			want: want{
-				err: errors.E(tf.ErrUnsupportedModSrc),
+				parsed: tf.Source{
+					Path:       "registry.terraform.io/hashicorp/consul/aws",
+					PathScheme: "registry",
+					Registry: tf.RegistryAddress{
+						Hostname:  "registry.terraform.io",
+						Namespace: "hashicorp",
+						Name:      "consul",
+						Provider:  "aws",
+					},
+				},
+			},
+		},
+		{
+			name:   "terraform registry source with subdir",
+			source: "hashicorp/consul/aws//modules/consul-cluster",
+			want: want{
+				parsed: tf.Source{
+					Path:       "registry.terraform.io/hashicorp/consul/aws",
+					PathScheme: "registry",
+					Subdir:     "/modules/consul-cluster",
+					Registry: tf.RegistryAddress{
+						Hostname:  "registry.terraform.io",
+						Namespace: "hashicorp",
+						Name:      "consul",
+						Provider:  "aws",
+					},
+				},
 			},
 		},
 		{
-			name:   "registry is not supported",
+			name:   "private registry source",
 			source: "app.terraform.io/example-corp/k8s-cluster/azurerm",
+			want: want{
+				parsed: tf.Source{
+					Path:       "app.terraform.io/example-corp/k8s-cluster/azurerm",
+					PathScheme: "registry",
+					Registry: tf.RegistryAddress{
+						Hostname:  "app.terraform.io",
+						Namespace: "example-corp",
+						Name:      "k8s-cluster",
+						Provider:  "azurerm",
+					},
+				},
+			},
+		},
+		{
+			name:   "private registry source with port",
+			source: "localhost:8443/example-corp/k8s-cluster/azurerm",
+			want: want{
+				parsed: tf.Source{
+					Path:       "localhost:8443/example-corp/k8s-cluster/azurerm",
+					PathScheme: "registry",
+					Registry: tf.RegistryAddress{
+						Hostname:  "localhost:8443",
+						Namespace: "example-corp",
+						Name:      "k8s-cluster",
+						Provider:  "azurerm",
+					},
+				},
+			},
+		},
+		{
+			name:   "registry source with invalid provider is not supported",
+			source: "hashicorp/consul/AWS",
+			want: want{
+				err: errors.E(tf.ErrUnsupportedModSrc),
+			},
+		},
+		{
+			name:   "registry source with query is not supported",
+			source: "hashicorp/consul/aws?ref=v1",
+			want: want{
+				err: errors.E(tf.ErrUnsupportedModSrc),
+			},
+		},
+		{
+			name:   "registry source with too many parts is not supported",
+			source: "example.com/a/hashicorp/consul/aws",
 			want: want{
 				err: errors.E(tf.ErrUnsupportedModSrc),
 			},
@@ -5,7 +5,9 @@ package tf
 
 import (
 	"os"
+	"strings"
 
+	hhcl "github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/rs/zerolog/log"
@@ -17,12 +19,40 @@ import (
 // Module represents a terraform module.
 // Note that only the fields relevant for terramate are declared here.
 type Module struct {
-	Source string // Source is the module source path (eg.: directory, git path, etc).
+	Source  string // Source is the module source path (eg.: directory, git path, etc).
+	Version string // Version is the version constraint of registry modules, if any.
 }
 
 // ErrHCLSyntax represents a HCL syntax error
 const ErrHCLSyntax errors.Kind = "HCL syntax error"
 
+// Extensions of the files parsed by Terraform and OpenTofu.
+var (
+	nativeExtensions = []string{".tf", ".tofu"}
+	jsonExtensions   = []string{".tf.json", ".tofu.json"}
+)
+
+// IsTerraformFile tells if the file is a Terraform or OpenTofu configuration
+// file, in native (.tf and .tofu) or JSON (.tf.json and .tofu.json) syntax.
+func IsTerraformFile(filename string) bool {
+	return hasAnySuffix(filename, nativeExtensions) || IsJSONFile(filename)
+}
+
+// IsJSONFile tells if the file is a Terraform or OpenTofu configuration file
+// in JSON syntax (.tf.json and .tofu.json).
+func IsJSONFile(filename string) bool {
+	return hasAnySuffix(filename, jsonExtensions)
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsLocal tells if module source is a local directory.
 func (m Module) IsLocal() bool {
 	// As specified here: https://www.terraform.io/docs/language/modules/sources.html#local-paths
@@ -44,18 +74,17 @@ func ParseModules(path string) ([]Module, error) {
 		return nil, errors.E(err, "stat failed on %q", path)
 	}
 
-	logger.Trace().Msg("Create new parser")
-
-	p := hclparse.NewParser()
-
-	logger.Debug().Msg("Parse HCL file")
+	logger.Debug().Msg("Parse file")
 
-	f, diags := p.ParseHCLFile(path)
-	if diags.HasErrors() {
-		return nil, errors.E(ErrHCLSyntax, diags)
+	f, err := parseFile(path)
+	if err != nil {
+		return nil, err
 	}
 
-	body := f.Body.(*hclsyntax.Body)
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return parseJSONModules(f.Body), nil
+	}
 
 	logger.Trace().Msg("Parse modules")
 
@@ -92,7 +121,13 @@ func ParseModules(path string) ([]Module, error) {
 
 			continue
 		}
-		modules = append(modules, Module{Source: source})
+		version, _, err := findStringAttr(block, "version")
+		if err != nil {
+			logger.Debug().
+				Err(err).
+				Msg("ignoring invalid module version")
+		}
+		modules = append(modules, Module{Source: source, Version: version})
 	}
 
 	return modules, nil
@@ -105,16 +140,17 @@ func IsStack(path string) (bool, error) {
 		Str("action", "IsStack").
 		Logger()
 
-	p := hclparse.NewParser()
-
 	logger.Debug().Msg("Parsing TF file")
 
-	f, diags := p.ParseHCLFile(path)
-	if diags.HasErrors() {
-		return false, errors.E(ErrHCLSyntax, diags)
+	f, err := parseFile(path)
+	if err != nil {
+		return false, err
 	}
 
-	body := f.Body.(*hclsyntax.Body)
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return isJSONStack(f.Body), nil
+	}
 
 	logger.Trace().Msg("Parse terraform.backend blocks")
 
@@ -137,6 +173,107 @@ func IsStack(path string) (bool, error) {
 	return false, nil
 }
 
+// parseFile parses the Terraform file using the JSON syntax for .tf.json and
+// .tofu.json files and the native syntax otherwise.
+func parseFile(path string) (*hhcl.File, error) {
+	p := hclparse.NewParser()
+
+	var (
+		f     *hhcl.File
+		diags hhcl.Diagnostics
+	)
+	if IsJSONFile(path) {
+		f, diags = p.ParseJSONFile(path)
+	} else {
+		f, diags = p.ParseHCLFile(path)
+	}
+	if diags.HasErrors() {
+		return nil, errors.E(ErrHCLSyntax, diags)
+	}
+	return f, nil
+}
+
+// parseJSONModules parses the module blocks of a file in JSON syntax. In JSON
+// the block labels are given by the schema, so module blocks always have a
+// single label.
+func parseJSONModules(body hhcl.Body) []Module {
+	logger := log.With().
+		Str("action", "parseJSONModules()").
+		Logger()
+
+	content, _, diags := body.PartialContent(&hhcl.BodySchema{
+		Blocks: []hhcl.BlockHeaderSchema{
+			{Type: "module", LabelNames: []string{"name"}},
+		},
+	})
+	if diags.HasErrors() {
+		logger.Debug().
+			Err(diags).
+			Msg("ignoring invalid module blocks")
+	}
+
+	var modules []Module
+	for _, block := range content.Blocks {
+		source, ok := jsonStringAttr(block.Body, "source")
+		if !ok {
+			logger.Debug().
+				Str("module", block.Labels[0]).
+				Msg("ignoring module block without source")
+			continue
+		}
+		version, _ := jsonStringAttr(block.Body, "version")
+		modules = append(modules, Module{Source: source, Version: version})
+	}
+	return modules
+}
+
+// isJSONStack is the JSON syntax version of [IsStack].
+func isJSONStack(body hhcl.Body) bool {
+	content, _, _ := body.PartialContent(&hhcl.BodySchema{
+		Blocks: []hhcl.BlockHeaderSchema{
+			{Type: "terraform"},
+			{Type: "provider", LabelNames: []string{"name"}},
+		},
+	})
+
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "terraform":
+			tfcontent, _, _ := block.Body.PartialContent(&hhcl.BodySchema{
+				Blocks: []hhcl.BlockHeaderSchema{
+					{Type: "backend", LabelNames: []string{"type"}},
+				},
+			})
+			if len(tfcontent.Blocks) > 0 {
+				return true
+			}
+		case "provider":
+			return true
+		}
+	}
+	return false
+}
+
+func jsonStringAttr(body hhcl.Body, attrName string) (string, bool) {
+	content, _, diags := body.PartialContent(&hhcl.BodySchema{
+		Attributes: []hhcl.AttributeSchema{{Name: attrName}},
+	})
+	if diags.HasErrors() {
+		return "", false
+	}
+	attr, ok := content.Attributes[attrName]
+	if !ok {
+		return "", false
+	}
+	// an empty context is needed, otherwise JSON strings are not evaluated
+	// as templates and interpolations are returned literally.
+	val, diags := attr.Expr.Value(&hhcl.EvalContext{})
+	if diags.HasErrors() || val.Type() != cty.String || val.IsNull() {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
 func findStringAttr(block *hclsyntax.Block, attrName string) (string, bool, error) {
 	logger := log.With().
 		Str("action", "findStringAttr()").
@@ -87,6 +87,39 @@ func TestHCLParserModules(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "registry module with version",
+			input: cfgfile{
+				filename: "main.tf",
+				body: `module "test" {
+					source = "hashicorp/consul/aws"
+					version = "~> 1.0"
+				}`,
+			},
+			want: want{
+				modules: []tf.Module{
+					{
+						Source:  "hashicorp/consul/aws",
+						Version: "~> 1.0",
+					},
+				},
+			},
+		},
+		{
+			name: "registry module with version in JSON file",
+			input: cfgfile{
+				filename: "main.tf.json",
+				body:     `{"module": {"test": {"source": "hashicorp/consul/aws", "version": "~> 1.0"}}}`,
+			},
+			want: want{
+				modules: []tf.Module{
+					{
+						Source:  "hashicorp/consul/aws",
+						Version: "~> 1.0",
+					},
+				},
+			},
+		},
 		{
 			name: "mixing modules and attributes, ignore attrs",
 			input: cfgfile{
@@ -172,6 +205,72 @@ module "test" {
 			`,
 			},
 		},
+		{
+			name: "modules in OpenTofu file",
+			input: cfgfile{
+				filename: "main.tofu",
+				body:     `module "test" {source = "test"}`,
+			},
+			want: want{
+				modules: []tf.Module{
+					{
+						Source: "test",
+					},
+				},
+			},
+		},
+		{
+			name: "modules in JSON file",
+			input: cfgfile{
+				filename: "main.tf.json",
+				body: `{
+					"variable": {"test": {}},
+					"module": {
+						"test": {"source": "test"},
+						"bleh": {"source": "bleh"},
+						"nosource": {},
+						"notstring": {"source": 1},
+						"interpolated": {"source": "${var.test}"}
+					}
+				}`,
+			},
+			want: want{
+				modules: []tf.Module{
+					{
+						Source: "test",
+					},
+					{
+						Source: "bleh",
+					},
+				},
+			},
+		},
+		{
+			name: "modules in OpenTofu JSON file",
+			input: cfgfile{
+				filename: "main.tofu.json",
+				body:     `{"module": {"test": {"source": "test"}}}`,
+			},
+			want: want{
+				modules: []tf.Module{
+					{
+						Source: "test",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid JSON file",
+			input: cfgfile{
+				filename: "main.tf.json",
+				body:     `{"module": `,
+			},
+			want: want{
+				errs: []error{
+					errors.E(tf.ErrHCLSyntax),
+				},
+			},
+		},
 		{
 			name: "multiple syntax errors on same file get reported",
 			input: cfgfile{
@@ -210,6 +309,8 @@ module "test" {
 			for i := 0; i < len(tc.want.modules); i++ {
 				assert.EqualStrings(t, tc.want.modules[i].Source, modules[i].Source,
 					"module source mismatch")
+				assert.EqualStrings(t, tc.want.modules[i].Version, modules[i].Version,
+					"module version mismatch")
 			}
 		})
 	}
@@ -369,6 +470,67 @@ func TestTerraformHasBackend(t *testing.T) {
 	}
 }
 
+func TestTerraformJSONIsStack(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name    string
+		body    string
+		isStack bool
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "no blocks defined",
+			body: `{}`,
+		},
+		{
+			name: "terraform block with no backend",
+			body: `{"terraform": {"required_version": ">= 1.0"}}`,
+		},
+		{
+			name:    "terraform block with backend",
+			body:    `{"terraform": {"backend": {"s3": {"bucket": "b"}}}}`,
+			isStack: true,
+		},
+		{
+			name:    "provider block defined",
+			body:    `{"provider": {"aws": {"region": "us-east-1"}}}`,
+			isStack: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := test.WriteFile(t, t.TempDir(), "main.tf.json", tc.body)
+			isStack, err := tf.IsStack(path)
+			assert.NoError(t, err)
+			if isStack != tc.isStack {
+				t.Fatalf("unexpected isStack. Expected %t but got %t", tc.isStack, isStack)
+			}
+		})
+	}
+}
+
+func TestIsTerraformFile(t *testing.T) {
+	t.Parallel()
+
+	for filename, want := range map[string]bool{
+		"main.tf":        true,
+		"main.tf.json":   true,
+		"main.tofu":      true,
+		"main.tofu.json": true,
+		"main.json":      false,
+		"main.tm.hcl":    false,
+		"main.hcl":       false,
+	} {
+		if got := tf.IsTerraformFile(filename); got != want {
+			t.Errorf("IsTerraformFile(%q) = %t, want %t", filename, got, want)
+		}
+	}
+}
+
 // some helpers to easy build file ranges.
 func mkrange(fname string, start, end hhcl.Pos) hhcl.Range {
 	if start.Byte == end.Byte {